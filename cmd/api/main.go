@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
 )
 
 // Defaults - overwritten by build
@@ -25,7 +26,7 @@ func main() {
 	logger := initLogger(cfg)
 
 	logger.Info(fmt.Sprintf("ez2boot version %s date %s", version, buildDate))
-	logger.Info(fmt.Sprintf("cloud provider is set to %s", cfg.CloudProvider))
+	logger.Info(fmt.Sprintf("cloud providers are set to %s", strings.Join(cfg.CloudProviders, ", ")))
 
 	// Connect to db and hold connection open
 	conn, repo := initDatabase(logger)
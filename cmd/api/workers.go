@@ -5,29 +5,47 @@ import (
 	"ez2boot/internal/app"
 	"ez2boot/internal/config"
 	"ez2boot/internal/provider"
+	"ez2boot/internal/shared"
 	"ez2boot/internal/worker"
 )
 
 func startWorkers(ctx context.Context, cfg *config.Config, wkr *worker.Worker, services *app.Services) {
-	// Assign scrape implementation based off configured cloud provider
-	var scraper provider.Scraper
-	var manager provider.Manager
-
-	switch cfg.CloudProvider {
-	case "aws":
-		scraper = services.AWSService
-		manager = services.AWSService
-	case "azure":
-		scraper = services.AzureService
-		manager = services.AzureService
+	// Assign scrape and manage implementations for each configured cloud provider
+	for _, cloudProvider := range cfg.CloudProviders {
+		var scraper provider.Scraper
+		var manager provider.Manager
+
+		switch cloudProvider {
+		case shared.CloudProviderAWS:
+			scraper = services.AWSService
+			manager = services.AWSService
+		case shared.CloudProviderAzure:
+			scraper = services.AzureService
+			manager = services.AzureService
+		case shared.CloudProviderGCP:
+			scraper = services.GCPService
+			manager = services.GCPService
+		case shared.CloudProviderDocker:
+			scraper = services.DockerService
+			manager = services.DockerService
+		case shared.CloudProviderKubernetes:
+			scraper = services.KubernetesService
+			manager = services.KubernetesService
+		case shared.CloudProviderProxmox:
+			scraper = services.ProxmoxService
+			manager = services.ProxmoxService
+		case shared.CloudProviderSimulated:
+			scraper = services.SimulatedService
+			manager = services.SimulatedService
+		}
+
+		// Start scraper
+		worker.StartScrapeRoutine(*wkr, ctx, scraper)
+
+		// Start manager
+		worker.StartManageRoutine(*wkr, ctx, manager)
 	}
 
-	// Start scraper
-	worker.StartScrapeRoutine(*wkr, ctx, scraper)
-
-	// Start manager
-	worker.StartManageRoutine(*wkr, ctx, manager)
-
 	// Start notification worker
 	worker.StartNotificationWorker(*wkr, ctx)
 
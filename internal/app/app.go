@@ -30,6 +30,18 @@ func NewApp(version string, buildDate string, cfg *config.Config, repo *db.Repos
 
 	cfg.SetupMode = !hasUsers
 
+	// Servers recorded before provider ownership was tracked can only be assigned when unambiguous
+	if len(cfg.CloudProviders) == 1 {
+		if err := services.ServerService.AssignUnownedServers(cfg.CloudProviders[0]); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	// Scrapes only touch their own provider, so servers of a provider removed from CLOUD_PROVIDER are removed here
+	if err := services.ServerService.RemoveUnconfiguredServers(cfg.CloudProviders); err != nil {
+		return nil, nil, nil, err
+	}
+
 	// Initialise OIDC provider if configured
 	if err := services.OidcService.InitProvider(context.Background()); err != nil {
 		if !errors.Is(err, shared.ErrOIDCConfigNotFound) {
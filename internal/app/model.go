@@ -12,6 +12,11 @@ import (
 	"ez2boot/internal/notification/telegram"
 	"ez2boot/internal/provider/aws"
 	"ez2boot/internal/provider/azure"
+	"ez2boot/internal/provider/docker"
+	"ez2boot/internal/provider/gcp"
+	"ez2boot/internal/provider/kubernetes"
+	"ez2boot/internal/provider/proxmox"
+	"ez2boot/internal/provider/simulated"
 	"ez2boot/internal/server"
 	"ez2boot/internal/session"
 	"ez2boot/internal/user"
@@ -30,6 +35,11 @@ type Services struct {
 	EmailService        *email.Service
 	AWSService          *aws.Service
 	AzureService        *azure.Service
+	GCPService          *gcp.Service        // Only set when GCP is a configured provider
+	DockerService       *docker.Service     // Only set when Docker is a configured provider
+	KubernetesService   *kubernetes.Service // Only set when Kubernetes is a configured provider
+	ProxmoxService      *proxmox.Service    // Only set when Proxmox is a configured provider
+	SimulatedService    *simulated.Service  // Only set when simulated is a configured provider
 }
 
 type Handlers struct {
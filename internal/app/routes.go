@@ -45,6 +45,10 @@ func SetupBackendRoutes(
 
 	//// Server Sessions
 	adminUIRouter.HandleFunc("/admin/session", handlers.SessionHandler.UpdateServerSessionAdmin()).Methods("PUT")
+	adminUIRouter.HandleFunc("/admin/session", handlers.SessionHandler.EndServerSessionAdmin()).Methods("DELETE")
+	adminUIRouter.HandleFunc("/admin/schedules", handlers.SessionHandler.GetRecurringSchedules()).Methods("GET")
+	adminUIRouter.HandleFunc("/admin/schedule", handlers.SessionHandler.NewRecurringSchedule()).Methods("POST")
+	adminUIRouter.HandleFunc("/admin/schedule", handlers.SessionHandler.DeleteRecurringSchedule()).Methods("DELETE")
 	// User
 	adminUIRouter.HandleFunc("/users", handlers.UserHandler.GetUsers()).Methods("GET")
 	adminUIRouter.HandleFunc("/user", handlers.UserHandler.CreateUser()).Methods("POST")
@@ -77,6 +81,18 @@ func SetupBackendRoutes(
 	uiRouter.HandleFunc("/sessions/summary", handlers.SessionHandler.GetServerSessionSummary()).Methods("GET")
 	uiRouter.HandleFunc("/session", handlers.SessionHandler.NewServerSession()).Methods("POST")
 	uiRouter.HandleFunc("/session", handlers.SessionHandler.UpdateServerSession()).Methods("PUT")
+	uiRouter.HandleFunc("/session", handlers.SessionHandler.EndServerSession()).Methods("DELETE")
+	uiRouter.HandleFunc("/session/owner", handlers.SessionHandler.AddCoOwner()).Methods("POST")
+	uiRouter.HandleFunc("/session/owner", handlers.SessionHandler.RemoveCoOwner()).Methods("DELETE")
+	uiRouter.HandleFunc("/session/handover", handlers.SessionHandler.HandOverServerSession()).Methods("PUT")
+	uiRouter.HandleFunc("/session/waitlist", handlers.SessionHandler.JoinWaitlist()).Methods("POST")
+	uiRouter.HandleFunc("/session/waitlist", handlers.SessionHandler.LeaveWaitlist()).Methods("DELETE")
+	uiRouter.HandleFunc("/sessions/scheduled", handlers.SessionHandler.GetScheduledSessions()).Methods("GET")
+	uiRouter.HandleFunc("/session/scheduled", handlers.SessionHandler.NewScheduledSession()).Methods("POST")
+	uiRouter.HandleFunc("/session/scheduled", handlers.SessionHandler.UpdateScheduledSession()).Methods("PUT")
+	uiRouter.HandleFunc("/session/scheduled", handlers.SessionHandler.CancelScheduledSession()).Methods("DELETE")
+	//// Servers
+	uiRouter.HandleFunc("/servers/history", handlers.ServerHandler.GetServerHistory()).Methods("GET")
 	//// Users
 	uiRouter.HandleFunc("/user/session", handlers.UserHandler.CheckSession()).Methods("GET") // UI specific
 	uiRouter.HandleFunc("/user/auth", handlers.UserHandler.GetUserAuthorisation()).Methods("GET")
@@ -105,6 +121,10 @@ func SetupBackendRoutes(
 
 	//// Server Sessions
 	adminAPIRouter.HandleFunc("/admin/session", handlers.SessionHandler.UpdateServerSessionAdmin()).Methods("PUT")
+	adminAPIRouter.HandleFunc("/admin/session", handlers.SessionHandler.EndServerSessionAdmin()).Methods("DELETE")
+	adminAPIRouter.HandleFunc("/admin/schedules", handlers.SessionHandler.GetRecurringSchedules()).Methods("GET")
+	adminAPIRouter.HandleFunc("/admin/schedule", handlers.SessionHandler.NewRecurringSchedule()).Methods("POST")
+	adminAPIRouter.HandleFunc("/admin/schedule", handlers.SessionHandler.DeleteRecurringSchedule()).Methods("DELETE")
 	// User
 	adminAPIRouter.HandleFunc("/users", handlers.UserHandler.GetUsers()).Methods("GET")
 	adminAPIRouter.HandleFunc("/user", handlers.UserHandler.CreateUser()).Methods("POST")
@@ -136,6 +156,18 @@ func SetupBackendRoutes(
 	//// Server sessions
 	apiRouter.HandleFunc("/session", handlers.SessionHandler.NewServerSession()).Methods("POST")
 	apiRouter.HandleFunc("/session", handlers.SessionHandler.UpdateServerSession()).Methods("PUT")
+	apiRouter.HandleFunc("/session", handlers.SessionHandler.EndServerSession()).Methods("DELETE")
+	apiRouter.HandleFunc("/session/owner", handlers.SessionHandler.AddCoOwner()).Methods("POST")
+	apiRouter.HandleFunc("/session/owner", handlers.SessionHandler.RemoveCoOwner()).Methods("DELETE")
+	apiRouter.HandleFunc("/session/handover", handlers.SessionHandler.HandOverServerSession()).Methods("PUT")
+	apiRouter.HandleFunc("/session/waitlist", handlers.SessionHandler.JoinWaitlist()).Methods("POST")
+	apiRouter.HandleFunc("/session/waitlist", handlers.SessionHandler.LeaveWaitlist()).Methods("DELETE")
+	apiRouter.HandleFunc("/sessions/scheduled", handlers.SessionHandler.GetScheduledSessions()).Methods("GET")
+	apiRouter.HandleFunc("/session/scheduled", handlers.SessionHandler.NewScheduledSession()).Methods("POST")
+	apiRouter.HandleFunc("/session/scheduled", handlers.SessionHandler.UpdateScheduledSession()).Methods("PUT")
+	apiRouter.HandleFunc("/session/scheduled", handlers.SessionHandler.CancelScheduledSession()).Methods("DELETE")
+	//// Servers
+	apiRouter.HandleFunc("/servers/history", handlers.ServerHandler.GetServerHistory()).Methods("GET")
 	//// Users
 	apiRouter.HandleFunc("/user/auth", handlers.UserHandler.GetUserAuthorisation()).Methods("GET")
 	apiRouter.HandleFunc("/user/password", handlers.UserHandler.ChangePassword()).Methods("PUT")
@@ -15,8 +15,15 @@ import (
 	"ez2boot/internal/notification/telegram"
 	"ez2boot/internal/provider/aws"
 	"ez2boot/internal/provider/azure"
+	"ez2boot/internal/provider/docker"
+	"ez2boot/internal/provider/gcp"
+	"ez2boot/internal/provider/kubernetes"
+	"ez2boot/internal/provider/proxmox"
+	"ez2boot/internal/provider/simulated"
+	"ez2boot/internal/readiness"
 	"ez2boot/internal/server"
 	"ez2boot/internal/session"
+	"ez2boot/internal/shared"
 	"ez2boot/internal/user"
 	"ez2boot/internal/util"
 	"ez2boot/internal/worker"
@@ -41,11 +48,6 @@ func InitServices(version string, buildDate string, cfg *config.Config, repo *db
 	auditService := audit.NewService(auditRepo, logger)
 	auditHandler := audit.NewHandler(auditService, logger)
 
-	// Server
-	serverRepo := server.NewRepository(repo)
-	serverService := server.NewService(serverRepo, logger)
-	serverHandler := server.NewHandler(serverService, logger)
-
 	// User
 	userRepo := user.NewRepository(repo, logger)
 	userService := user.NewService(userRepo, cfg, auditService, logger)
@@ -70,9 +72,21 @@ func InitServices(version string, buildDate string, cfg *config.Config, repo *db
 	notificationService := notification.NewService(notificationRepo, auditService, encryptor, logger)
 	notificationHandler := notification.NewHandler(notificationService, logger)
 
+	// Server
+	serverRepo := server.NewRepository(repo)
+	serverService := server.NewService(serverRepo, cfg, notificationService, auditService, logger)
+	serverHandler := server.NewHandler(serverService, logger)
+
+	// Readiness
+	readinessRepo := readiness.NewRepository(repo)
+	readinessService, err := readiness.NewService(readinessRepo, cfg, logger)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
 	// Session
 	sessionRepo := session.NewRepository(repo)
-	sessionService := session.NewService(sessionRepo, cfg, notificationService, userService, auditService, logger)
+	sessionService := session.NewService(sessionRepo, cfg, notificationService, userService, readinessService, auditService, logger)
 	sessionHandler := session.NewHandler(sessionService, cfg, logger)
 
 	// Encryption
@@ -114,6 +128,56 @@ func InitServices(version string, buildDate string, cfg *config.Config, repo *db
 		return nil, nil, nil, nil, err
 	}
 
+	// GCP - client creation requires credentials to be present, so only build when selected
+	var gcpService *gcp.Service
+	if cfg.HasCloudProvider(shared.CloudProviderGCP) {
+		gcpRepo := gcp.NewRepository(repo)
+		gcpService, err = gcp.NewService(gcpRepo, cfg, serverService, logger)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+	}
+
+	// Docker - only build when selected, DOCKER_HOST must be valid
+	var dockerService *docker.Service
+	if cfg.HasCloudProvider(shared.CloudProviderDocker) {
+		dockerRepo := docker.NewRepository(repo)
+		dockerService, err = docker.NewService(dockerRepo, cfg, serverService, logger)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+	}
+
+	// Kubernetes - client creation requires a kubeconfig or in-cluster service account
+	var kubernetesService *kubernetes.Service
+	if cfg.HasCloudProvider(shared.CloudProviderKubernetes) {
+		kubernetesRepo := kubernetes.NewRepository(repo)
+		kubernetesService, err = kubernetes.NewService(kubernetesRepo, cfg, serverService, logger)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+	}
+
+	// Proxmox
+	var proxmoxService *proxmox.Service
+	if cfg.HasCloudProvider(shared.CloudProviderProxmox) {
+		proxmoxRepo := proxmox.NewRepository(repo)
+		proxmoxService, err = proxmox.NewService(proxmoxRepo, cfg, serverService, logger)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+	}
+
+	// Simulated - in-memory fleet for demos and tests
+	var simulatedService *simulated.Service
+	if cfg.HasCloudProvider(shared.CloudProviderSimulated) {
+		simulatedRepo := simulated.NewRepository(repo)
+		simulatedService, err = simulated.NewService(simulatedRepo, cfg, serverService, logger)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+	}
+
 	// Middlware
 	mw := middleware.NewMiddleware(userService, cfg, logger)
 
@@ -148,6 +212,11 @@ func InitServices(version string, buildDate string, cfg *config.Config, repo *db
 		EmailService:        emailService,
 		AWSService:          awsService,
 		AzureService:        azureService,
+		GCPService:          gcpService,
+		DockerService:       dockerService,
+		KubernetesService:   kubernetesService,
+		ProxmoxService:      proxmoxService,
+		SimulatedService:    simulatedService,
 	}
 
 	return mw, wkr, handlers, services, nil
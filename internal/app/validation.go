@@ -3,22 +3,49 @@ package app
 import (
 	"errors"
 	"ez2boot/internal/config"
+	"ez2boot/internal/shared"
 	"fmt"
 )
 
 // Validates required env vars have been provided
 func validateProviderConfig(cfg *config.Config) error {
-	switch cfg.CloudProvider {
-	case "aws":
-		if cfg.AWSRegion == "" {
-			return errors.New("AWS_REGION environment variable is required")
+	if len(cfg.CloudProviders) == 0 {
+		return errors.New("CLOUD_PROVIDER environment variable is required")
+	}
+
+	seen := make(map[string]bool)
+	for _, cloudProvider := range cfg.CloudProviders {
+		if seen[cloudProvider] {
+			return fmt.Errorf("duplicate value for CLOUD_PROVIDER: %s", cloudProvider)
 		}
-	case "azure":
-		if cfg.AzureSubscriptionID == "" {
-			return errors.New("AZURE_SUBSCRIPTION_ID is required")
+		seen[cloudProvider] = true
+
+		switch cloudProvider {
+		case shared.CloudProviderAWS:
+			if len(cfg.AWSTargets) == 0 {
+				return errors.New("AWS_REGION or AWS_TARGETS environment variable is required")
+			}
+		case shared.CloudProviderAzure:
+			if len(cfg.AzureSubscriptions) == 0 {
+				return errors.New("AZURE_SUBSCRIPTION_ID or AZURE_SUBSCRIPTIONS is required")
+			}
+		case shared.CloudProviderGCP:
+			if cfg.GCPProjectID == "" {
+				return errors.New("GCP_PROJECT_ID is required")
+			}
+		case shared.CloudProviderDocker:
+			// DOCKER_HOST has a default
+		case shared.CloudProviderKubernetes:
+			// Connection comes from KUBECONFIG or the in-cluster service account
+		case shared.CloudProviderProxmox:
+			if cfg.ProxmoxURL == "" || cfg.ProxmoxTokenID == "" || cfg.ProxmoxTokenSecret == "" {
+				return errors.New("PROXMOX_URL, PROXMOX_TOKEN_ID and PROXMOX_TOKEN_SECRET are required")
+			}
+		case shared.CloudProviderSimulated:
+			// Built in demo fleet used without SIMULATED_FLEET_FILE
+		default:
+			return fmt.Errorf("unsupported value for CLOUD_PROVIDER (supported aws, azure, gcp, docker, kubernetes, proxmox, simulated): %s", cloudProvider)
 		}
-	default:
-		return fmt.Errorf("unsupported value for CLOUD_PROVIDER (supported aws, azure): %s", cfg.CloudProvider)
 	}
 
 	return nil
@@ -7,23 +7,50 @@ import (
 )
 
 type Config struct {
-	SetupMode                bool          // Mode which allows initial user bootstrap, not manually setable
-	TrustProxyHeaders        bool          // Affects source IP address recognition within middleware
-	CloudProvider            string        // Cloud provider eg aws, azure
-	Port                     string        // Listener port for this application
-	ScrapeInterval           time.Duration // Interval for scraping cloud provider
-	InternalClock            time.Duration // Interval for all other background workers
-	TagKey                   string        // Tag Key used to itentify target servers, where the values are the server groups
-	AWSRegion                string        // AWS Region, AWS scrape specific
-	UserSessionDuration      time.Duration // Duration for user UI authenticated session, not related to server session duration
-	MaxServerSessionDuration time.Duration // Maximum duration for a server session
-	LogLevel                 slog.Level    // Logging level, use info unless debugging
-	EncryptionPhrase         string        // Implementation specific encryption phrase used to derive an encryption key to encrypt sensitive credentials within the app
-	PublicRateLimit          int           // Max number of requests per second allowed by each user (IP) of this application to public routes
-	PrivateRateLimit         int           // Max number of requests per second allowed by each user (IP) of this application to authenticated routes
-	ShowBetaVersions         bool          // UI will show alert for beta releases and not just full releases
-	AzureSubscriptionID      string        // Azure subscription ID, Azure scrape specific
-	SecureCookie             bool          // Session cookie parameter. Browser will send cookie over https only - affects insecure http login
-	SameSiteMode             http.SameSite // Session cookie parameter. Controls when the browser will send cookie
+	SetupMode                bool                // Mode which allows initial user bootstrap, not manually setable
+	TrustProxyHeaders        bool                // Affects source IP address recognition within middleware
+	CloudProviders           []string            // Cloud providers to scrape and manage eg aws, azure, gcp, docker, kubernetes, proxmox, simulated. Servers of providers removed from the list are removed at startup
+	Port                     string              // Listener port for this application
+	ScrapeInterval           time.Duration       // Interval for scraping cloud provider
+	InternalClock            time.Duration       // Interval for all other background workers
+	TagKey                   string              // Tag Key used to itentify target servers, where the values are the server groups
+	AWSRegion                string              // AWS Region, AWS scrape specific
+	AWSTargets               []AWSTarget         // AWS account and region pairs to scrape, defaults to AWSRegion with ambient credentials
+	UserSessionDuration      time.Duration       // Duration for user UI authenticated session, not related to server session duration
+	MaxServerSessionDuration time.Duration       // Maximum duration for a server session
+	ServerMaxAttempts        int                 // Failed start or stop attempts per server before giving up
+	ServerRetryBackoff       time.Duration       // Wait after the first failed attempt, doubles with each further failure
+	ServerTransitionTimeout  time.Duration       // Time a server group may take to reach the requested state before its session is flagged as failed
+	DriftPolicy              string              // What to do with server groups running without a session, ignore (default), alert, adopt or off. Overridden per group by tag. Adopted sessions are owned by the longest standing active admin
+	DriftGracePeriod         time.Duration       // Length of an adopted session, or wait before stopping drifted servers
+	WaitlistClaimWindow      time.Duration       // Time a notified waitlist user has to start a session before the next user is served
+	LogLevel                 slog.Level          // Logging level, use info unless debugging
+	EncryptionPhrase         string              // Implementation specific encryption phrase used to derive an encryption key to encrypt sensitive credentials within the app
+	PublicRateLimit          int                 // Max number of requests per second allowed by each user (IP) of this application to public routes
+	PrivateRateLimit         int                 // Max number of requests per second allowed by each user (IP) of this application to authenticated routes
+	ShowBetaVersions         bool                // UI will show alert for beta releases and not just full releases
+	AzureSubscriptionID      string              // Azure subscription ID, Azure scrape specific
+	AzureSubscriptions       []AzureSubscription // Azure subscriptions to scrape, defaults to AzureSubscriptionID across all resource groups
+	GCPProjectID             string              // GCP project ID, GCP scrape specific
+	DockerHost               string              // Docker Engine API endpoint eg unix:///var/run/docker.sock, Docker scrape specific
+	KubernetesNamespaces     []string            // Namespaces to scrape for workloads, all namespaces if empty
+	ProxmoxURL               string              // Proxmox VE API address eg https://pve.example.com:8006, Proxmox scrape specific
+	ProxmoxTokenID           string              // Proxmox API token ID eg ez2boot@pve!ez2boot
+	ProxmoxTokenSecret       string              // Proxmox API token secret
+	ProxmoxSkipTLSVerify     bool                // Accept self signed Proxmox certificates
+	SimulatedFleetFile       string              // YAML or JSON fleet definition for the simulated provider, built in demo fleet if empty
+	ReadinessProbeFile       string              // YAML or JSON probes which must pass before a session is ready, none if empty
+	SecureCookie             bool                // Session cookie parameter. Browser will send cookie over https only - affects insecure http login
+	SameSiteMode             http.SameSite       // Session cookie parameter. Controls when the browser will send cookie
 	// Add more fields as needed
 }
+
+type AzureSubscription struct {
+	ID             string   // Azure subscription ID to scrape and manage
+	ResourceGroups []string // Optional, limits the scrape to these resource groups
+}
+
+type AWSTarget struct {
+	Region  string // AWS Region to scrape and manage
+	RoleARN string // Optional IAM role assumed through STS, used to reach other accounts
+}
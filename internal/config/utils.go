@@ -1,8 +1,11 @@
 package config
 
 import (
+	"ez2boot/internal/shared"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 )
@@ -17,6 +20,71 @@ func GetDurationFromString(strValue string) (time.Duration, error) {
 	return duration, nil
 }
 
+// Split a comma separated list, trimming whitespace and dropping empty entries
+func ParseList(strValue string) []string {
+	items := []string{}
+	for _, item := range strings.Split(strValue, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// Parse AWS targets from a comma separated list of region or region@roleARN eg "ap-southeast-2,us-east-1@arn:aws:iam::111122223333:role/ez2boot"
+func ParseAWSTargets(strValue string) ([]AWSTarget, error) {
+	targets := []AWSTarget{}
+	for _, item := range ParseList(strValue) {
+		region, roleARN, _ := strings.Cut(item, "@")
+		if region == "" {
+			return nil, fmt.Errorf("invalid AWS target, region missing: %s", item)
+		}
+
+		if roleARN != "" && !strings.HasPrefix(roleARN, "arn:") {
+			return nil, fmt.Errorf("invalid AWS target, role is not an ARN: %s", item)
+		}
+
+		targets = append(targets, AWSTarget{
+			Region:  region,
+			RoleARN: roleARN,
+		})
+	}
+
+	return targets, nil
+}
+
+// Parse Azure subscriptions from a comma separated list of subscription or subscription@rg1|rg2 eg "sub-a,sub-b@rg-qa|rg-dev"
+func ParseAzureSubscriptions(strValue string) ([]AzureSubscription, error) {
+	subscriptions := []AzureSubscription{}
+	for _, item := range ParseList(strValue) {
+		id, groups, _ := strings.Cut(item, "@")
+		if id == "" {
+			return nil, fmt.Errorf("invalid Azure subscription, ID missing: %s", item)
+		}
+
+		resourceGroups := []string{}
+		for _, rg := range strings.Split(groups, "|") {
+			if rg = strings.TrimSpace(rg); rg != "" {
+				resourceGroups = append(resourceGroups, rg)
+			}
+		}
+
+		subscriptions = append(subscriptions, AzureSubscription{
+			ID:             id,
+			ResourceGroups: resourceGroups,
+		})
+	}
+
+	return subscriptions, nil
+}
+
+// Check whether a cloud provider has been configured
+func (c *Config) HasCloudProvider(name string) bool {
+	return slices.Contains(c.CloudProviders, name)
+}
+
 func ParseLogLevel(strValue string) slog.Level {
 	switch strings.ToLower(strValue) {
 	case "debug":
@@ -46,3 +114,13 @@ func ParseSameSiteMode(strValue string) http.SameSite {
 		return http.SameSiteLaxMode
 	}
 }
+
+// Parse a drift policy, one of ignore, alert, adopt or off
+func ParseDriftPolicy(strValue string) (string, error) {
+	policy := strings.ToLower(strings.TrimSpace(strValue))
+	if !slices.Contains(shared.DriftPolicies, policy) {
+		return "", fmt.Errorf("invalid drift policy: %s", strValue)
+	}
+
+	return policy, nil
+}
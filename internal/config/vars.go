@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -28,6 +29,8 @@ func GetEnvVars() (*Config, error) {
 		cloudProvider = "aws" // default
 	}
 
+	cloudProviders := ParseList(strings.ToLower(cloudProvider))
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8000" //default
@@ -60,6 +63,16 @@ func GetEnvVars() (*Config, error) {
 
 	awsRegion := os.Getenv("AWS_REGION") // "" default
 
+	awsTargets, err := ParseAWSTargets(os.Getenv("AWS_TARGETS"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Single target from AWS_REGION when no targets listed
+	if len(awsTargets) == 0 && awsRegion != "" {
+		awsTargets = []AWSTarget{{Region: awsRegion}}
+	}
+
 	userSessionDurationStr := os.Getenv("USER_SESSION_DURATION")
 	if userSessionDurationStr == "" {
 		userSessionDurationStr = "6h" //default
@@ -80,6 +93,66 @@ func GetEnvVars() (*Config, error) {
 		return nil, err
 	}
 
+	serverMaxAttemptsStr := os.Getenv("SERVER_MAX_ATTEMPTS")
+	if serverMaxAttemptsStr == "" {
+		serverMaxAttemptsStr = "3" //default
+	}
+
+	serverMaxAttempts, err := strconv.Atoi(serverMaxAttemptsStr)
+	if err != nil {
+		return nil, err
+	}
+
+	serverRetryBackoffStr := os.Getenv("SERVER_RETRY_BACKOFF")
+	if serverRetryBackoffStr == "" {
+		serverRetryBackoffStr = "1m" //default
+	}
+
+	serverRetryBackoff, err := GetDurationFromString(serverRetryBackoffStr)
+	if err != nil {
+		return nil, err
+	}
+
+	serverTransitionTimeoutStr := os.Getenv("SERVER_TRANSITION_TIMEOUT")
+	if serverTransitionTimeoutStr == "" {
+		serverTransitionTimeoutStr = "30m" //default
+	}
+
+	serverTransitionTimeout, err := GetDurationFromString(serverTransitionTimeoutStr)
+	if err != nil {
+		return nil, err
+	}
+
+	driftPolicyStr := os.Getenv("DRIFT_POLICY")
+	if driftPolicyStr == "" {
+		driftPolicyStr = "ignore" //default
+	}
+
+	driftPolicy, err := ParseDriftPolicy(driftPolicyStr)
+	if err != nil {
+		return nil, err
+	}
+
+	driftGracePeriodStr := os.Getenv("DRIFT_GRACE_PERIOD")
+	if driftGracePeriodStr == "" {
+		driftGracePeriodStr = "15m" //default
+	}
+
+	driftGracePeriod, err := GetDurationFromString(driftGracePeriodStr)
+	if err != nil {
+		return nil, err
+	}
+
+	waitlistClaimWindowStr := os.Getenv("WAITLIST_CLAIM_WINDOW")
+	if waitlistClaimWindowStr == "" {
+		waitlistClaimWindowStr = "10m" //default
+	}
+
+	waitlistClaimWindow, err := GetDurationFromString(waitlistClaimWindowStr)
+	if err != nil {
+		return nil, err
+	}
+
 	logLevelStr := os.Getenv("LOG_LEVEL")
 	if logLevelStr == "" {
 		logLevelStr = "info" //default
@@ -120,6 +193,44 @@ func GetEnvVars() (*Config, error) {
 	}
 
 	azureSubscriptionID := os.Getenv("AZURE_SUBSCRIPTION_ID") // "" default
+
+	azureSubscriptions, err := ParseAzureSubscriptions(os.Getenv("AZURE_SUBSCRIPTIONS"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Single subscription from AZURE_SUBSCRIPTION_ID when no subscriptions listed
+	if len(azureSubscriptions) == 0 && azureSubscriptionID != "" {
+		azureSubscriptions = []AzureSubscription{{ID: azureSubscriptionID}}
+	}
+
+	gcpProjectID := os.Getenv("GCP_PROJECT_ID") // "" default
+
+	dockerHost := os.Getenv("DOCKER_HOST")
+	if dockerHost == "" {
+		dockerHost = "unix:///var/run/docker.sock" //default
+	}
+
+	kubernetesNamespaces := ParseList(os.Getenv("KUBERNETES_NAMESPACES")) // Empty default, all namespaces
+
+	proxmoxURL := os.Getenv("PROXMOX_URL")                  // "" default
+	proxmoxTokenID := os.Getenv("PROXMOX_TOKEN_ID")         // "" default
+	proxmoxTokenSecret := os.Getenv("PROXMOX_TOKEN_SECRET") // "" default
+
+	proxmoxSkipTLSVerifyStr := os.Getenv("PROXMOX_SKIP_TLS_VERIFY")
+	if proxmoxSkipTLSVerifyStr == "" {
+		proxmoxSkipTLSVerifyStr = "false" //default
+	}
+
+	proxmoxSkipTLSVerify, err := strconv.ParseBool(proxmoxSkipTLSVerifyStr)
+	if err != nil {
+		return nil, err
+	}
+
+	simulatedFleetFile := os.Getenv("SIMULATED_FLEET_FILE") // "" default, uses built in demo fleet
+
+	readinessProbeFile := os.Getenv("READINESS_PROBE_FILE") // "" default, no probes
+
 	secureCookieStr := os.Getenv("SECURE_COOKIE")
 	if secureCookieStr == "" {
 		secureCookieStr = "false" //default
@@ -139,20 +250,37 @@ func GetEnvVars() (*Config, error) {
 
 	cfg := &Config{
 		TrustProxyHeaders:        trustProxyHeaders,
-		CloudProvider:            cloudProvider,
+		CloudProviders:           cloudProviders,
 		Port:                     port,
 		ScrapeInterval:           scrapeInterval,
 		InternalClock:            internalClock,
 		TagKey:                   tagKey,
 		AWSRegion:                awsRegion,
+		AWSTargets:               awsTargets,
 		UserSessionDuration:      userSessionDuration,
 		MaxServerSessionDuration: maxServerSessionDuration,
+		ServerMaxAttempts:        serverMaxAttempts,
+		ServerRetryBackoff:       serverRetryBackoff,
+		ServerTransitionTimeout:  serverTransitionTimeout,
+		DriftPolicy:              driftPolicy,
+		DriftGracePeriod:         driftGracePeriod,
+		WaitlistClaimWindow:      waitlistClaimWindow,
 		LogLevel:                 logLevel,
 		EncryptionPhrase:         encryptionPhrase,
 		PublicRateLimit:          publicrateLimit,
 		PrivateRateLimit:         privaterateLimit,
 		ShowBetaVersions:         showBetaVersions,
 		AzureSubscriptionID:      azureSubscriptionID,
+		AzureSubscriptions:       azureSubscriptions,
+		GCPProjectID:             gcpProjectID,
+		DockerHost:               dockerHost,
+		KubernetesNamespaces:     kubernetesNamespaces,
+		ProxmoxURL:               proxmoxURL,
+		ProxmoxTokenID:           proxmoxTokenID,
+		ProxmoxTokenSecret:       proxmoxTokenSecret,
+		ProxmoxSkipTLSVerify:     proxmoxSkipTLSVerify,
+		SimulatedFleetFile:       simulatedFleetFile,
+		ReadinessProbeFile:       readinessProbeFile,
 		SecureCookie:             secureCookie,
 		SameSiteMode:             sameSiteMode,
 	}
@@ -15,6 +15,24 @@ type Migration struct {
 var migrations = []Migration{
 	// Add numbered migration statements as needed eg:
     //{Version: 1, SQL: `ALTER TABLE...`},
+	{Version: 1, SQL: `ALTER TABLE servers ADD COLUMN provider TEXT NOT NULL DEFAULT ''`},
+	{Version: 2, SQL: `ALTER TABLE servers ADD COLUMN last_error TEXT`},
+	{Version: 3, SQL: `ALTER TABLE servers ADD COLUMN last_error_time INTEGER`},
+	{Version: 4, SQL: `ALTER TABLE servers ADD COLUMN instance_type TEXT NOT NULL DEFAULT ''`},
+	{Version: 5, SQL: `ALTER TABLE servers ADD COLUMN private_ip TEXT NOT NULL DEFAULT ''`},
+	{Version: 6, SQL: `ALTER TABLE servers ADD COLUMN public_ip TEXT NOT NULL DEFAULT ''`},
+	{Version: 7, SQL: `ALTER TABLE servers ADD COLUMN zone TEXT NOT NULL DEFAULT ''`},
+	{Version: 8, SQL: `ALTER TABLE servers ADD COLUMN platform TEXT NOT NULL DEFAULT ''`},
+	{Version: 9, SQL: `ALTER TABLE servers ADD COLUMN launch_time INTEGER NOT NULL DEFAULT 0`},
+	{Version: 10, SQL: `ALTER TABLE servers ADD COLUMN boot_order INTEGER NOT NULL DEFAULT 0`},
+	{Version: 11, SQL: `ALTER TABLE servers ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0`},
+	{Version: 12, SQL: `ALTER TABLE servers ADD COLUMN next_attempt INTEGER NOT NULL DEFAULT 0`},
+	{Version: 13, SQL: `ALTER TABLE server_sessions ADD COLUMN failed INTEGER NOT NULL DEFAULT 0 CHECK (failed IN (0, 1))`},
+	{Version: 14, SQL: `ALTER TABLE server_sessions ADD COLUMN failure_reason TEXT`},
+	{Version: 15, SQL: `ALTER TABLE server_sessions ADD COLUMN orphan INTEGER NOT NULL DEFAULT 0 CHECK (orphan IN (0, 1))`},
+	{Version: 16, SQL: `ALTER TABLE server_sessions ADD COLUMN schedule_id INTEGER`},
+	{Version: 17, SQL: `ALTER TABLE servers ADD COLUMN region TEXT NOT NULL DEFAULT ''`},
+	{Version: 18, SQL: `ALTER TABLE servers ADD COLUMN target TEXT NOT NULL DEFAULT ''`},
 }
 
 func (r *Repository) SetupDB() error {
@@ -84,6 +102,51 @@ func (r *Repository) createTables() error {
 		return err
 	}
 
+	// create table for server group companion tags, replaced per provider on each scrape
+	if _, err := r.DB.Exec("CREATE TABLE IF NOT EXISTS server_group_tags (server_group TEXT NOT NULL, provider TEXT NOT NULL, name TEXT NOT NULL, value TEXT NOT NULL, PRIMARY KEY (server_group, provider, name))"); err != nil {
+		return err
+	}
+
+	// create table for the latest readiness probe results per server group
+	if _, err := r.DB.Exec("CREATE TABLE IF NOT EXISTS probe_results (server_group TEXT NOT NULL, position INTEGER NOT NULL, probe TEXT NOT NULL, success INTEGER NOT NULL CHECK (success IN (0, 1)), message TEXT NOT NULL, checked_at INTEGER NOT NULL, PRIMARY KEY (server_group, position))"); err != nil {
+		return err
+	}
+
+	// create table for server groups found running without a session
+	if _, err := r.DB.Exec("CREATE TABLE IF NOT EXISTS server_drift (server_group TEXT PRIMARY KEY, policy TEXT NOT NULL, detected_at INTEGER NOT NULL, enforced INTEGER NOT NULL DEFAULT 0 CHECK (enforced IN (0, 1)))"); err != nil {
+		return err
+	}
+
+	// create table for observed server state changes
+	if _, err := r.DB.Exec("CREATE TABLE IF NOT EXISTS server_history (id INTEGER PRIMARY KEY AUTOINCREMENT, unique_id TEXT NOT NULL, name TEXT NOT NULL, server_group TEXT NOT NULL, provider TEXT NOT NULL, state TEXT NOT NULL, time_stamp INTEGER NOT NULL)"); err != nil {
+		return err
+	}
+
+	// index for finding each server's changes in time order when building history
+	if _, err := r.DB.Exec("CREATE INDEX IF NOT EXISTS idx_server_history_unique_id_time_stamp ON server_history (unique_id, time_stamp)"); err != nil {
+		return err
+	}
+
+	// create table for server sessions booked to start in the future
+	if _, err := r.DB.Exec("CREATE TABLE IF NOT EXISTS scheduled_sessions (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE, server_group TEXT NOT NULL, start_time INTEGER NOT NULL, duration TEXT NOT NULL, expiry INTEGER NOT NULL, time_added INTEGER NOT NULL)"); err != nil {
+		return err
+	}
+
+	// create table for recurring time windows which open server sessions automatically
+	if _, err := r.DB.Exec("CREATE TABLE IF NOT EXISTS recurring_schedules (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE, server_group TEXT NOT NULL, days TEXT NOT NULL, start_time TEXT NOT NULL, end_time TEXT NOT NULL, time_zone TEXT NOT NULL, last_opened INTEGER NOT NULL DEFAULT 0, time_added INTEGER NOT NULL)"); err != nil {
+		return err
+	}
+
+	// create table for users waiting on a busy server group, served in id order
+	if _, err := r.DB.Exec("CREATE TABLE IF NOT EXISTS waitlist (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE, server_group TEXT NOT NULL, duration TEXT NOT NULL DEFAULT '', auto_start INTEGER NOT NULL DEFAULT 0 CHECK (auto_start IN (0, 1)), time_added INTEGER NOT NULL, notified_at INTEGER NOT NULL DEFAULT 0, UNIQUE (user_id, server_group))"); err != nil {
+		return err
+	}
+
+	// create table for users sharing a server session with its owner
+	if _, err := r.DB.Exec("CREATE TABLE IF NOT EXISTS session_owners (server_group TEXT NOT NULL, user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE, time_added INTEGER NOT NULL, PRIMARY KEY (server_group, user_id))"); err != nil {
+		return err
+	}
+
 	// create table for version
 	if _, err := r.DB.Exec("CREATE TABLE IF NOT EXISTS release (id INTEGER PRIMARY KEY, latest_release TEXT, latest_prerelease TEXT, checked_at INTEGER, release_url TEXT, prerelease_url TEXT)"); err != nil {
 		return err
@@ -0,0 +1,171 @@
+package aws
+
+import (
+	"context"
+	"ez2boot/internal/server"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	astypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+)
+
+// Describe tagged Auto Scaling groups within a single target, following pagination
+func (s *Service) scrapeAutoScalingGroups(target *Target) ([]server.Server, error) {
+	input := &autoscaling.DescribeAutoScalingGroupsInput{
+		Filters: []astypes.Filter{
+			{
+				Name:   aws.String("tag-key"),
+				Values: []string{s.Config.TagKey},
+			},
+		},
+	}
+
+	servers := []server.Server{}
+	paginator := autoscaling.NewDescribeAutoScalingGroupsPaginator(target.ASGClient, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, group := range page.AutoScalingGroups {
+			svr := server.Server{
+				UniqueID:    aws.ToString(group.AutoScalingGroupARN),
+				Name:        aws.ToString(group.AutoScalingGroupName),
+				State:       mapASGState(group),
+				ServerGroup: getASGTagValue(group, s.Config.TagKey),
+				TimeAdded:   time.Now().Unix(),
+				GroupTags:   getASGGroupTags(group, s.Config.TagKey),
+				BootOrder:   server.ParseBootOrder(getASGTagValue(group, server.GetGroupTagKey(s.Config.TagKey, server.TagBootOrder))),
+			}
+
+			servers = append(servers, svr)
+		}
+	}
+
+	return servers, nil
+}
+
+// Restore the capacity saved when the group was scaled to zero
+func (s *Service) startAutoScalingGroup(target *Target, id string) error {
+	name, err := getASGNameFromARN(id)
+	if err != nil {
+		return err
+	}
+
+	group, err := s.describeAutoScalingGroup(target, name)
+	if err != nil {
+		return err
+	}
+
+	// Never scaled down by ez2boot, bring up a single instance
+	minSize, desired := int32(0), int32(1)
+	tagKey := getSavedCapacityTagKey(s.Config.TagKey)
+	if saved := getASGTagValue(group, tagKey); saved != "" {
+		minSize, desired, err = parseSavedCapacity(saved)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Max may have been lowered while the group was off
+	desired = min(desired, aws.ToInt32(group.MaxSize))
+	minSize = min(minSize, desired)
+
+	if _, err := target.ASGClient.UpdateAutoScalingGroup(context.Background(), &autoscaling.UpdateAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String(name),
+		MinSize:              aws.Int32(minSize),
+		DesiredCapacity:      aws.Int32(desired),
+	}); err != nil {
+		return fmt.Errorf("failed to restore capacity: %w", err)
+	}
+
+	if _, err := target.ASGClient.DeleteTags(context.Background(), &autoscaling.DeleteTagsInput{
+		Tags: []astypes.Tag{{
+			ResourceId:   aws.String(name),
+			ResourceType: aws.String("auto-scaling-group"),
+			Key:          aws.String(tagKey),
+		}},
+	}); err != nil {
+		s.Logger.Warn("Failed to remove saved capacity tag", "name", name, "target", target.String(), "domain", "aws", "error", err)
+	}
+
+	return nil
+}
+
+// Save the current capacity in a tag then scale the group to zero
+func (s *Service) stopAutoScalingGroup(target *Target, id string) error {
+	name, err := getASGNameFromARN(id)
+	if err != nil {
+		return err
+	}
+
+	group, err := s.describeAutoScalingGroup(target, name)
+	if err != nil {
+		return err
+	}
+
+	minSize := aws.ToInt32(group.MinSize)
+	desired := aws.ToInt32(group.DesiredCapacity)
+
+	// Already scaled down, keep whatever capacity was saved before
+	if minSize == 0 && desired == 0 {
+		return nil
+	}
+
+	if _, err := target.ASGClient.CreateOrUpdateTags(context.Background(), &autoscaling.CreateOrUpdateTagsInput{
+		Tags: []astypes.Tag{{
+			ResourceId:        aws.String(name),
+			ResourceType:      aws.String("auto-scaling-group"),
+			Key:               aws.String(getSavedCapacityTagKey(s.Config.TagKey)),
+			Value:             aws.String(formatSavedCapacity(minSize, desired)),
+			PropagateAtLaunch: aws.Bool(false),
+		}},
+	}); err != nil {
+		return fmt.Errorf("failed to save capacity: %w", err)
+	}
+
+	if _, err := target.ASGClient.UpdateAutoScalingGroup(context.Background(), &autoscaling.UpdateAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String(name),
+		MinSize:              aws.Int32(0),
+		DesiredCapacity:      aws.Int32(0),
+	}); err != nil {
+		return fmt.Errorf("failed to scale to zero: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) describeAutoScalingGroup(target *Target, name string) (astypes.AutoScalingGroup, error) {
+	out, err := target.ASGClient.DescribeAutoScalingGroups(context.Background(), &autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []string{name},
+	})
+	if err != nil {
+		return astypes.AutoScalingGroup{}, err
+	}
+
+	if len(out.AutoScalingGroups) == 0 {
+		return astypes.AutoScalingGroup{}, fmt.Errorf("Auto Scaling group %s not found", name)
+	}
+
+	return out.AutoScalingGroups[0], nil
+}
+
+func getASGGroupTags(group astypes.AutoScalingGroup, tagKey string) map[string]string {
+	tags := make(map[string]string, len(group.Tags))
+	for _, tag := range group.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return server.GetGroupTags(tagKey, tags)
+}
+
+func getASGTagValue(group astypes.AutoScalingGroup, tagKey string) string {
+	for _, tag := range group.Tags {
+		if aws.ToString(tag.Key) == tagKey {
+			return aws.ToString(tag.Value)
+		}
+	}
+	return ""
+}
@@ -8,27 +8,66 @@ import (
 	"fmt"
 	"log/slog"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
 func NewService(awsRepo *Repository, cfg *config.Config, serverService *server.Service, logger *slog.Logger) (*Service, error) {
-	awsCFG, err := awsconfig.LoadDefaultConfig(context.Background(), awsconfig.WithRegion(cfg.AWSRegion))
-	if err != nil {
-		return nil, fmt.Errorf("Failed to load AWS config %w", err)
-	}
+	targets := []*Target{}
+	for _, t := range cfg.AWSTargets {
+		target, err := newTarget(t)
+		if err != nil {
+			return nil, err
+		}
 
-	ec2Client := ec2.NewFromConfig(awsCFG)
+		targets = append(targets, target)
+	}
 
 	return &Service{
 		Repo:          awsRepo,
 		Config:        cfg,
 		ServerService: serverService,
-		EC2Client:     ec2Client,
+		Targets:       targets,
 		Logger:        logger,
 	}, nil
 }
 
+// Build a target client, assuming the configured role through STS when set
+func newTarget(t config.AWSTarget) (*Target, error) {
+	awsCFG, err := awsconfig.LoadDefaultConfig(context.Background(), awsconfig.WithRegion(t.Region))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load AWS config %w", err)
+	}
+
+	var accountID string
+	if t.RoleARN != "" {
+		accountID, err = getAccountIDFromARN(t.RoleARN)
+		if err != nil {
+			return nil, err
+		}
+
+		// Credentials are retrieved and refreshed on demand
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(awsCFG), t.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = "ez2boot"
+		})
+		awsCFG.Credentials = aws.NewCredentialsCache(provider)
+	}
+
+	return &Target{
+		Region:    t.Region,
+		RoleARN:   t.RoleARN,
+		AccountID: accountID,
+		EC2Client: ec2.NewFromConfig(awsCFG),
+		ASGClient: autoscaling.NewFromConfig(awsCFG),
+		RDSClient: rds.NewFromConfig(awsCFG),
+	}, nil
+}
+
 func NewRepository(base *db.Repository) *Repository {
 	return &Repository{
 		Base: base,
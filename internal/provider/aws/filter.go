@@ -6,9 +6,13 @@ import (
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 )
 
-// Filter for opt-in instances
+// Max instance IDs sent in a single start or stop call
+const maxBatchSize = 100
+
+// Filter for opt-in instances, page size set explicitly so large accounts return a next token
 func getDescribeInstancesInput(tagKey string) *ec2.DescribeInstancesInput {
 	return &ec2.DescribeInstancesInput{
+		MaxResults: aws.Int32(1000),
 		Filters: []ec2types.Filter{
 			{
 				Name:   aws.String("tag-key"),
@@ -17,3 +21,18 @@ func getDescribeInstancesInput(tagKey string) *ec2.DescribeInstancesInput {
 		},
 	}
 }
+
+// Split instance IDs into batches of at most size
+func getBatches(ids []string, size int) [][]string {
+	batches := [][]string{}
+	for len(ids) > size {
+		batches = append(batches, ids[:size])
+		ids = ids[size:]
+	}
+
+	if len(ids) > 0 {
+		batches = append(batches, ids)
+	}
+
+	return batches
+}
@@ -1,12 +1,15 @@
 package aws
 
 import (
+	"context"
 	"ez2boot/internal/config"
 	"ez2boot/internal/db"
 	"ez2boot/internal/server"
 	"log/slog"
 
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
 )
 
 type Repository struct {
@@ -17,6 +20,51 @@ type Service struct {
 	Repo          *Repository
 	Config        *config.Config
 	ServerService *server.Service
-	EC2Client     *ec2.Client
+	Targets       []*Target
 	Logger        *slog.Logger
 }
+
+// An account and region pair with a client using the credentials for that account
+type Target struct {
+	Region    string
+	RoleARN   string // Empty when using ambient credentials
+	AccountID string // Empty when using ambient credentials
+	EC2Client EC2API
+	ASGClient AutoScalingAPI
+	RDSClient RDSAPI
+}
+
+// Subset of the EC2 API used by this provider. Allows a fake client to be used in tests
+type EC2API interface {
+	DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+	StartInstances(ctx context.Context, params *ec2.StartInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error)
+	StopInstances(ctx context.Context, params *ec2.StopInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error)
+}
+
+// Subset of the Auto Scaling API used by this provider
+type AutoScalingAPI interface {
+	DescribeAutoScalingGroups(ctx context.Context, params *autoscaling.DescribeAutoScalingGroupsInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DescribeAutoScalingGroupsOutput, error)
+	UpdateAutoScalingGroup(ctx context.Context, params *autoscaling.UpdateAutoScalingGroupInput, optFns ...func(*autoscaling.Options)) (*autoscaling.UpdateAutoScalingGroupOutput, error)
+	CreateOrUpdateTags(ctx context.Context, params *autoscaling.CreateOrUpdateTagsInput, optFns ...func(*autoscaling.Options)) (*autoscaling.CreateOrUpdateTagsOutput, error)
+	DeleteTags(ctx context.Context, params *autoscaling.DeleteTagsInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DeleteTagsOutput, error)
+}
+
+// Subset of the RDS API used by this provider, covers DB instances and Aurora clusters
+type RDSAPI interface {
+	DescribeDBInstances(ctx context.Context, params *rds.DescribeDBInstancesInput, optFns ...func(*rds.Options)) (*rds.DescribeDBInstancesOutput, error)
+	DescribeDBClusters(ctx context.Context, params *rds.DescribeDBClustersInput, optFns ...func(*rds.Options)) (*rds.DescribeDBClustersOutput, error)
+	StartDBInstance(ctx context.Context, params *rds.StartDBInstanceInput, optFns ...func(*rds.Options)) (*rds.StartDBInstanceOutput, error)
+	StopDBInstance(ctx context.Context, params *rds.StopDBInstanceInput, optFns ...func(*rds.Options)) (*rds.StopDBInstanceOutput, error)
+	StartDBCluster(ctx context.Context, params *rds.StartDBClusterInput, optFns ...func(*rds.Options)) (*rds.StartDBClusterOutput, error)
+	StopDBCluster(ctx context.Context, params *rds.StopDBClusterInput, optFns ...func(*rds.Options)) (*rds.StopDBClusterOutput, error)
+}
+
+// Kinds of resource managed by this provider, derived from the server unique ID
+type resourceKind int
+
+const (
+	kindEC2Instance resourceKind = iota
+	kindAutoScalingGroup
+	kindDBInstance
+	kindDBCluster
+)
@@ -1,10 +1,17 @@
 package aws
 
 import (
+	"errors"
 	"ez2boot/internal/server"
+	"fmt"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	astypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	rdstypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/aws/smithy-go"
 )
 
 // Iterate the tags on the instance to find the value
@@ -17,6 +24,65 @@ func getTagValue(inst ec2types.Instance, tagKey string) string {
 	return ""
 }
 
+// Companion tags describing the server group, eg ez2boot:description
+func getGroupTags(inst ec2types.Instance, tagKey string) map[string]string {
+	tags := make(map[string]string, len(inst.Tags))
+	for _, tag := range inst.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return server.GetGroupTags(tagKey, tags)
+}
+
+// Collect the descriptive details of an EC2 instance
+func getInstanceMetadata(inst ec2types.Instance) server.Metadata {
+	m := server.Metadata{
+		InstanceType: string(inst.InstanceType),
+		PrivateIP:    aws.ToString(inst.PrivateIpAddress),
+		PublicIP:     aws.ToString(inst.PublicIpAddress),
+		Platform:     aws.ToString(inst.PlatformDetails),
+	}
+
+	if inst.Placement != nil {
+		m.Zone = aws.ToString(inst.Placement.AvailabilityZone)
+	}
+
+	if inst.LaunchTime != nil {
+		m.LaunchTime = inst.LaunchTime.Unix()
+	}
+
+	return m
+}
+
+// Collect the descriptive details of an RDS DB instance
+func getDBInstanceMetadata(db rdstypes.DBInstance) server.Metadata {
+	m := server.Metadata{
+		InstanceType: aws.ToString(db.DBInstanceClass),
+		Zone:         aws.ToString(db.AvailabilityZone),
+		Platform:     aws.ToString(db.Engine),
+	}
+
+	if db.InstanceCreateTime != nil {
+		m.LaunchTime = db.InstanceCreateTime.Unix()
+	}
+
+	return m
+}
+
+// Collect the descriptive details of an RDS DB cluster
+func getDBClusterMetadata(cluster rdstypes.DBCluster) server.Metadata {
+	m := server.Metadata{
+		InstanceType: aws.ToString(cluster.DBClusterInstanceClass),
+		Zone:         strings.Join(cluster.AvailabilityZones, ","),
+		Platform:     aws.ToString(cluster.Engine),
+	}
+
+	if cluster.ClusterCreateTime != nil {
+		m.LaunchTime = cluster.ClusterCreateTime.Unix()
+	}
+
+	return m
+}
+
 // Map provider specific states to generic
 func mapState(state string) server.ServerState {
 	switch state {
@@ -28,3 +94,144 @@ func mapState(state string) server.ServerState {
 		return server.ServerOff
 	}
 }
+
+// Auto Scaling groups are off once scaled to zero with no instances left, and on once every desired instance is in service
+func mapASGState(group astypes.AutoScalingGroup) server.ServerState {
+	desired := aws.ToInt32(group.DesiredCapacity)
+
+	inService := int32(0)
+	for _, inst := range group.Instances {
+		if inst.LifecycleState == astypes.LifecycleStateInService {
+			inService++
+		}
+	}
+
+	switch {
+	case desired == 0 && len(group.Instances) == 0:
+		return server.ServerOff
+	case desired > 0 && inService >= desired:
+		return server.ServerOn
+	default:
+		return server.ServerTransitioning
+	}
+}
+
+// Map RDS instance and cluster statuses to generic. Statuses the database cannot leave without intervention are off,
+// so a session fails through the start attempts rather than waiting on a transition which never ends
+func mapRDSState(status string) server.ServerState {
+	switch status {
+	case "available":
+		return server.ServerOn
+	case "stopped":
+		return server.ServerOff
+	case "failed", "storage-full", "restore-error", "insufficient-capacity", "inaccessible-encryption-credentials",
+		"incompatible-network", "incompatible-option-group", "incompatible-parameters", "incompatible-restore":
+		return server.ServerOff
+	default: // starting, stopping, backing-up, modifying and the like
+		return server.ServerTransitioning
+	}
+}
+
+// Describe calls refused by IAM, as opposed to throttling or outages
+func isAccessDenied(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	switch apiErr.ErrorCode() {
+	case "AccessDenied", "AccessDeniedException", "UnauthorizedOperation":
+		return true
+	default:
+		return false
+	}
+}
+
+// EC2 instances are identified by instance ID, everything else by ARN
+func getResourceKind(id string) (resourceKind, error) {
+	if !arn.IsARN(id) {
+		return kindEC2Instance, nil
+	}
+
+	parsed, err := arn.Parse(id)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case parsed.Service == "autoscaling":
+		return kindAutoScalingGroup, nil
+	case parsed.Service == "rds" && strings.HasPrefix(parsed.Resource, "db:"):
+		return kindDBInstance, nil
+	case parsed.Service == "rds" && strings.HasPrefix(parsed.Resource, "cluster:"):
+		return kindDBCluster, nil
+	default:
+		return 0, fmt.Errorf("unsupported resource ARN: %s", id)
+	}
+}
+
+// Resource takes the form autoScalingGroup:{uuid}:autoScalingGroupName/{name}
+func getASGNameFromARN(id string) (string, error) {
+	parsed, err := arn.Parse(id)
+	if err != nil {
+		return "", err
+	}
+
+	_, name, ok := strings.Cut(parsed.Resource, "autoScalingGroupName/")
+	if !ok || name == "" {
+		return "", errors.New("invalid Auto Scaling group ARN")
+	}
+
+	return name, nil
+}
+
+// Resource takes the form db:{identifier} or cluster:{identifier}
+func getRDSIdentifierFromARN(id string) (string, error) {
+	parsed, err := arn.Parse(id)
+	if err != nil {
+		return "", err
+	}
+
+	_, identifier, ok := strings.Cut(parsed.Resource, ":")
+	if !ok || identifier == "" {
+		return "", errors.New("invalid RDS ARN")
+	}
+
+	return identifier, nil
+}
+
+// Capacity saved on an Auto Scaling group before scaling to zero, stored as min=1,desired=2
+func formatSavedCapacity(minSize int32, desired int32) string {
+	return fmt.Sprintf("min=%d,desired=%d", minSize, desired)
+}
+
+func parseSavedCapacity(value string) (int32, int32, error) {
+	var minSize, desired int32
+	if _, err := fmt.Sscanf(value, "min=%d,desired=%d", &minSize, &desired); err != nil {
+		return 0, 0, fmt.Errorf("invalid saved capacity %q: %w", value, err)
+	}
+
+	return minSize, desired, nil
+}
+
+// Tag key holding the saved capacity, derived from the tag key so it is easy to spot
+func getSavedCapacityTagKey(tagKey string) string {
+	return tagKey + ":saved-capacity"
+}
+
+// Role ARN takes the form arn:aws:iam::{account}:role/{name}
+func getAccountIDFromARN(arn string) (string, error) {
+	parts := strings.Split(arn, ":")
+	if len(parts) < 6 || parts[4] == "" {
+		return "", fmt.Errorf("invalid role ARN: %s", arn)
+	}
+	return parts[4], nil
+}
+
+// Readable identifier for a target, used in logs
+func (t *Target) String() string {
+	if t.AccountID == "" {
+		return t.Region
+	}
+	return t.AccountID + "/" + t.Region
+}
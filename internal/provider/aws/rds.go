@@ -0,0 +1,130 @@
+package aws
+
+import (
+	"context"
+	"ez2boot/internal/server"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	rdstypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+)
+
+// Describe tagged RDS DB instances and clusters within a single target. Cluster members are managed through their cluster
+func (s *Service) scrapeRDS(target *Target) ([]server.Server, error) {
+	servers := []server.Server{}
+
+	// RDS cannot filter on tags server side, filter here instead
+	instances := rds.NewDescribeDBInstancesPaginator(target.RDSClient, &rds.DescribeDBInstancesInput{})
+	for instances.HasMorePages() {
+		page, err := instances.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, db := range page.DBInstances {
+			group, ok := getRDSTagValue(db.TagList, s.Config.TagKey)
+			if !ok || db.DBClusterIdentifier != nil {
+				continue
+			}
+
+			bootOrder, _ := getRDSTagValue(db.TagList, server.GetGroupTagKey(s.Config.TagKey, server.TagBootOrder))
+			servers = append(servers, server.Server{
+				UniqueID:    aws.ToString(db.DBInstanceArn),
+				Name:        aws.ToString(db.DBInstanceIdentifier),
+				State:       mapRDSState(aws.ToString(db.DBInstanceStatus)),
+				ServerGroup: group,
+				TimeAdded:   time.Now().Unix(),
+				Metadata:    getDBInstanceMetadata(db),
+				GroupTags:   getRDSGroupTags(db.TagList, s.Config.TagKey),
+				BootOrder:   server.ParseBootOrder(bootOrder),
+			})
+		}
+	}
+
+	clusters := rds.NewDescribeDBClustersPaginator(target.RDSClient, &rds.DescribeDBClustersInput{})
+	for clusters.HasMorePages() {
+		page, err := clusters.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cluster := range page.DBClusters {
+			group, ok := getRDSTagValue(cluster.TagList, s.Config.TagKey)
+			if !ok {
+				continue
+			}
+
+			bootOrder, _ := getRDSTagValue(cluster.TagList, server.GetGroupTagKey(s.Config.TagKey, server.TagBootOrder))
+			servers = append(servers, server.Server{
+				UniqueID:    aws.ToString(cluster.DBClusterArn),
+				Name:        aws.ToString(cluster.DBClusterIdentifier),
+				State:       mapRDSState(aws.ToString(cluster.Status)),
+				ServerGroup: group,
+				TimeAdded:   time.Now().Unix(),
+				Metadata:    getDBClusterMetadata(cluster),
+				GroupTags:   getRDSGroupTags(cluster.TagList, s.Config.TagKey),
+				BootOrder:   server.ParseBootOrder(bootOrder),
+			})
+		}
+	}
+
+	return servers, nil
+}
+
+func (s *Service) startDBInstance(target *Target, id string) error {
+	identifier, err := getRDSIdentifierFromARN(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = target.RDSClient.StartDBInstance(context.Background(), &rds.StartDBInstanceInput{DBInstanceIdentifier: aws.String(identifier)})
+	return err
+}
+
+func (s *Service) stopDBInstance(target *Target, id string) error {
+	identifier, err := getRDSIdentifierFromARN(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = target.RDSClient.StopDBInstance(context.Background(), &rds.StopDBInstanceInput{DBInstanceIdentifier: aws.String(identifier)})
+	return err
+}
+
+func (s *Service) startDBCluster(target *Target, id string) error {
+	identifier, err := getRDSIdentifierFromARN(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = target.RDSClient.StartDBCluster(context.Background(), &rds.StartDBClusterInput{DBClusterIdentifier: aws.String(identifier)})
+	return err
+}
+
+func (s *Service) stopDBCluster(target *Target, id string) error {
+	identifier, err := getRDSIdentifierFromARN(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = target.RDSClient.StopDBCluster(context.Background(), &rds.StopDBClusterInput{DBClusterIdentifier: aws.String(identifier)})
+	return err
+}
+
+func getRDSGroupTags(tags []rdstypes.Tag, tagKey string) map[string]string {
+	tagMap := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		tagMap[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return server.GetGroupTags(tagKey, tagMap)
+}
+
+func getRDSTagValue(tags []rdstypes.Tag, tagKey string) (string, bool) {
+	for _, tag := range tags {
+		if aws.ToString(tag.Key) == tagKey {
+			return aws.ToString(tag.Value), true
+		}
+	}
+	return "", false
+}
@@ -2,7 +2,11 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"ez2boot/internal/server"
+	"ez2boot/internal/shared"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -11,120 +15,324 @@ import (
 
 // Scrape AWS to retrieve servers.
 func (s *Service) Scrape() error {
-	s.Logger.Debug("Scraping AWS", "domain", "aws")
+	s.Logger.Debug("Scraping AWS", "domain", "aws", "targets", len(s.Targets))
 
-	input := getDescribeInstancesInput(s.Config.TagKey) // Target tagged instances
+	type result struct {
+		servers []server.Server
+		err     error
+	}
+
+	// Fan out across all targets
+	results := make([]result, len(s.Targets))
+	var wg sync.WaitGroup
+	for i, target := range s.Targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			servers, err := s.scrapeTarget(target)
+			results[i] = result{servers: servers, err: err}
+		}()
+	}
+	wg.Wait()
+
+	servers := []server.Server{}
+	failedTargets := []string{}
+	errs := []error{}
+	for i, r := range results {
+		// A failed target's servers are kept as recorded, the other targets are still updated
+		if r.err != nil {
+			s.Logger.Error("Failed to scrape AWS target", "domain", "aws", "target", s.Targets[i].String(), "error", r.err)
+			failedTargets = append(failedTargets, s.Targets[i].String())
+			errs = append(errs, fmt.Errorf("failed to scrape target %s: %w", s.Targets[i].String(), r.err))
+			continue
+		}
+
+		for j := range r.servers {
+			r.servers[j].Target = s.Targets[i].String()
+		}
+
+		servers = append(servers, r.servers...)
+	}
+
+	s.Logger.Debug("Scraped and found number of matching resources", "domain", "aws", "count", len(servers), "failed_targets", len(failedTargets))
+	s.ServerService.UpdateServersPartial(shared.CloudProviderAWS, servers, failedTargets)
+
+	return errors.Join(errs...)
+}
 
-	// Describe instances from AWS. Max 1000 responses without pagination
-	result, err := s.EC2Client.DescribeInstances(context.Background(), input)
+// Describe tagged Auto Scaling groups, EC2 instances and RDS databases within a single target. Auto Scaling and RDS are
+// skipped when the credentials are not allowed to describe them, so a role granting only EC2 keeps scraping instances
+func (s *Service) scrapeTarget(target *Target) ([]server.Server, error) {
+	groups, err := s.scrapeAutoScalingGroups(target)
+	if isAccessDenied(err) {
+		s.Logger.Debug("Skipping Auto Scaling groups, describe not permitted", "target", target.String(), "domain", "aws", "error", err)
+		groups, err = []server.Server{}, nil
+	}
 	if err != nil {
-		s.Logger.Error("Failed to describe EC2 instances", "domain", "aws", "error", err)
-		return err
+		return nil, fmt.Errorf("failed to describe Auto Scaling groups: %w", err)
+	}
+
+	// Instances belonging to a managed group are started and stopped by the group, not directly
+	managedGroups := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		managedGroups[g.Name] = true
+	}
+
+	instances, err := s.scrapeInstances(target, managedGroups)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe EC2 instances: %w", err)
 	}
 
+	databases, err := s.scrapeRDS(target)
+	if isAccessDenied(err) {
+		s.Logger.Debug("Skipping RDS databases, describe not permitted", "target", target.String(), "domain", "aws", "error", err)
+		databases, err = []server.Server{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe RDS databases: %w", err)
+	}
+
+	servers := append(groups, instances...)
+	return append(servers, databases...), nil
+}
+
+// Describe tagged instances within a single target, following pagination
+func (s *Service) scrapeInstances(target *Target, managedGroups map[string]bool) ([]server.Server, error) {
+	input := getDescribeInstancesInput(s.Config.TagKey) // Target tagged instances
+
 	servers := []server.Server{}
-	for _, reservation := range result.Reservations {
-		for _, inst := range reservation.Instances {
-
-			// Add to struct
-			var svr = server.Server{
-				UniqueID:    aws.ToString(inst.InstanceId),
-				Name:        getTagValue(inst, "Name"),
-				State:       mapState(string(inst.State.Name)),
-				ServerGroup: getTagValue(inst, s.Config.TagKey),
-				TimeAdded:   time.Now().Unix(),
-			}
+	paginator := ec2.NewDescribeInstancesPaginator(target.EC2Client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
 
-			servers = append(servers, svr)
+		for _, reservation := range page.Reservations {
+			for _, inst := range reservation.Instances {
+				if managedGroups[getTagValue(inst, "aws:autoscaling:groupName")] {
+					continue
+				}
+
+				// Add to struct
+				var svr = server.Server{
+					UniqueID:    aws.ToString(inst.InstanceId),
+					Name:        getTagValue(inst, "Name"),
+					State:       mapState(string(inst.State.Name)),
+					ServerGroup: getTagValue(inst, s.Config.TagKey),
+					TimeAdded:   time.Now().Unix(),
+					Metadata:    getInstanceMetadata(inst),
+					GroupTags:   getGroupTags(inst, s.Config.TagKey),
+					BootOrder:   server.ParseBootOrder(getTagValue(inst, server.GetGroupTagKey(s.Config.TagKey, server.TagBootOrder))),
+				}
+
+				servers = append(servers, svr)
+			}
 		}
 	}
 
-	s.Logger.Debug("Scraped and found number of matching instances", "domain", "aws", "count", len(servers))
-	s.ServerService.UpdateServers(servers)
+	return servers, nil
+}
 
-	return nil
+// Group resource IDs by the target recorded against them by the last scrape which saw them, so routing survives a restart.
+// Resources with no recorded target, or one no longer configured, are skipped until the next cycle
+func (s *Service) groupByTarget(resourceIDs []string) map[*Target][]string {
+	grouped := make(map[*Target][]string)
+
+	recorded, err := s.ServerService.GetServerTargets(shared.CloudProviderAWS)
+	if err != nil {
+		s.Logger.Error("Failed to get resource targets", "domain", "aws", "error", err)
+		return grouped
+	}
+
+	targets := make(map[string]*Target, len(s.Targets))
+	for _, t := range s.Targets {
+		targets[t.String()] = t
+	}
+
+	for _, id := range resourceIDs {
+		target, ok := targets[recorded[id]]
+		if !ok {
+			s.Logger.Warn("Resource has no known target, waiting for next scrape", "id", id, "target", recorded[id], "domain", "aws")
+			continue
+		}
+
+		grouped[target] = append(grouped[target], id)
+	}
+
+	return grouped
 }
 
 // Start required AWS servers
 func (s *Service) Start() error {
-	s.Logger.Debug("Starting requested AWS instances", "domain", "aws")
+	s.Logger.Debug("Starting requested AWS resources", "domain", "aws")
 
-	// Get start instance IDs
-	instanceIDs, err := s.ServerService.GetPending("off", "on")
+	// Get start resource IDs
+	resourceIDs, err := s.ServerService.GetPending(shared.CloudProviderAWS, "off", "on")
 	if err != nil {
-		s.Logger.Error("Failed to get instance IDs pending on", "domain", "aws", "error", err)
+		s.Logger.Error("Failed to get resource IDs pending on", "domain", "aws", "error", err)
 		return err
 	}
 
 	// Nothing to do
-	if len(instanceIDs) == 0 {
-		s.Logger.Debug("No instances to start", "domain", "aws")
+	if len(resourceIDs) == 0 {
+		s.Logger.Debug("No resources to start", "domain", "aws")
 		return nil
 	}
 
-	// Loop and turn each on
-	for _, id := range instanceIDs {
-		s.Logger.Debug("Starting", "id", id, "domain", "aws")
-		input := &ec2.StartInstancesInput{
-			InstanceIds: []string{id},
-		}
+	for target, ids := range s.groupByTarget(resourceIDs) {
+		instanceIDs := []string{}
+		for _, id := range ids {
+			kind, err := getResourceKind(id)
+			if err != nil {
+				s.Logger.Error("Failed to identify resource", "id", id, "domain", "aws", "error", err)
+				continue
+			}
 
-		result, err := s.EC2Client.StartInstances(context.Background(), input)
-		if err != nil {
-			s.Logger.Error("Failed to start instance", "id", id, "domain", "aws", "error", err)
-			continue
+			switch kind {
+			case kindEC2Instance:
+				instanceIDs = append(instanceIDs, id)
+			case kindAutoScalingGroup:
+				s.applyResource(target, id, "start", s.startAutoScalingGroup)
+			case kindDBInstance:
+				s.applyResource(target, id, "start", s.startDBInstance)
+			case kindDBCluster:
+				s.applyResource(target, id, "start", s.startDBCluster)
+			}
 		}
 
-		for _, instance := range result.StartingInstances {
-			s.Logger.Info("Instance start initiated", "domain", "aws",
-				"id", aws.ToString(instance.InstanceId),
-				"previous_state", instance.PreviousState.Name,
-				"current_state", instance.CurrentState.Name,
-			)
+		// One call per batch of instances
+		for _, batch := range getBatches(instanceIDs, maxBatchSize) {
+			s.startBatch(target, batch)
 		}
 	}
 
 	return nil
 }
 
+// Start or stop a single non EC2 resource, recording the outcome against the server
+func (s *Service) applyResource(target *Target, id string, action string, fn func(*Target, string) error) {
+	s.Logger.Debug("Applying action to resource", "id", id, "action", action, "target", target.String(), "domain", "aws")
+
+	if err := fn(target, id); err != nil {
+		s.Logger.Error("Failed to apply action to resource", "id", id, "action", action, "target", target.String(), "domain", "aws", "error", err)
+		s.ServerService.RecordFailure(id, action, err)
+		return
+	}
+
+	s.ServerService.ClearFailure(id)
+	s.Logger.Info("Resource action initiated", "id", id, "action", action, "target", target.String(), "domain", "aws")
+}
+
+// Start a batch of instances. A single bad instance fails the whole call, so fall back to individual calls to find which
+func (s *Service) startBatch(target *Target, ids []string) {
+	s.Logger.Debug("Starting", "ids", ids, "target", target.String(), "domain", "aws")
+
+	result, err := target.EC2Client.StartInstances(context.Background(), &ec2.StartInstancesInput{InstanceIds: ids})
+	if err != nil {
+		if len(ids) > 1 {
+			s.Logger.Warn("Batch start failed, retrying instances individually", "count", len(ids), "target", target.String(), "domain", "aws", "error", err)
+			for _, id := range ids {
+				s.startBatch(target, []string{id})
+			}
+			return
+		}
+
+		s.Logger.Error("Failed to start instance", "id", ids[0], "target", target.String(), "domain", "aws", "error", err)
+		s.ServerService.RecordFailure(ids[0], "start", err)
+		return
+	}
+
+	for _, id := range ids {
+		s.ServerService.ClearFailure(id)
+	}
+
+	for _, instance := range result.StartingInstances {
+		s.Logger.Info("Instance start initiated", "domain", "aws",
+			"id", aws.ToString(instance.InstanceId),
+			"target", target.String(),
+			"previous_state", instance.PreviousState.Name,
+			"current_state", instance.CurrentState.Name,
+		)
+	}
+}
+
 // Stop no longer required AWS servers
 func (s *Service) Stop() error {
-	s.Logger.Debug("Stopping requested AWS instances", "domain", "aws")
+	s.Logger.Debug("Stopping requested AWS resources", "domain", "aws")
 
-	// Get start instance IDs
-	instanceIDs, err := s.ServerService.GetPending("on", "off")
+	// Get stop resource IDs
+	resourceIDs, err := s.ServerService.GetPending(shared.CloudProviderAWS, "on", "off")
 	if err != nil {
-		s.Logger.Error("Failed to get instance IDs pending off", "domain", "aws", "error", err)
+		s.Logger.Error("Failed to get resource IDs pending off", "domain", "aws", "error", err)
 		return err
 	}
 
 	// Nothing to do
-	if len(instanceIDs) == 0 {
-		s.Logger.Debug("No instances to stop", "domain", "aws")
+	if len(resourceIDs) == 0 {
+		s.Logger.Debug("No resources to stop", "domain", "aws")
 		return nil
 	}
 
-	// Loop and turn each on
-	for _, id := range instanceIDs {
-		s.Logger.Debug("Stopping", "id", id, "domain", "aws")
-		input := &ec2.StopInstancesInput{
-			InstanceIds: []string{id},
-		}
+	for target, ids := range s.groupByTarget(resourceIDs) {
+		instanceIDs := []string{}
+		for _, id := range ids {
+			kind, err := getResourceKind(id)
+			if err != nil {
+				s.Logger.Error("Failed to identify resource", "id", id, "domain", "aws", "error", err)
+				continue
+			}
 
-		result, err := s.EC2Client.StopInstances(context.Background(), input)
-		if err != nil {
-			s.Logger.Error("Failed to stop instance", "id", id, "domain", "aws", "error", err)
-			continue
+			switch kind {
+			case kindEC2Instance:
+				instanceIDs = append(instanceIDs, id)
+			case kindAutoScalingGroup:
+				s.applyResource(target, id, "stop", s.stopAutoScalingGroup)
+			case kindDBInstance:
+				s.applyResource(target, id, "stop", s.stopDBInstance)
+			case kindDBCluster:
+				s.applyResource(target, id, "stop", s.stopDBCluster)
+			}
 		}
 
-		for _, instance := range result.StoppingInstances {
-			s.Logger.Info("Instance stop initiated", "domain", "aws",
-				"id", aws.ToString(instance.InstanceId),
-				"previous_state", instance.PreviousState.Name,
-				"current_state", instance.CurrentState.Name,
-			)
+		// One call per batch of instances
+		for _, batch := range getBatches(instanceIDs, maxBatchSize) {
+			s.stopBatch(target, batch)
 		}
 	}
 
 	return nil
 }
+
+// Stop a batch of instances. A single bad instance fails the whole call, so fall back to individual calls to find which
+func (s *Service) stopBatch(target *Target, ids []string) {
+	s.Logger.Debug("Stopping", "ids", ids, "target", target.String(), "domain", "aws")
+
+	result, err := target.EC2Client.StopInstances(context.Background(), &ec2.StopInstancesInput{InstanceIds: ids})
+	if err != nil {
+		if len(ids) > 1 {
+			s.Logger.Warn("Batch stop failed, retrying instances individually", "count", len(ids), "target", target.String(), "domain", "aws", "error", err)
+			for _, id := range ids {
+				s.stopBatch(target, []string{id})
+			}
+			return
+		}
+
+		s.Logger.Error("Failed to stop instance", "id", ids[0], "target", target.String(), "domain", "aws", "error", err)
+		s.ServerService.RecordFailure(ids[0], "stop", err)
+		return
+	}
+
+	for _, id := range ids {
+		s.ServerService.ClearFailure(id)
+	}
+
+	for _, instance := range result.StoppingInstances {
+		s.Logger.Info("Instance stop initiated", "domain", "aws",
+			"id", aws.ToString(instance.InstanceId),
+			"target", target.String(),
+			"previous_state", instance.PreviousState.Name,
+			"current_state", instance.CurrentState.Name,
+		)
+	}
+}
@@ -0,0 +1,760 @@
+package aws_test
+
+import (
+	"context"
+	"errors"
+	"ez2boot/internal/provider/aws"
+	"ez2boot/internal/testutil"
+	"fmt"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	astypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	rdstypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/aws/smithy-go"
+)
+
+// Fake EC2 client which serves a fixed instance list and records start/stop calls
+type fakeEC2Client struct {
+	Instances   []ec2types.Instance
+	PageSize    int // Instances per describe page, 0 for a single page
+	DescribeErr error
+	BadIDs      map[string]bool // Any call containing these IDs fails
+	StartCalls  int
+	StopCalls   int
+	Started     []string
+	Stopped     []string
+}
+
+func (f *fakeEC2Client) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	if f.DescribeErr != nil {
+		return nil, f.DescribeErr
+	}
+
+	if f.PageSize == 0 {
+		return &ec2.DescribeInstancesOutput{
+			Reservations: []ec2types.Reservation{{Instances: f.Instances}},
+		}, nil
+	}
+
+	// Next token is the offset into the instance list
+	offset := 0
+	if params.NextToken != nil {
+		offset, _ = strconv.Atoi(*params.NextToken)
+	}
+
+	end := min(offset+f.PageSize, len(f.Instances))
+	out := &ec2.DescribeInstancesOutput{
+		Reservations: []ec2types.Reservation{{Instances: f.Instances[offset:end]}},
+	}
+
+	if end < len(f.Instances) {
+		out.NextToken = awssdk.String(strconv.Itoa(end))
+	}
+
+	return out, nil
+}
+
+func (f *fakeEC2Client) hasBadID(ids []string) bool {
+	for _, id := range ids {
+		if f.BadIDs[id] {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeEC2Client) StartInstances(ctx context.Context, params *ec2.StartInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error) {
+	f.StartCalls++
+	if f.hasBadID(params.InstanceIds) {
+		return nil, errors.New("IncorrectInstanceState")
+	}
+
+	f.Started = append(f.Started, params.InstanceIds...)
+
+	changes := []ec2types.InstanceStateChange{}
+	for _, id := range params.InstanceIds {
+		changes = append(changes, ec2types.InstanceStateChange{
+			InstanceId:    awssdk.String(id),
+			PreviousState: &ec2types.InstanceState{Name: ec2types.InstanceStateNameStopped},
+			CurrentState:  &ec2types.InstanceState{Name: ec2types.InstanceStateNamePending},
+		})
+	}
+
+	return &ec2.StartInstancesOutput{StartingInstances: changes}, nil
+}
+
+func (f *fakeEC2Client) StopInstances(ctx context.Context, params *ec2.StopInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error) {
+	f.StopCalls++
+	if f.hasBadID(params.InstanceIds) {
+		return nil, errors.New("IncorrectInstanceState")
+	}
+
+	f.Stopped = append(f.Stopped, params.InstanceIds...)
+
+	changes := []ec2types.InstanceStateChange{}
+	for _, id := range params.InstanceIds {
+		changes = append(changes, ec2types.InstanceStateChange{
+			InstanceId:    awssdk.String(id),
+			PreviousState: &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning},
+			CurrentState:  &ec2types.InstanceState{Name: ec2types.InstanceStateNameStopping},
+		})
+	}
+
+	return &ec2.StopInstancesOutput{StoppingInstances: changes}, nil
+}
+
+// Fake Auto Scaling client holding groups in memory, updates and tag changes are applied to them
+type fakeASGClient struct {
+	Groups      []astypes.AutoScalingGroup
+	DescribeErr error
+}
+
+func (f *fakeASGClient) find(name string) *astypes.AutoScalingGroup {
+	for i := range f.Groups {
+		if awssdk.ToString(f.Groups[i].AutoScalingGroupName) == name {
+			return &f.Groups[i]
+		}
+	}
+	return nil
+}
+
+func (f *fakeASGClient) DescribeAutoScalingGroups(ctx context.Context, params *autoscaling.DescribeAutoScalingGroupsInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	if f.DescribeErr != nil {
+		return nil, f.DescribeErr
+	}
+
+	if len(params.AutoScalingGroupNames) == 0 {
+		return &autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: f.Groups}, nil
+	}
+
+	groups := []astypes.AutoScalingGroup{}
+	for _, name := range params.AutoScalingGroupNames {
+		if g := f.find(name); g != nil {
+			groups = append(groups, *g)
+		}
+	}
+
+	return &autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: groups}, nil
+}
+
+func (f *fakeASGClient) UpdateAutoScalingGroup(ctx context.Context, params *autoscaling.UpdateAutoScalingGroupInput, optFns ...func(*autoscaling.Options)) (*autoscaling.UpdateAutoScalingGroupOutput, error) {
+	g := f.find(awssdk.ToString(params.AutoScalingGroupName))
+	if g == nil {
+		return nil, errors.New("ValidationError")
+	}
+
+	g.MinSize = params.MinSize
+	g.DesiredCapacity = params.DesiredCapacity
+
+	return &autoscaling.UpdateAutoScalingGroupOutput{}, nil
+}
+
+func (f *fakeASGClient) CreateOrUpdateTags(ctx context.Context, params *autoscaling.CreateOrUpdateTagsInput, optFns ...func(*autoscaling.Options)) (*autoscaling.CreateOrUpdateTagsOutput, error) {
+	for _, tag := range params.Tags {
+		g := f.find(awssdk.ToString(tag.ResourceId))
+		g.Tags = append(g.Tags, astypes.TagDescription{Key: tag.Key, Value: tag.Value})
+	}
+
+	return &autoscaling.CreateOrUpdateTagsOutput{}, nil
+}
+
+func (f *fakeASGClient) DeleteTags(ctx context.Context, params *autoscaling.DeleteTagsInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DeleteTagsOutput, error) {
+	for _, tag := range params.Tags {
+		g := f.find(awssdk.ToString(tag.ResourceId))
+
+		kept := []astypes.TagDescription{}
+		for _, existing := range g.Tags {
+			if awssdk.ToString(existing.Key) != awssdk.ToString(tag.Key) {
+				kept = append(kept, existing)
+			}
+		}
+		g.Tags = kept
+	}
+
+	return &autoscaling.DeleteTagsOutput{}, nil
+}
+
+// Fake RDS client serving fixed instances and clusters and recording start/stop calls
+type fakeRDSClient struct {
+	Instances   []rdstypes.DBInstance
+	Clusters    []rdstypes.DBCluster
+	Calls       []string
+	DescribeErr error
+}
+
+func (f *fakeRDSClient) DescribeDBInstances(ctx context.Context, params *rds.DescribeDBInstancesInput, optFns ...func(*rds.Options)) (*rds.DescribeDBInstancesOutput, error) {
+	if f.DescribeErr != nil {
+		return nil, f.DescribeErr
+	}
+
+	return &rds.DescribeDBInstancesOutput{DBInstances: f.Instances}, nil
+}
+
+func (f *fakeRDSClient) DescribeDBClusters(ctx context.Context, params *rds.DescribeDBClustersInput, optFns ...func(*rds.Options)) (*rds.DescribeDBClustersOutput, error) {
+	return &rds.DescribeDBClustersOutput{DBClusters: f.Clusters}, nil
+}
+
+func (f *fakeRDSClient) StartDBInstance(ctx context.Context, params *rds.StartDBInstanceInput, optFns ...func(*rds.Options)) (*rds.StartDBInstanceOutput, error) {
+	f.Calls = append(f.Calls, "start-instance/"+awssdk.ToString(params.DBInstanceIdentifier))
+	return &rds.StartDBInstanceOutput{}, nil
+}
+
+func (f *fakeRDSClient) StopDBInstance(ctx context.Context, params *rds.StopDBInstanceInput, optFns ...func(*rds.Options)) (*rds.StopDBInstanceOutput, error) {
+	f.Calls = append(f.Calls, "stop-instance/"+awssdk.ToString(params.DBInstanceIdentifier))
+	return &rds.StopDBInstanceOutput{}, nil
+}
+
+func (f *fakeRDSClient) StartDBCluster(ctx context.Context, params *rds.StartDBClusterInput, optFns ...func(*rds.Options)) (*rds.StartDBClusterOutput, error) {
+	f.Calls = append(f.Calls, "start-cluster/"+awssdk.ToString(params.DBClusterIdentifier))
+	return &rds.StartDBClusterOutput{}, nil
+}
+
+func (f *fakeRDSClient) StopDBCluster(ctx context.Context, params *rds.StopDBClusterInput, optFns ...func(*rds.Options)) (*rds.StopDBClusterOutput, error) {
+	f.Calls = append(f.Calls, "stop-cluster/"+awssdk.ToString(params.DBClusterIdentifier))
+	return &rds.StopDBClusterOutput{}, nil
+}
+
+func newASG(name string, group string, minSize int32, desired int32, inService int) astypes.AutoScalingGroup {
+	instances := []astypes.Instance{}
+	for i := range inService {
+		instances = append(instances, astypes.Instance{
+			InstanceId:     awssdk.String(fmt.Sprintf("i-%s%d", name, i)),
+			LifecycleState: astypes.LifecycleStateInService,
+		})
+	}
+
+	return astypes.AutoScalingGroup{
+		AutoScalingGroupName: awssdk.String(name),
+		AutoScalingGroupARN:  awssdk.String("arn:aws:autoscaling:ap-southeast-2:111122223333:autoScalingGroup:6d4a1f2e-0000-0000-0000-000000000000:autoScalingGroupName/" + name),
+		MinSize:              awssdk.Int32(minSize),
+		MaxSize:              awssdk.Int32(10),
+		DesiredCapacity:      awssdk.Int32(desired),
+		Instances:            instances,
+		Tags:                 []astypes.TagDescription{{Key: awssdk.String("ez2boot"), Value: awssdk.String(group)}},
+	}
+}
+
+func newDBInstance(identifier string, status string, group string, cluster string) rdstypes.DBInstance {
+	db := rdstypes.DBInstance{
+		DBInstanceIdentifier: awssdk.String(identifier),
+		DBInstanceArn:        awssdk.String("arn:aws:rds:ap-southeast-2:111122223333:db:" + identifier),
+		DBInstanceStatus:     awssdk.String(status),
+	}
+
+	if group != "" {
+		db.TagList = []rdstypes.Tag{{Key: awssdk.String("ez2boot"), Value: awssdk.String(group)}}
+	}
+	if cluster != "" {
+		db.DBClusterIdentifier = awssdk.String(cluster)
+	}
+
+	return db
+}
+
+func newDBCluster(identifier string, status string, group string) rdstypes.DBCluster {
+	return rdstypes.DBCluster{
+		DBClusterIdentifier: awssdk.String(identifier),
+		DBClusterArn:        awssdk.String("arn:aws:rds:ap-southeast-2:111122223333:cluster:" + identifier),
+		Status:              awssdk.String(status),
+		TagList:             []rdstypes.Tag{{Key: awssdk.String("ez2boot"), Value: awssdk.String(group)}},
+	}
+}
+
+func newInstance(id string, name string, state ec2types.InstanceStateName, group string) ec2types.Instance {
+	return ec2types.Instance{
+		InstanceId: awssdk.String(id),
+		State:      &ec2types.InstanceState{Name: state},
+		Tags: []ec2types.Tag{
+			{Key: awssdk.String("Name"), Value: awssdk.String(name)},
+			{Key: awssdk.String("ez2boot"), Value: awssdk.String(group)},
+		},
+	}
+}
+
+func newAWSService(env *testutil.TestEnv, targets ...*aws.Target) *aws.Service {
+	env.Cfg.TagKey = "ez2boot"
+
+	return &aws.Service{
+		Repo:          aws.NewRepository(env.Base),
+		Config:        env.Cfg,
+		ServerService: env.ServerService,
+		Targets:       targets,
+		Logger:        env.Logger,
+	}
+}
+
+func setServerTarget(t *testing.T, env *testutil.TestEnv, uniqueID string, target string) {
+	t.Helper()
+
+	if _, err := env.DB.Exec("UPDATE servers SET target = $1 WHERE unique_id = $2", target, uniqueID); err != nil {
+		t.Fatalf("failed to set server target: %v", err)
+	}
+}
+
+func TestAWSScrapeMultiTarget_Success(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	sydney := &fakeEC2Client{Instances: []ec2types.Instance{
+		newInstance("i-0000000000000000a", "qa-app", ec2types.InstanceStateNameRunning, "QA"),
+	}}
+	virginia := &fakeEC2Client{Instances: []ec2types.Instance{
+		newInstance("i-0000000000000000b", "dev-app", ec2types.InstanceStateNameStopped, "DEV"),
+	}}
+
+	svc := newAWSService(env,
+		&aws.Target{Region: "ap-southeast-2", EC2Client: sydney, ASGClient: &fakeASGClient{}, RDSClient: &fakeRDSClient{}},
+		&aws.Target{Region: "us-east-1", RoleARN: "arn:aws:iam::111122223333:role/ez2boot", AccountID: "111122223333", EC2Client: virginia, ASGClient: &fakeASGClient{}, RDSClient: &fakeRDSClient{}},
+	)
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	var count int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM servers WHERE provider = $1", "aws").Scan(&count); err != nil {
+		t.Fatalf("failed to query servers: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("want 2 servers scraped across targets, got %d", count)
+	}
+
+	// Both groups pending a state change
+	testutil.InsertUser(t, env.DB, "user@example.com", nil, true, false, false, true, "local")
+	testutil.InsertServerSession(t, env.DB, 1, "DEV", time.Now().Add(1*time.Hour).Unix())
+	if _, err := env.DB.Exec("UPDATE servers SET next_state = $1 WHERE server_group = $2", "off", "QA"); err != nil {
+		t.Fatalf("failed to update server: %v", err)
+	}
+
+	if err := svc.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+
+	if err := svc.Stop(); err != nil {
+		t.Fatalf("stop failed: %v", err)
+	}
+
+	// Calls routed to the owning target only
+	if len(virginia.Started) != 1 || virginia.Started[0] != "i-0000000000000000b" {
+		t.Errorf("want start routed to us-east-1, got %v", virginia.Started)
+	}
+	if len(sydney.Started) != 0 {
+		t.Errorf("want no starts in ap-southeast-2, got %v", sydney.Started)
+	}
+	if len(sydney.Stopped) != 1 || sydney.Stopped[0] != "i-0000000000000000a" {
+		t.Errorf("want stop routed to ap-southeast-2, got %v", sydney.Stopped)
+	}
+	if len(virginia.Stopped) != 0 {
+		t.Errorf("want no stops in us-east-1, got %v", virginia.Stopped)
+	}
+}
+
+// A failed target must not cause servers from that target to be removed, while the other targets are still updated
+func TestAWSScrapeTargetFailure_KeepsServers(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	healthy := &fakeEC2Client{Instances: []ec2types.Instance{
+		newInstance("i-0000000000000000a", "qa-app", ec2types.InstanceStateNameRunning, "QA"),
+	}}
+	failing := &fakeEC2Client{DescribeErr: errors.New("access denied")}
+
+	svc := newAWSService(env,
+		&aws.Target{Region: "ap-southeast-2", EC2Client: healthy, ASGClient: &fakeASGClient{}, RDSClient: &fakeRDSClient{}},
+		&aws.Target{Region: "us-east-1", EC2Client: failing, ASGClient: &fakeASGClient{}, RDSClient: &fakeRDSClient{}},
+	)
+
+	testutil.InsertServer(t, env.DB, "i-0000000000000000b", "dev-app", "off", "DEV", time.Now().Unix())
+	testutil.InsertServer(t, env.DB, "i-0000000000000000c", "old-app", "off", "OLD", time.Now().Unix())
+	testutil.SetServerProvider(t, env.DB, "DEV", "aws")
+	testutil.SetServerProvider(t, env.DB, "OLD", "aws")
+	setServerTarget(t, env, "i-0000000000000000b", "us-east-1")
+	setServerTarget(t, env, "i-0000000000000000c", "ap-southeast-2")
+
+	if err := svc.Scrape(); err == nil {
+		t.Fatalf("want scrape error when a target fails")
+	}
+
+	got := map[string]string{}
+	rows, err := env.DB.Query("SELECT unique_id, target FROM servers ORDER BY unique_id")
+	if err != nil {
+		t.Fatalf("failed to query servers: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, target string
+		if err := rows.Scan(&id, &target); err != nil {
+			t.Fatalf("failed to scan server row: %v", err)
+		}
+		got[id] = target
+	}
+
+	// Failed target retained, healthy target scraped and its missing server removed
+	want := map[string]string{"i-0000000000000000a": "ap-southeast-2", "i-0000000000000000b": "us-east-1"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("want servers %v, got %v", want, got)
+	}
+}
+
+// Routing comes from the target recorded with each server, so start works after a restart before the first scrape
+func TestAWSStartBeforeScrape_RoutesFromRecordedTarget(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	sydney := &fakeEC2Client{}
+	virginia := &fakeEC2Client{}
+
+	svc := newAWSService(env,
+		&aws.Target{Region: "ap-southeast-2", EC2Client: sydney, ASGClient: &fakeASGClient{}, RDSClient: &fakeRDSClient{}},
+		&aws.Target{Region: "us-east-1", RoleARN: "arn:aws:iam::111122223333:role/ez2boot", AccountID: "111122223333", EC2Client: virginia, ASGClient: &fakeASGClient{}, RDSClient: &fakeRDSClient{}},
+	)
+
+	testutil.InsertServer(t, env.DB, "i-0000000000000000b", "dev-app", "off", "DEV", time.Now().Unix())
+	testutil.SetServerProvider(t, env.DB, "DEV", "aws")
+	setServerTarget(t, env, "i-0000000000000000b", "111122223333/us-east-1")
+
+	testutil.InsertUser(t, env.DB, "user@example.com", nil, true, false, false, true, "local")
+	testutil.InsertServerSession(t, env.DB, 1, "DEV", time.Now().Add(1*time.Hour).Unix())
+
+	if err := svc.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+
+	if len(virginia.Started) != 1 || virginia.Started[0] != "i-0000000000000000b" {
+		t.Errorf("want start routed to us-east-1, got %v", virginia.Started)
+	}
+	if len(sydney.Started) != 0 {
+		t.Errorf("want no starts in ap-southeast-2, got %v", sydney.Started)
+	}
+}
+
+func TestAWSScrapePaginated_Success(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	instances := []ec2types.Instance{}
+	for i := range 25 {
+		instances = append(instances, newInstance(fmt.Sprintf("i-%017d", i), fmt.Sprintf("qa%02d", i), ec2types.InstanceStateNameStopped, "QA"))
+	}
+
+	client := &fakeEC2Client{Instances: instances, PageSize: 10}
+	svc := newAWSService(env, &aws.Target{Region: "ap-southeast-2", EC2Client: client, ASGClient: &fakeASGClient{}, RDSClient: &fakeRDSClient{}})
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	var count int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM servers").Scan(&count); err != nil {
+		t.Fatalf("failed to query servers: %v", err)
+	}
+	if count != 25 {
+		t.Errorf("want all 25 servers across pages, got %d", count)
+	}
+}
+
+func TestAWSScrapeMetadata_Success(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	launched := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	inst := newInstance("i-0000000000000000a", "qa-app", ec2types.InstanceStateNameRunning, "QA")
+	inst.InstanceType = ec2types.InstanceTypeT3Medium
+	inst.PrivateIpAddress = awssdk.String("10.0.1.10")
+	inst.PublicIpAddress = awssdk.String("203.0.113.10")
+	inst.Placement = &ec2types.Placement{AvailabilityZone: awssdk.String("ap-southeast-2a")}
+	inst.PlatformDetails = awssdk.String("Linux/UNIX")
+	inst.LaunchTime = &launched
+
+	client := &fakeEC2Client{Instances: []ec2types.Instance{inst}}
+	svc := newAWSService(env, &aws.Target{Region: "ap-southeast-2", EC2Client: client, ASGClient: &fakeASGClient{}, RDSClient: &fakeRDSClient{}})
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	var instanceType, privateIP, publicIP, zone, platform string
+	var launchTime int64
+	if err := env.DB.QueryRow("SELECT instance_type, private_ip, public_ip, zone, platform, launch_time FROM servers WHERE unique_id = $1", "i-0000000000000000a").Scan(&instanceType, &privateIP, &publicIP, &zone, &platform, &launchTime); err != nil {
+		t.Fatalf("failed to query server: %v", err)
+	}
+
+	if instanceType != "t3.medium" || privateIP != "10.0.1.10" || publicIP != "203.0.113.10" || zone != "ap-southeast-2a" || platform != "Linux/UNIX" || launchTime != launched.Unix() {
+		t.Errorf("metadata mismatch, got: %s/%s/%s/%s/%s/%d", instanceType, privateIP, publicIP, zone, platform, launchTime)
+	}
+}
+
+func TestAWSScrapeGroupTags_Success(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	inst := newInstance("i-0000000000000000a", "qa-app", ec2types.InstanceStateNameRunning, "QA")
+	inst.Tags = append(inst.Tags,
+		ec2types.Tag{Key: awssdk.String("ez2boot:description"), Value: awssdk.String("QA environment")},
+		ec2types.Tag{Key: awssdk.String("ez2boot:owner"), Value: awssdk.String("qa-team")},
+		ec2types.Tag{Key: awssdk.String("ez2boot:unknown"), Value: awssdk.String("ignored")},
+		ec2types.Tag{Key: awssdk.String("ez2boot:boot-order"), Value: awssdk.String("2")},
+	)
+
+	client := &fakeEC2Client{Instances: []ec2types.Instance{inst}}
+	svc := newAWSService(env, &aws.Target{Region: "ap-southeast-2", EC2Client: client, ASGClient: &fakeASGClient{}, RDSClient: &fakeRDSClient{}})
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	rows, err := env.DB.Query("SELECT name, value FROM server_group_tags WHERE server_group = $1 AND provider = $2", "QA", "aws")
+	if err != nil {
+		t.Fatalf("failed to query group tags: %v", err)
+	}
+	defer rows.Close()
+
+	got := map[string]string{}
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			t.Fatalf("failed to scan group tag row: %v", err)
+		}
+		got[name] = value
+	}
+
+	if len(got) != 2 || got["description"] != "QA environment" || got["owner"] != "qa-team" {
+		t.Errorf("group tags mismatch, got %v", got)
+	}
+
+	var bootOrder int64
+	if err := env.DB.QueryRow("SELECT boot_order FROM servers WHERE unique_id = $1", "i-0000000000000000a").Scan(&bootOrder); err != nil {
+		t.Fatalf("failed to query server: %v", err)
+	}
+	if bootOrder != 2 {
+		t.Errorf("want boot order 2, got %d", bootOrder)
+	}
+}
+
+func TestAWSStartBatched_Success(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	instances := []ec2types.Instance{}
+	for i := range 5 {
+		instances = append(instances, newInstance(fmt.Sprintf("i-%017d", i), fmt.Sprintf("qa%02d", i), ec2types.InstanceStateNameStopped, "QA"))
+	}
+
+	client := &fakeEC2Client{Instances: instances}
+	svc := newAWSService(env, &aws.Target{Region: "ap-southeast-2", EC2Client: client, ASGClient: &fakeASGClient{}, RDSClient: &fakeRDSClient{}})
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	testutil.InsertUser(t, env.DB, "user@example.com", nil, true, false, false, true, "local")
+	testutil.InsertServerSession(t, env.DB, 1, "QA", time.Now().Add(1*time.Hour).Unix())
+
+	if err := svc.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+
+	if client.StartCalls != 1 {
+		t.Errorf("want 1 batched start call, got %d", client.StartCalls)
+	}
+	if len(client.Started) != 5 {
+		t.Errorf("want 5 instances started, got %d", len(client.Started))
+	}
+}
+
+// One bad instance must not prevent the rest of the batch from starting
+func TestAWSStartBatched_FallbackOnError(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	instances := []ec2types.Instance{}
+	for i := range 3 {
+		instances = append(instances, newInstance(fmt.Sprintf("i-%017d", i), fmt.Sprintf("qa%02d", i), ec2types.InstanceStateNameStopped, "QA"))
+	}
+
+	badID := fmt.Sprintf("i-%017d", 1)
+	client := &fakeEC2Client{Instances: instances, BadIDs: map[string]bool{badID: true}}
+	svc := newAWSService(env, &aws.Target{Region: "ap-southeast-2", EC2Client: client, ASGClient: &fakeASGClient{}, RDSClient: &fakeRDSClient{}})
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	testutil.InsertUser(t, env.DB, "user@example.com", nil, true, false, false, true, "local")
+	testutil.InsertServerSession(t, env.DB, 1, "QA", time.Now().Add(1*time.Hour).Unix())
+
+	if err := svc.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+
+	// Failed batch followed by one call per instance
+	if client.StartCalls != 4 {
+		t.Errorf("want 4 start calls, got %d", client.StartCalls)
+	}
+	if len(client.Started) != 2 {
+		t.Errorf("want 2 instances started, got %v", client.Started)
+	}
+	for _, id := range client.Started {
+		if id == badID {
+			t.Errorf("want bad instance %s not started", badID)
+		}
+	}
+}
+
+func TestAWSScrapeASGAndRDS_Success(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	member := newInstance("i-0000000000000000a", "web-1", ec2types.InstanceStateNameRunning, "QA")
+	member.Tags = append(member.Tags, ec2types.Tag{Key: awssdk.String("aws:autoscaling:groupName"), Value: awssdk.String("qa-web")})
+
+	ec2Client := &fakeEC2Client{Instances: []ec2types.Instance{
+		member, // Managed through its group
+		newInstance("i-0000000000000000b", "qa-bastion", ec2types.InstanceStateNameStopped, "QA"),
+	}}
+	asgClient := &fakeASGClient{Groups: []astypes.AutoScalingGroup{
+		newASG("qa-web", "QA", 1, 2, 2),
+		newASG("dev-web", "DEV", 0, 0, 0),
+		newASG("uat-web", "UAT", 1, 2, 1),
+	}}
+	rdsClient := &fakeRDSClient{
+		Instances: []rdstypes.DBInstance{
+			newDBInstance("qa-db", "stopped", "QA", ""),
+			newDBInstance("dev-aurora-1", "available", "DEV", "dev-aurora"), // Managed through its cluster
+			newDBInstance("untagged-db", "available", "", ""),
+			newDBInstance("uat-db", "storage-full", "UAT", ""), // Stuck until fixed, not transitioning
+		},
+		Clusters: []rdstypes.DBCluster{
+			newDBCluster("dev-aurora", "available", "DEV"),
+			newDBCluster("uat-aurora", "stopping", "UAT"),
+		},
+	}
+
+	svc := newAWSService(env, &aws.Target{Region: "ap-southeast-2", EC2Client: ec2Client, ASGClient: asgClient, RDSClient: rdsClient})
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	rows, err := env.DB.Query("SELECT name, state, server_group FROM servers ORDER BY name")
+	if err != nil {
+		t.Fatalf("failed to query servers: %v", err)
+	}
+	defer rows.Close()
+
+	got := []string{}
+	for rows.Next() {
+		var name, state, group string
+		if err := rows.Scan(&name, &state, &group); err != nil {
+			t.Fatalf("failed to scan server row: %v", err)
+		}
+		got = append(got, name+"/"+state+"/"+group)
+	}
+
+	want := []string{
+		"dev-aurora/on/DEV",
+		"dev-web/off/DEV",
+		"qa-bastion/off/QA",
+		"qa-db/off/QA",
+		"qa-web/on/QA",
+		"uat-aurora/transitioning/UAT",
+		"uat-db/off/UAT",
+		"uat-web/transitioning/UAT",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("servers mismatch, want: %v, got: %v", want, got)
+	}
+}
+
+// A role allowed to describe EC2 only keeps scraping instances, other errors still fail the target
+func TestAWSScrapeAccessDenied_KeepsInstances(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	ec2Client := &fakeEC2Client{Instances: []ec2types.Instance{
+		newInstance("i-0000000000000000a", "qa-app", ec2types.InstanceStateNameRunning, "QA"),
+	}}
+	asgClient := &fakeASGClient{DescribeErr: &smithy.GenericAPIError{Code: "AccessDenied", Message: "not authorized"}}
+	rdsClient := &fakeRDSClient{DescribeErr: &smithy.GenericAPIError{Code: "AccessDenied", Message: "not authorized"}}
+
+	svc := newAWSService(env, &aws.Target{Region: "ap-southeast-2", EC2Client: ec2Client, ASGClient: asgClient, RDSClient: rdsClient})
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	var count int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM servers WHERE unique_id = $1", "i-0000000000000000a").Scan(&count); err != nil {
+		t.Fatalf("failed to query servers: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("want instance scraped, got %d", count)
+	}
+
+	rdsClient.DescribeErr = &smithy.GenericAPIError{Code: "Throttling", Message: "rate exceeded"}
+	if err := svc.Scrape(); err == nil {
+		t.Errorf("want scrape error when RDS is throttled")
+	}
+}
+
+// Stopping saves the group capacity and scales to zero, starting restores it
+func TestAWSStopStartASGAndRDS_RestoresCapacity(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	asgClient := &fakeASGClient{Groups: []astypes.AutoScalingGroup{newASG("qa-web", "QA", 1, 3, 3)}}
+	rdsClient := &fakeRDSClient{
+		Instances: []rdstypes.DBInstance{newDBInstance("qa-db", "available", "QA", "")},
+		Clusters:  []rdstypes.DBCluster{newDBCluster("qa-aurora", "available", "QA")},
+	}
+
+	svc := newAWSService(env, &aws.Target{Region: "ap-southeast-2", EC2Client: &fakeEC2Client{}, ASGClient: asgClient, RDSClient: rdsClient})
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	if _, err := env.DB.Exec("UPDATE servers SET next_state = $1 WHERE server_group = $2", "off", "QA"); err != nil {
+		t.Fatalf("failed to update server: %v", err)
+	}
+
+	if err := svc.Stop(); err != nil {
+		t.Fatalf("stop failed: %v", err)
+	}
+
+	group := asgClient.Groups[0]
+	if awssdk.ToInt32(group.MinSize) != 0 || awssdk.ToInt32(group.DesiredCapacity) != 0 {
+		t.Fatalf("want group scaled to zero, got min %d desired %d", awssdk.ToInt32(group.MinSize), awssdk.ToInt32(group.DesiredCapacity))
+	}
+
+	saved := ""
+	for _, tag := range group.Tags {
+		if awssdk.ToString(tag.Key) == "ez2boot:saved-capacity" {
+			saved = awssdk.ToString(tag.Value)
+		}
+	}
+	if saved != "min=1,desired=3" {
+		t.Fatalf("want saved capacity tag, got %q", saved)
+	}
+
+	// Now off and requested on
+	testutil.UpdateServerState(t, env.DB, "QA", "off")
+	testutil.InsertUser(t, env.DB, "user@example.com", nil, true, false, false, true, "local")
+	testutil.InsertServerSession(t, env.DB, 1, "QA", time.Now().Add(1*time.Hour).Unix())
+
+	if err := svc.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+
+	group = asgClient.Groups[0]
+	if awssdk.ToInt32(group.MinSize) != 1 || awssdk.ToInt32(group.DesiredCapacity) != 3 {
+		t.Errorf("want capacity restored to min 1 desired 3, got min %d desired %d", awssdk.ToInt32(group.MinSize), awssdk.ToInt32(group.DesiredCapacity))
+	}
+	if len(group.Tags) != 1 {
+		t.Errorf("want saved capacity tag removed, got %v", group.Tags)
+	}
+
+	want := []string{"start-cluster/qa-aurora", "start-instance/qa-db", "stop-cluster/qa-aurora", "stop-instance/qa-db"}
+	slices.Sort(rdsClient.Calls)
+	if fmt.Sprint(rdsClient.Calls) != fmt.Sprint(want) {
+		t.Errorf("rds calls mismatch, want: %v, got: %v", want, rdsClient.Calls)
+	}
+}
@@ -6,9 +6,14 @@ import (
 	"ez2boot/internal/server"
 	"fmt"
 	"log/slog"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
 )
 
 func NewService(azureRepo *Repository, cfg *config.Config, serverService *server.Service, logger *slog.Logger) (*Service, error) {
@@ -17,16 +22,45 @@ func NewService(azureRepo *Repository, cfg *config.Config, serverService *server
 		return nil, fmt.Errorf("Failed to load Azure credentials %w", err)
 	}
 
-	vmClient, err := armcompute.NewVirtualMachinesClient(cfg.AzureSubscriptionID, cred, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create VM client: %w", err)
+	// Instance views are read per VM in scoped subscriptions, allow more retries when ARM throttles. Retry-After is honoured
+	vmOptions := &arm.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			Retry: policy.RetryOptions{MaxRetries: 6, MaxRetryDelay: time.Minute},
+		},
+	}
+
+	// One client per subscription, sharing the same credential
+	subscriptions := []*Subscription{}
+	for _, sub := range cfg.AzureSubscriptions {
+		vmClient, err := armcompute.NewVirtualMachinesClient(sub.ID, cred, vmOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create VM client for subscription %s: %w", sub.ID, err)
+		}
+
+		nicClient, err := armnetwork.NewInterfacesClient(sub.ID, cred, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create network interface client for subscription %s: %w", sub.ID, err)
+		}
+
+		publicIPClient, err := armnetwork.NewPublicIPAddressesClient(sub.ID, cred, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create public IP client for subscription %s: %w", sub.ID, err)
+		}
+
+		subscriptions = append(subscriptions, &Subscription{
+			ID:             sub.ID,
+			ResourceGroups: sub.ResourceGroups,
+			VMClient:       vmClient,
+			NICClient:      nicClient,
+			PublicIPClient: publicIPClient,
+		})
 	}
 
 	return &Service{
 		Repo:          azureRepo,
 		Config:        cfg,
 		ServerService: serverService,
-		VMClient:      vmClient,
+		Subscriptions: subscriptions,
 		Logger:        logger,
 	}, nil
 }
@@ -5,8 +5,11 @@ import (
 	"ez2boot/internal/db"
 	"ez2boot/internal/server"
 	"log/slog"
+	"sync"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
 )
 
 type Repository struct {
@@ -17,6 +20,30 @@ type Service struct {
 	Repo          *Repository
 	Config        *config.Config
 	ServerService *server.Service
-	VMClient      *armcompute.VirtualMachinesClient
+	Subscriptions []*Subscription
 	Logger        *slog.Logger
+	inFlight      map[string]bool // Lower case VM IDs with a start or stop being tracked
+	operations    sync.WaitGroup
+	mu            sync.Mutex
+}
+
+const (
+	pollFrequency        = 10 * time.Second
+	operationTimeout     = 30 * time.Minute
+	maxInstanceViewReads = 8 // Concurrent instance view reads per subscription, keeps within ARM read limits
+)
+
+// A subscription with clients scoped to it, optionally limited to specific resource groups
+type Subscription struct {
+	ID             string
+	ResourceGroups []string // Empty scrapes all resource groups
+	VMClient       *armcompute.VirtualMachinesClient
+	NICClient      *armnetwork.InterfacesClient        // Optional, IP addresses are left empty without it
+	PublicIPClient *armnetwork.PublicIPAddressesClient // Optional, public IP addresses are left empty without it
+}
+
+// Network interfaces and public IP addresses listed in the same scope as the VMs, keyed by lower case resource ID
+type networkIndex struct {
+	nics      map[string]*armnetwork.Interface
+	publicIPs map[string]string
 }
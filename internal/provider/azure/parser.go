@@ -1,11 +1,14 @@
 package azure
 
 import (
+	"context"
 	"ez2boot/internal/server"
 	"fmt"
 	"strings"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
 )
 
 // Iterate the VM properties to find power state
@@ -21,6 +24,167 @@ func getPowerState(vm *armcompute.VirtualMachine) string {
 	return "unknown"
 }
 
+// Collect the descriptive details of a VM. IP addresses live on network interfaces, see getIPs
+func getMetadata(vm *armcompute.VirtualMachine) server.Metadata {
+	m := server.Metadata{}
+
+	// Location is the region, the zone number is only set when the VM is pinned to one
+	if vm.Location != nil {
+		m.Region = *vm.Location
+	}
+
+	if len(vm.Zones) > 0 && vm.Zones[0] != nil {
+		m.Zone = *vm.Zones[0]
+	}
+
+	props := vm.Properties
+	if props == nil {
+		return m
+	}
+
+	if props.HardwareProfile != nil && props.HardwareProfile.VMSize != nil {
+		m.InstanceType = string(*props.HardwareProfile.VMSize)
+	}
+
+	if props.StorageProfile != nil && props.StorageProfile.OSDisk != nil && props.StorageProfile.OSDisk.OSType != nil {
+		m.Platform = string(*props.StorageProfile.OSDisk.OSType)
+	}
+
+	if props.TimeCreated != nil {
+		m.LaunchTime = props.TimeCreated.Unix()
+	}
+
+	return m
+}
+
+// Find the primary private and public IP addresses of a VM through its primary network interface. Interfaces and public
+// IPs outside the listed scope, eg in another resource group, are fetched directly
+func (s *Service) getIPs(sub *Subscription, vm *armcompute.VirtualMachine, network networkIndex) (string, string) {
+	if sub.NICClient == nil || vm.Properties == nil || vm.Properties.NetworkProfile == nil {
+		return "", ""
+	}
+
+	nicID := ""
+	for _, ref := range vm.Properties.NetworkProfile.NetworkInterfaces {
+		if ref == nil || ref.ID == nil {
+			continue
+		}
+
+		primary := ref.Properties != nil && ref.Properties.Primary != nil && *ref.Properties.Primary
+		if nicID == "" || primary {
+			nicID = *ref.ID
+		}
+		if primary {
+			break
+		}
+	}
+
+	if nicID == "" {
+		return "", ""
+	}
+
+	nic, ok := network.nics[strings.ToLower(nicID)]
+	if !ok {
+		id, err := arm.ParseResourceID(nicID)
+		if err != nil {
+			s.Logger.Error("Failed to parse network interface ID", "id", nicID, "domain", "azure", "error", err)
+			return "", ""
+		}
+
+		resp, err := sub.NICClient.Get(context.Background(), id.ResourceGroupName, id.Name, nil)
+		if err != nil {
+			s.Logger.Error("Failed to get network interface", "id", nicID, "domain", "azure", "error", err)
+			return "", ""
+		}
+		nic = &resp.Interface
+	}
+
+	config := getPrimaryIPConfig(nic)
+	if config == nil || config.Properties == nil {
+		return "", ""
+	}
+
+	privateIP := ""
+	if config.Properties.PrivateIPAddress != nil {
+		privateIP = *config.Properties.PrivateIPAddress
+	}
+
+	if sub.PublicIPClient == nil || config.Properties.PublicIPAddress == nil || config.Properties.PublicIPAddress.ID == nil {
+		return privateIP, ""
+	}
+
+	publicIPID := *config.Properties.PublicIPAddress.ID
+	if publicIP, ok := network.publicIPs[strings.ToLower(publicIPID)]; ok {
+		return privateIP, publicIP
+	}
+
+	id, err := arm.ParseResourceID(publicIPID)
+	if err != nil {
+		s.Logger.Error("Failed to parse public IP ID", "id", publicIPID, "domain", "azure", "error", err)
+		return privateIP, ""
+	}
+
+	resp, err := sub.PublicIPClient.Get(context.Background(), id.ResourceGroupName, id.Name, nil)
+	if err != nil {
+		s.Logger.Error("Failed to get public IP", "id", publicIPID, "domain", "azure", "error", err)
+		return privateIP, ""
+	}
+
+	if resp.Properties == nil || resp.Properties.IPAddress == nil {
+		return privateIP, ""
+	}
+
+	return privateIP, *resp.Properties.IPAddress
+}
+
+// The primary IP configuration of a network interface, or the first when none is marked
+func getPrimaryIPConfig(nic *armnetwork.Interface) *armnetwork.InterfaceIPConfiguration {
+	if nic.Properties == nil {
+		return nil
+	}
+
+	var config *armnetwork.InterfaceIPConfiguration
+	for _, c := range nic.Properties.IPConfigurations {
+		if c == nil {
+			continue
+		}
+
+		if c.Properties != nil && c.Properties.Primary != nil && *c.Properties.Primary {
+			return c
+		}
+
+		if config == nil {
+			config = c
+		}
+	}
+
+	return config
+}
+
+// Companion tags describing the server group, eg ez2boot:description
+func getGroupTags(vm *armcompute.VirtualMachine, tagKey string) map[string]string {
+	tags := make(map[string]string, len(vm.Tags))
+	for key, value := range vm.Tags {
+		if value != nil {
+			tags[key] = *value
+		}
+	}
+	return server.GetGroupTags(tagKey, tags)
+}
+
+// Server state from the power state read this scrape, falling back to the recorded state when it could not be read
+func getServerState(id string, powerStates map[string]string, recorded map[string]server.ServerState) (server.ServerState, error) {
+	if powerState, ok := powerStates[strings.ToLower(id)]; ok {
+		return mapState(powerState), nil
+	}
+
+	if state, ok := recorded[id]; ok {
+		return state, nil
+	}
+
+	return "", fmt.Errorf("no power state for new VM %s", id)
+}
+
 // Map provider specific states to generic
 func mapState(state string) server.ServerState {
 	switch state {
@@ -35,10 +199,20 @@ func mapState(state string) server.ServerState {
 
 // Azure VM ID is a long string with important values embedded
 // /subscriptions/{sub}/resourceGroups/{rg}/providers/Microsoft.Compute/virtualMachines/{name}
-func parseVMID(id string) (resourceGroup, vmName string, err error) {
+func parseVMID(id string) (subscriptionID, resourceGroup, vmName string, err error) {
 	parts := strings.Split(id, "/")
 	if len(parts) < 9 {
-		return "", "", fmt.Errorf("invalid VM ID: %s", id)
+		return "", "", "", fmt.Errorf("invalid VM ID: %s", id)
+	}
+	return parts[2], parts[4], parts[8], nil
+}
+
+// Find the configured subscription which owns the VM. Azure IDs are case insensitive
+func (s *Service) getSubscription(subscriptionID string) (*Subscription, error) {
+	for _, sub := range s.Subscriptions {
+		if strings.EqualFold(sub.ID, subscriptionID) {
+			return sub, nil
+		}
 	}
-	return parts[4], parts[8], nil
+	return nil, fmt.Errorf("subscription not configured: %s", subscriptionID)
 }
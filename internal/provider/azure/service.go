@@ -2,64 +2,335 @@ package azure
 
 import (
 	"context"
+	"errors"
 	"ez2boot/internal/server"
+	"ez2boot/internal/shared"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
 )
 
 // Scrape Azure to retrieve servers.
 func (s *Service) Scrape() error {
-	s.Logger.Debug("Scraping Azure", "domain", "azure")
+	s.Logger.Debug("Scraping Azure", "domain", "azure", "subscriptions", len(s.Subscriptions))
 
-	pager := s.VMClient.NewListAllPager(nil)
+	type result struct {
+		servers []server.Server
+		err     error
+	}
+
+	// Fan out across all subscriptions
+	results := make([]result, len(s.Subscriptions))
+	var wg sync.WaitGroup
+	for i, sub := range s.Subscriptions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			servers, err := s.scrapeSubscription(sub)
+			results[i] = result{servers: servers, err: err}
+		}()
+	}
+	wg.Wait()
 
 	servers := []server.Server{}
+	failedSubscriptions := []string{}
+	errs := []error{}
+	for i, r := range results {
+		// A failed subscription's servers are kept as recorded, the other subscriptions are still updated
+		if r.err != nil {
+			s.Logger.Error("Failed to list Azure VMs", "domain", "azure", "subscription", s.Subscriptions[i].ID, "error", r.err)
+			failedSubscriptions = append(failedSubscriptions, s.Subscriptions[i].ID)
+			errs = append(errs, fmt.Errorf("failed to scrape subscription %s: %w", s.Subscriptions[i].ID, r.err))
+			continue
+		}
+
+		for j := range r.servers {
+			r.servers[j].Target = s.Subscriptions[i].ID
+		}
+
+		servers = append(servers, r.servers...)
+	}
+
+	s.Logger.Debug("Scraped and found number of matching VMs", "domain", "azure", "count", len(servers), "failed_subscriptions", len(failedSubscriptions))
+	s.ServerService.UpdateServersPartial(shared.CloudProviderAzure, servers, failedSubscriptions)
+
+	return errors.Join(errs...)
+}
+
+// List VMs in a subscription, or only in its configured resource groups
+func (s *Service) listVMs(sub *Subscription) ([]*armcompute.VirtualMachine, error) {
+	vms := []*armcompute.VirtualMachine{}
+
+	if len(sub.ResourceGroups) == 0 {
+		pager := sub.VMClient.NewListAllPager(nil)
+		for pager.More() {
+			page, err := pager.NextPage(context.Background())
+			if err != nil {
+				return nil, err
+			}
+			vms = append(vms, page.Value...)
+		}
+
+		return vms, nil
+	}
+
+	for _, rg := range sub.ResourceGroups {
+		pager := sub.VMClient.NewListPager(rg, nil)
+		for pager.More() {
+			page, err := pager.NextPage(context.Background())
+			if err != nil {
+				return nil, fmt.Errorf("resource group %s: %w", rg, err)
+			}
+			vms = append(vms, page.Value...)
+		}
+	}
+
+	return vms, nil
+}
+
+// Get the power state of the tagged VMs, keyed by lower case VM ID. An unscoped subscription uses a single status only listing. Status
+// only listing is subscription wide, so a subscription scoped to resource groups reads each VM's instance view in its group instead,
+// as do VMs created between the two listings. VMs whose instance view could not be read are left out
+func (s *Service) getPowerStates(sub *Subscription, vms []*armcompute.VirtualMachine) (map[string]string, error) {
+	states := make(map[string]string)
+	if len(sub.ResourceGroups) == 0 {
+		listed, err := s.listPowerStates(sub)
+		if err != nil {
+			return nil, err
+		}
+		states = listed
+	}
+
+	missing := []*armcompute.VirtualMachine{}
+	for _, vm := range vms {
+		if _, ok := states[strings.ToLower(*vm.ID)]; !ok {
+			missing = append(missing, vm)
+		}
+	}
+
+	for id, state := range s.readInstanceViews(sub, missing) {
+		states[id] = state
+	}
+
+	return states, nil
+}
+
+// Read instance views with a bounded number in flight. Throttled reads are retried by the client, honouring Retry-After
+func (s *Service) readInstanceViews(sub *Subscription, vms []*armcompute.VirtualMachine) map[string]string {
+	states := make(map[string]string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxInstanceViewReads)
+
+	for _, vm := range vms {
+		_, resourceGroup, vmName, err := parseVMID(*vm.ID)
+		if err != nil {
+			continue // Reported by the scrape
+		}
+
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			detail, err := sub.VMClient.Get(context.Background(), resourceGroup, vmName, &armcompute.VirtualMachinesClientGetOptions{
+				Expand: to.Ptr(armcompute.InstanceViewTypesInstanceView),
+			})
+			if err != nil {
+				s.Logger.Error("Failed to get VM instance view", "name", vmName, "subscription", sub.ID, "domain", "azure", "error", err)
+				return
+			}
+
+			mu.Lock()
+			states[strings.ToLower(*vm.ID)] = getPowerState(&detail.VirtualMachine)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return states
+}
+
+// List the power state of every VM in the subscription from a single status only listing, keyed by lower case VM ID
+func (s *Service) listPowerStates(sub *Subscription) (map[string]string, error) {
+	states := make(map[string]string)
+
+	pager := sub.VMClient.NewListAllPager(&armcompute.VirtualMachinesClientListAllOptions{
+		StatusOnly: to.Ptr("true"),
+	})
 	for pager.More() {
 		page, err := pager.NextPage(context.Background())
 		if err != nil {
-			s.Logger.Error("Failed to list Azure VMs", "domain", "azure", "error", err)
-			return err
+			return nil, err
 		}
 
 		for _, vm := range page.Value {
-			// Filter by tag key
-			if _, ok := vm.Tags[s.Config.TagKey]; !ok {
+			if vm.ID == nil {
 				continue
 			}
+			states[strings.ToLower(*vm.ID)] = getPowerState(vm)
+		}
+	}
+
+	return states, nil
+}
+
+// List network interfaces and public IPs in the same scope as the VMs, so IP addresses resolve without a call per VM
+func (s *Service) listNetwork(sub *Subscription) (networkIndex, error) {
+	network := networkIndex{nics: make(map[string]*armnetwork.Interface), publicIPs: make(map[string]string)}
+	if sub.NICClient == nil {
+		return network, nil
+	}
+
+	addNICs := func(nics []*armnetwork.Interface) {
+		for _, nic := range nics {
+			if nic != nil && nic.ID != nil {
+				network.nics[strings.ToLower(*nic.ID)] = nic
+			}
+		}
+	}
+
+	addPublicIPs := func(ips []*armnetwork.PublicIPAddress) {
+		for _, ip := range ips {
+			if ip != nil && ip.ID != nil && ip.Properties != nil && ip.Properties.IPAddress != nil {
+				network.publicIPs[strings.ToLower(*ip.ID)] = *ip.Properties.IPAddress
+			}
+		}
+	}
 
-			resourceGroup, vmName, err := parseVMID(*vm.ID)
+	if len(sub.ResourceGroups) == 0 {
+		nicPager := sub.NICClient.NewListAllPager(nil)
+		for nicPager.More() {
+			page, err := nicPager.NextPage(context.Background())
 			if err != nil {
-				s.Logger.Error("Failed to parse VM ID", "id", *vm.ID, "domain", "azure", "error", err)
-				continue
+				return network, err
 			}
+			addNICs(page.Value)
+		}
 
-			// Fetch instance view for power state
-			detail, err := s.VMClient.Get(context.Background(), resourceGroup, vmName, &armcompute.VirtualMachinesClientGetOptions{
-				Expand: to.Ptr(armcompute.InstanceViewTypesInstanceView),
-			})
+		if sub.PublicIPClient == nil {
+			return network, nil
+		}
+
+		ipPager := sub.PublicIPClient.NewListAllPager(nil)
+		for ipPager.More() {
+			page, err := ipPager.NextPage(context.Background())
 			if err != nil {
-				s.Logger.Error("Failed to get VM instance view", "name", vmName, "domain", "azure", "error", err)
-				continue
+				return network, err
+			}
+			addPublicIPs(page.Value)
+		}
+
+		return network, nil
+	}
+
+	for _, rg := range sub.ResourceGroups {
+		nicPager := sub.NICClient.NewListPager(rg, nil)
+		for nicPager.More() {
+			page, err := nicPager.NextPage(context.Background())
+			if err != nil {
+				return network, fmt.Errorf("resource group %s: %w", rg, err)
 			}
+			addNICs(page.Value)
+		}
 
-			svr := server.Server{
-				UniqueID:    *vm.ID,
-				Name:        vmName,
-				State:       mapState(getPowerState(&detail.VirtualMachine)),
-				ServerGroup: *vm.Tags[s.Config.TagKey],
-				TimeAdded:   time.Now().Unix(),
+		if sub.PublicIPClient == nil {
+			continue
+		}
+
+		ipPager := sub.PublicIPClient.NewListPager(rg, nil)
+		for ipPager.More() {
+			page, err := ipPager.NextPage(context.Background())
+			if err != nil {
+				return network, fmt.Errorf("resource group %s: %w", rg, err)
 			}
+			addPublicIPs(page.Value)
+		}
+	}
+
+	return network, nil
+}
 
-			servers = append(servers, svr)
+// Find tagged VMs within a single subscription
+func (s *Service) scrapeSubscription(sub *Subscription) ([]server.Server, error) {
+	listed, err := s.listVMs(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	// Filter by tag key
+	vms := []*armcompute.VirtualMachine{}
+	for _, vm := range listed {
+		if _, ok := vm.Tags[s.Config.TagKey]; ok && vm.ID != nil {
+			vms = append(vms, vm)
 		}
 	}
 
-	s.Logger.Debug("Scraped and found number of matching VMs", "domain", "azure", "count", len(servers))
-	s.ServerService.UpdateServers(servers)
+	// Power state in bulk where the scope allows, avoids a call per VM
+	powerStates, err := s.getPowerStates(sub, vms)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list power states: %w", err)
+	}
 
-	return nil
+	// A VM whose state could not be read keeps its recorded state, leaving it out would remove it and end its session
+	var recorded map[string]server.ServerState
+	if len(powerStates) < len(vms) {
+		recorded, err = s.ServerService.GetServerStates(shared.CloudProviderAzure)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get recorded states: %w", err)
+		}
+	}
+
+	// IP addresses are descriptive only, a failed listing leaves any not found to be fetched directly
+	network, err := s.listNetwork(sub)
+	if err != nil {
+		s.Logger.Error("Failed to list network interfaces", "subscription", sub.ID, "domain", "azure", "error", err)
+	}
+
+	servers := []server.Server{}
+	for _, vm := range vms {
+		_, _, vmName, err := parseVMID(*vm.ID)
+		if err != nil {
+			s.Logger.Error("Failed to parse VM ID", "id", *vm.ID, "domain", "azure", "error", err)
+			continue
+		}
+
+		state, err := getServerState(*vm.ID, powerStates, recorded)
+		if err != nil {
+			s.Logger.Warn("Skipping VM until its state can be read", "name", vmName, "subscription", sub.ID, "domain", "azure", "error", err)
+			continue
+		}
+
+		metadata := getMetadata(vm)
+		metadata.PrivateIP, metadata.PublicIP = s.getIPs(sub, vm, network)
+
+		var bootOrder string
+		if value := vm.Tags[server.GetGroupTagKey(s.Config.TagKey, server.TagBootOrder)]; value != nil {
+			bootOrder = *value
+		}
+
+		svr := server.Server{
+			UniqueID:    *vm.ID,
+			Name:        vmName,
+			State:       state,
+			ServerGroup: *vm.Tags[s.Config.TagKey],
+			TimeAdded:   time.Now().Unix(),
+			Metadata:    metadata,
+			GroupTags:   getGroupTags(vm, s.Config.TagKey),
+			BootOrder:   server.ParseBootOrder(bootOrder),
+		}
+
+		servers = append(servers, svr)
+	}
+
+	return servers, nil
 }
 
 // Start required Azure servers
@@ -67,7 +338,7 @@ func (s *Service) Start() error {
 	s.Logger.Debug("Starting requested VMs", "domain", "azure")
 
 	// Get start VM IDs
-	vmIDs, err := s.ServerService.GetPending("off", "on")
+	vmIDs, err := s.ServerService.GetPending(shared.CloudProviderAzure, "off", "on")
 	if err != nil {
 		s.Logger.Error("Failed to get VM IDs pending on", "domain", "azure", "error", err)
 		return err
@@ -81,21 +352,38 @@ func (s *Service) Start() error {
 
 	// Loop and turn each on
 	for _, id := range vmIDs {
-		resourceGroup, vmName, err := parseVMID(id)
+		subscriptionID, resourceGroup, vmName, err := parseVMID(id)
 		if err != nil {
 			s.Logger.Error("Failed to parse VM ID", "id", id, "domain", "azure", "error", err)
 			continue
 		}
 
-		s.Logger.Debug("Starting VM", "name", vmName, "domain", "azure")
+		sub, err := s.getSubscription(subscriptionID)
+		if err != nil {
+			s.Logger.Error("Failed to find client for VM", "id", id, "domain", "azure", "error", err)
+			continue
+		}
+
+		// Previous request still running, the scrape has not caught up yet
+		if !s.claimOperation(id) {
+			s.Logger.Debug("VM operation already in progress", "name", vmName, "subscription", sub.ID, "domain", "azure")
+			continue
+		}
 
-		_, err = s.VMClient.BeginStart(context.Background(), resourceGroup, vmName, nil)
+		s.Logger.Debug("Starting VM", "name", vmName, "subscription", sub.ID, "domain", "azure")
+
+		poller, err := sub.VMClient.BeginStart(context.Background(), resourceGroup, vmName, nil)
 		if err != nil {
-			s.Logger.Error("Failed to start VM", "name", vmName, "domain", "azure", "error", err)
+			s.Logger.Error("Failed to start VM", "name", vmName, "subscription", sub.ID, "domain", "azure", "error", err)
+			s.releaseOperation(id)
+			s.ServerService.RecordFailure(id, "start", err)
 			continue
 		}
 
-		s.Logger.Info("VM start initiated", "name", vmName, "domain", "azure")
+		s.Logger.Info("VM start initiated", "name", vmName, "subscription", sub.ID, "domain", "azure")
+
+		s.operations.Add(1)
+		go trackOperation(s, poller, id, "start")
 	}
 
 	return nil
@@ -105,7 +393,7 @@ func (s *Service) Start() error {
 func (s *Service) Stop() error {
 	s.Logger.Debug("Stopping requested VMs", "domain", "azure")
 
-	vmIDs, err := s.ServerService.GetPending("on", "off")
+	vmIDs, err := s.ServerService.GetPending(shared.CloudProviderAzure, "on", "off")
 	if err != nil {
 		s.Logger.Error("Failed to get VM IDs pending off", "domain", "azure", "error", err)
 		return err
@@ -117,22 +405,86 @@ func (s *Service) Stop() error {
 	}
 
 	for _, id := range vmIDs {
-		resourceGroup, vmName, err := parseVMID(id)
+		subscriptionID, resourceGroup, vmName, err := parseVMID(id)
 		if err != nil {
 			s.Logger.Error("Failed to parse VM ID", "id", id, "domain", "azure", "error", err)
 			continue
 		}
 
-		s.Logger.Debug("Stopping VM", "name", vmName, "domain", "azure")
+		sub, err := s.getSubscription(subscriptionID)
+		if err != nil {
+			s.Logger.Error("Failed to find client for VM", "id", id, "domain", "azure", "error", err)
+			continue
+		}
+
+		if !s.claimOperation(id) {
+			s.Logger.Debug("VM operation already in progress", "name", vmName, "subscription", sub.ID, "domain", "azure")
+			continue
+		}
+
+		s.Logger.Debug("Stopping VM", "name", vmName, "subscription", sub.ID, "domain", "azure")
 
-		_, err = s.VMClient.BeginDeallocate(context.Background(), resourceGroup, vmName, nil)
+		poller, err := sub.VMClient.BeginDeallocate(context.Background(), resourceGroup, vmName, nil)
 		if err != nil {
-			s.Logger.Error("Failed to stop VM", "name", vmName, "domain", "azure", "error", err)
+			s.Logger.Error("Failed to stop VM", "name", vmName, "subscription", sub.ID, "domain", "azure", "error", err)
+			s.releaseOperation(id)
+			s.ServerService.RecordFailure(id, "stop", err)
 			continue
 		}
 
-		s.Logger.Info("VM stop initiated", "name", vmName, "domain", "azure")
+		s.Logger.Info("VM stop initiated", "name", vmName, "subscription", sub.ID, "domain", "azure")
+
+		s.operations.Add(1)
+		go trackOperation(s, poller, id, "stop")
 	}
 
 	return nil
 }
+
+// Wait blocks until all tracked start and stop operations have finished
+func (s *Service) Wait() {
+	s.operations.Wait()
+}
+
+// Poll a long running operation to completion in the background and record the outcome against the server
+func trackOperation[T any](s *Service, poller *runtime.Poller[T], id string, action string) {
+	defer s.operations.Done()
+	defer s.releaseOperation(id)
+
+	ctx, cancel := context.WithTimeout(context.Background(), operationTimeout)
+	defer cancel()
+
+	if _, err := poller.PollUntilDone(ctx, &runtime.PollUntilDoneOptions{Frequency: pollFrequency}); err != nil {
+		s.Logger.Error("VM operation failed", "id", id, "action", action, "domain", "azure", "error", err)
+		s.ServerService.RecordFailure(id, action, err)
+		return
+	}
+
+	s.Logger.Info("VM operation completed", "id", id, "action", action, "domain", "azure")
+	s.ServerService.ClearFailure(id)
+}
+
+// Mark a VM as having an operation in progress, false if one already is
+func (s *Service) claimOperation(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.inFlight == nil {
+		s.inFlight = make(map[string]bool)
+	}
+
+	key := strings.ToLower(id)
+	if s.inFlight[key] {
+		return false
+	}
+
+	s.inFlight[key] = true
+	return true
+}
+
+func (s *Service) releaseOperation(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.inFlight, strings.ToLower(id))
+}
@@ -0,0 +1,533 @@
+package azure_test
+
+import (
+	"context"
+	"ez2boot/internal/provider/azure"
+	"ez2boot/internal/testutil"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	azfake "github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute/v6/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6"
+	netfake "github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork/v6/fake"
+)
+
+// In-memory subscription backing a fake VM server, records start/deallocate calls
+type fakeSubscription struct {
+	ID        string
+	VMs       map[string][]*armcompute.VirtualMachine // Resource group to VMs
+	PowerCode map[string]string                       // VM name to PowerState code
+	mu        sync.Mutex
+	Started   []string
+	Stopped   []string
+	ListCalls []string // Resource group listed, "*" for list all, "status" for status only list all
+	GetCalls  int
+	FailStart map[string]string // VM name to error code returned by the start operation
+	FailGet   map[string]bool   // VM names whose instance view read fails
+}
+
+func newVM(subID string, rg string, name string, group string) *armcompute.VirtualMachine {
+	tags := map[string]*string{}
+	if group != "" {
+		tags["ez2boot"] = to.Ptr(group)
+	}
+
+	return &armcompute.VirtualMachine{
+		ID:   to.Ptr(fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/virtualMachines/%s", subID, rg, name)),
+		Name: to.Ptr(name),
+		Tags: tags,
+	}
+}
+
+func (f *fakeSubscription) instanceView(vmName string) *armcompute.VirtualMachineProperties {
+	return &armcompute.VirtualMachineProperties{
+		InstanceView: &armcompute.VirtualMachineInstanceView{
+			Statuses: []*armcompute.InstanceViewStatus{
+				{Code: to.Ptr("ProvisioningState/succeeded")},
+				{Code: to.Ptr("PowerState/" + f.PowerCode[vmName])},
+			},
+		},
+	}
+}
+
+func (f *fakeSubscription) server() *fake.VirtualMachinesServer {
+	return &fake.VirtualMachinesServer{
+		NewListAllPager: func(options *armcompute.VirtualMachinesClientListAllOptions) (resp azfake.PagerResponder[armcompute.VirtualMachinesClientListAllResponse]) {
+			statusOnly := options != nil && options.StatusOnly != nil && *options.StatusOnly == "true"
+
+			f.mu.Lock()
+			if statusOnly {
+				f.ListCalls = append(f.ListCalls, "status")
+			} else {
+				f.ListCalls = append(f.ListCalls, "*")
+			}
+			f.mu.Unlock()
+
+			all := []*armcompute.VirtualMachine{}
+			for _, vms := range f.VMs {
+				for _, vm := range vms {
+					if statusOnly {
+						vm = &armcompute.VirtualMachine{ID: vm.ID, Name: vm.Name, Properties: f.instanceView(*vm.Name)}
+					}
+					all = append(all, vm)
+				}
+			}
+			resp.AddPage(http.StatusOK, armcompute.VirtualMachinesClientListAllResponse{VirtualMachineListResult: armcompute.VirtualMachineListResult{Value: all}}, nil)
+			return
+		},
+		NewListPager: func(resourceGroupName string, options *armcompute.VirtualMachinesClientListOptions) (resp azfake.PagerResponder[armcompute.VirtualMachinesClientListResponse]) {
+			f.mu.Lock()
+			f.ListCalls = append(f.ListCalls, resourceGroupName)
+			f.mu.Unlock()
+
+			resp.AddPage(http.StatusOK, armcompute.VirtualMachinesClientListResponse{VirtualMachineListResult: armcompute.VirtualMachineListResult{Value: f.VMs[resourceGroupName]}}, nil)
+			return
+		},
+		Get: func(ctx context.Context, resourceGroupName string, vmName string, options *armcompute.VirtualMachinesClientGetOptions) (resp azfake.Responder[armcompute.VirtualMachinesClientGetResponse], errResp azfake.ErrorResponder) {
+			f.mu.Lock()
+			f.GetCalls++
+			fail := f.FailGet[vmName]
+			f.mu.Unlock()
+
+			if fail {
+				errResp.SetResponseError(http.StatusForbidden, "AuthorizationFailed")
+				return
+			}
+
+			vm := armcompute.VirtualMachine{Properties: f.instanceView(vmName)}
+			resp.SetResponse(http.StatusOK, armcompute.VirtualMachinesClientGetResponse{VirtualMachine: vm}, nil)
+			return
+		},
+		BeginStart: func(ctx context.Context, resourceGroupName string, vmName string, options *armcompute.VirtualMachinesClientBeginStartOptions) (resp azfake.PollerResponder[armcompute.VirtualMachinesClientStartResponse], errResp azfake.ErrorResponder) {
+			f.mu.Lock()
+			f.Started = append(f.Started, resourceGroupName+"/"+vmName)
+			code, fail := f.FailStart[vmName]
+			f.mu.Unlock()
+
+			if fail {
+				resp.SetTerminalError(http.StatusConflict, code)
+				return
+			}
+
+			resp.SetTerminalResponse(http.StatusOK, armcompute.VirtualMachinesClientStartResponse{}, nil)
+			return
+		},
+		BeginDeallocate: func(ctx context.Context, resourceGroupName string, vmName string, options *armcompute.VirtualMachinesClientBeginDeallocateOptions) (resp azfake.PollerResponder[armcompute.VirtualMachinesClientDeallocateResponse], errResp azfake.ErrorResponder) {
+			f.mu.Lock()
+			f.Stopped = append(f.Stopped, resourceGroupName+"/"+vmName)
+			f.mu.Unlock()
+
+			resp.SetTerminalResponse(http.StatusOK, armcompute.VirtualMachinesClientDeallocateResponse{}, nil)
+			return
+		},
+	}
+}
+
+// Build a subscription whose client is served by the fake
+func (f *fakeSubscription) subscription(t *testing.T, resourceGroups ...string) *azure.Subscription {
+	t.Helper()
+
+	client, err := armcompute.NewVirtualMachinesClient(f.ID, &azfake.TokenCredential{}, &arm.ClientOptions{
+		ClientOptions: azcore.ClientOptions{Transport: fake.NewVirtualMachinesServerTransport(f.server())},
+	})
+	if err != nil {
+		t.Fatalf("failed to create fake VM client: %v", err)
+	}
+
+	return &azure.Subscription{ID: f.ID, ResourceGroups: resourceGroups, VMClient: client}
+}
+
+func newAzureService(env *testutil.TestEnv, subscriptions ...*azure.Subscription) *azure.Service {
+	env.Cfg.TagKey = "ez2boot"
+
+	return &azure.Service{
+		Repo:          azure.NewRepository(env.Base),
+		Config:        env.Cfg,
+		ServerService: env.ServerService,
+		Subscriptions: subscriptions,
+		Logger:        env.Logger,
+	}
+}
+
+func TestAzureScrapeMultiSubscription_Success(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	subA := &fakeSubscription{
+		ID: "aaaaaaaa-0000-0000-0000-000000000000",
+		VMs: map[string][]*armcompute.VirtualMachine{
+			"rg-app": {newVM("aaaaaaaa-0000-0000-0000-000000000000", "rg-app", "qa-app", "QA")},
+		},
+		PowerCode: map[string]string{"qa-app": "running"},
+	}
+
+	subB := &fakeSubscription{
+		ID: "bbbbbbbb-0000-0000-0000-000000000000",
+		VMs: map[string][]*armcompute.VirtualMachine{
+			"rg-dev":   {newVM("bbbbbbbb-0000-0000-0000-000000000000", "rg-dev", "dev-app", "DEV")},
+			"rg-other": {newVM("bbbbbbbb-0000-0000-0000-000000000000", "rg-other", "other-app", "OTHER")},
+		},
+		PowerCode: map[string]string{"dev-app": "deallocated", "other-app": "running"},
+	}
+
+	svc := newAzureService(env, subA.subscription(t), subB.subscription(t, "rg-dev"))
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	// Unscoped subscription lists all with power state from one status listing. Scoped subscription stays in its resource groups,
+	// reading power state per VM rather than listing the whole subscription
+	if fmt.Sprint(subA.ListCalls) != "[* status]" {
+		t.Errorf("want list all and status list for unscoped subscription, got %v", subA.ListCalls)
+	}
+	if fmt.Sprint(subB.ListCalls) != "[rg-dev]" {
+		t.Errorf("want only rg-dev listed for scoped subscription, got %v", subB.ListCalls)
+	}
+	if subB.GetCalls != 1 {
+		t.Errorf("want one instance view get for scoped subscription, got %d", subB.GetCalls)
+	}
+
+	rows, err := env.DB.Query("SELECT name, state, server_group FROM servers ORDER BY name")
+	if err != nil {
+		t.Fatalf("failed to query servers: %v", err)
+	}
+	defer rows.Close()
+
+	got := []string{}
+	for rows.Next() {
+		var name, state, group string
+		if err := rows.Scan(&name, &state, &group); err != nil {
+			t.Fatalf("failed to scan server row: %v", err)
+		}
+		got = append(got, name+"/"+state+"/"+group)
+	}
+
+	want := []string{"dev-app/off/DEV", "qa-app/on/QA"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("servers mismatch, want: %v, got: %v", want, got)
+	}
+
+	// Both groups pending a state change
+	testutil.InsertUser(t, env.DB, "user@example.com", nil, true, false, false, true, "local")
+	testutil.InsertServerSession(t, env.DB, 1, "DEV", time.Now().Add(1*time.Hour).Unix())
+	if _, err := env.DB.Exec("UPDATE servers SET next_state = $1 WHERE server_group = $2", "off", "QA"); err != nil {
+		t.Fatalf("failed to update server: %v", err)
+	}
+
+	if err := svc.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+
+	if err := svc.Stop(); err != nil {
+		t.Fatalf("stop failed: %v", err)
+	}
+
+	svc.Wait()
+
+	// Calls routed to the subscription in the VM ID
+	if len(subB.Started) != 1 || subB.Started[0] != "rg-dev/dev-app" {
+		t.Errorf("want start routed to subscription b, got %v", subB.Started)
+	}
+	if len(subA.Started) != 0 {
+		t.Errorf("want no starts in subscription a, got %v", subA.Started)
+	}
+	if len(subA.Stopped) != 1 || subA.Stopped[0] != "rg-app/qa-app" {
+		t.Errorf("want stop routed to subscription a, got %v", subA.Stopped)
+	}
+	if len(subB.Stopped) != 0 {
+		t.Errorf("want no stops in subscription b, got %v", subB.Stopped)
+	}
+}
+
+// Power state must come from the bulk status listing, with no per VM calls as VM count grows
+func TestAzureScrape_NoPerVMCalls(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	subID := "aaaaaaaa-0000-0000-0000-000000000000"
+	sub := &fakeSubscription{
+		ID:        subID,
+		VMs:       map[string][]*armcompute.VirtualMachine{},
+		PowerCode: map[string]string{},
+	}
+
+	for i := range 50 {
+		name := fmt.Sprintf("qa%02d", i)
+		sub.VMs["rg-app"] = append(sub.VMs["rg-app"], newVM(subID, "rg-app", name, "QA"))
+		sub.PowerCode[name] = "running"
+	}
+
+	svc := newAzureService(env, sub.subscription(t))
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	if sub.GetCalls != 0 {
+		t.Errorf("want no per VM get calls, got %d", sub.GetCalls)
+	}
+
+	var count int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM servers WHERE state = $1", "on").Scan(&count); err != nil {
+		t.Fatalf("failed to query servers: %v", err)
+	}
+	if count != 50 {
+		t.Errorf("want 50 servers on, got %d", count)
+	}
+}
+
+// A VM whose instance view cannot be read keeps its recorded state instead of being removed, a new one waits for the next scrape
+func TestAzureScrapeScoped_KeepsUnreadState(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	subID := "aaaaaaaa-0000-0000-0000-000000000000"
+	sub := &fakeSubscription{
+		ID: subID,
+		VMs: map[string][]*armcompute.VirtualMachine{
+			"rg-dev": {newVM(subID, "rg-dev", "dev-app", "DEV"), newVM(subID, "rg-dev", "dev-db", "DEV")},
+		},
+		PowerCode: map[string]string{"dev-app": "running", "dev-db": "running"},
+	}
+
+	svc := newAzureService(env, sub.subscription(t, "rg-dev"))
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	sub.PowerCode["dev-app"] = "deallocated"
+	sub.PowerCode["dev-db"] = "deallocated"
+	sub.VMs["rg-dev"] = append(sub.VMs["rg-dev"], newVM(subID, "rg-dev", "dev-new", "DEV"))
+	sub.FailGet = map[string]bool{"dev-app": true, "dev-new": true}
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	rows, err := env.DB.Query("SELECT name, state FROM servers ORDER BY name")
+	if err != nil {
+		t.Fatalf("failed to query servers: %v", err)
+	}
+	defer rows.Close()
+
+	got := []string{}
+	for rows.Next() {
+		var name, state string
+		if err := rows.Scan(&name, &state); err != nil {
+			t.Fatalf("failed to scan server row: %v", err)
+		}
+		got = append(got, name+"/"+state)
+	}
+
+	want := []string{"dev-app/on", "dev-db/off"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("servers mismatch, want: %v, got: %v", want, got)
+	}
+}
+
+// A start operation which fails after being accepted is recorded against the server and the session owner told
+func TestAzureStartOperationFailure_Recorded(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	subID := "aaaaaaaa-0000-0000-0000-000000000000"
+	sub := &fakeSubscription{
+		ID: subID,
+		VMs: map[string][]*armcompute.VirtualMachine{
+			"rg-dev": {newVM(subID, "rg-dev", "dev-app", "DEV")},
+		},
+		PowerCode: map[string]string{"dev-app": "deallocated"},
+		FailStart: map[string]string{"dev-app": "AllocationFailed"},
+	}
+
+	svc := newAzureService(env, sub.subscription(t))
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	testutil.InsertUser(t, env.DB, "user@example.com", nil, true, false, false, true, "local")
+	testutil.InsertServerSession(t, env.DB, 1, "DEV", time.Now().Add(1*time.Hour).Unix())
+
+	if err := svc.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	svc.Wait()
+
+	var lastError *string
+	if err := env.DB.QueryRow("SELECT last_error FROM servers WHERE name = $1", "dev-app").Scan(&lastError); err != nil {
+		t.Fatalf("failed to query server: %v", err)
+	}
+	if lastError == nil {
+		t.Fatal("want failure recorded against server, got none")
+	}
+
+	var notifications int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM notification_queue WHERE user_id = $1", 1).Scan(&notifications); err != nil {
+		t.Fatalf("failed to query notifications: %v", err)
+	}
+	if notifications != 1 {
+		t.Errorf("want 1 notification for session owner, got %d", notifications)
+	}
+
+	var audits int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM audit_log WHERE action = $1 AND resource = $2 AND success = 0", "start", "server").Scan(&audits); err != nil {
+		t.Fatalf("failed to query audit log: %v", err)
+	}
+	if audits != 1 {
+		t.Errorf("want 1 failed start audit event, got %d", audits)
+	}
+
+	// Next attempt succeeds and clears the failure
+	sub.mu.Lock()
+	delete(sub.FailStart, "dev-app")
+	sub.mu.Unlock()
+
+	// Fresh client, the fake server only tracks one poller per VM
+	svc.Subscriptions = []*azure.Subscription{sub.subscription(t)}
+
+	if err := svc.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	svc.Wait()
+
+	if err := env.DB.QueryRow("SELECT last_error FROM servers WHERE name = $1", "dev-app").Scan(&lastError); err != nil {
+		t.Fatalf("failed to query server: %v", err)
+	}
+	if lastError != nil {
+		t.Errorf("want failure cleared after successful start, got %q", *lastError)
+	}
+}
+
+// IP addresses resolve through the VM's primary network interface, with location and zone stored apart
+func TestAzureScrape_NetworkMetadata(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	subID := "aaaaaaaa-0000-0000-0000-000000000000"
+	nicID := fmt.Sprintf("/subscriptions/%s/resourceGroups/rg-app/providers/Microsoft.Network/networkInterfaces/qa-app-nic", subID)
+	secondaryNICID := fmt.Sprintf("/subscriptions/%s/resourceGroups/rg-app/providers/Microsoft.Network/networkInterfaces/qa-app-nic2", subID)
+	publicIPID := fmt.Sprintf("/subscriptions/%s/resourceGroups/rg-net/providers/Microsoft.Network/publicIPAddresses/qa-app-ip", subID) // Outside the scraped resource group
+
+	vm := newVM(subID, "rg-app", "qa-app", "QA")
+	vm.Location = to.Ptr("uksouth")
+	vm.Zones = []*string{to.Ptr("2")}
+	vm.Properties = &armcompute.VirtualMachineProperties{
+		NetworkProfile: &armcompute.NetworkProfile{
+			NetworkInterfaces: []*armcompute.NetworkInterfaceReference{
+				{ID: to.Ptr(secondaryNICID), Properties: &armcompute.NetworkInterfaceReferenceProperties{Primary: to.Ptr(false)}},
+				{ID: to.Ptr(nicID), Properties: &armcompute.NetworkInterfaceReferenceProperties{Primary: to.Ptr(true)}},
+			},
+		},
+	}
+
+	sub := &fakeSubscription{
+		ID:        subID,
+		VMs:       map[string][]*armcompute.VirtualMachine{"rg-app": {vm}},
+		PowerCode: map[string]string{"qa-app": "running"},
+	}
+
+	nics := []*armnetwork.Interface{
+		{
+			ID: to.Ptr(secondaryNICID),
+			Properties: &armnetwork.InterfacePropertiesFormat{
+				IPConfigurations: []*armnetwork.InterfaceIPConfiguration{
+					{Properties: &armnetwork.InterfaceIPConfigurationPropertiesFormat{Primary: to.Ptr(true), PrivateIPAddress: to.Ptr("10.0.1.9")}},
+				},
+			},
+		},
+		{
+			ID: to.Ptr(nicID),
+			Properties: &armnetwork.InterfacePropertiesFormat{
+				IPConfigurations: []*armnetwork.InterfaceIPConfiguration{
+					{Properties: &armnetwork.InterfaceIPConfigurationPropertiesFormat{Primary: to.Ptr(false), PrivateIPAddress: to.Ptr("10.0.0.5")}},
+					{Properties: &armnetwork.InterfaceIPConfigurationPropertiesFormat{
+						Primary:          to.Ptr(true),
+						PrivateIPAddress: to.Ptr("10.0.0.4"),
+						PublicIPAddress:  &armnetwork.PublicIPAddress{ID: to.Ptr(publicIPID)},
+					}},
+				},
+			},
+		},
+	}
+
+	var mu sync.Mutex
+	nicLists := []string{}
+	publicIPGets := []string{}
+
+	nicServer := &netfake.InterfacesServer{
+		NewListPager: func(resourceGroupName string, options *armnetwork.InterfacesClientListOptions) (resp azfake.PagerResponder[armnetwork.InterfacesClientListResponse]) {
+			mu.Lock()
+			nicLists = append(nicLists, resourceGroupName)
+			mu.Unlock()
+
+			resp.AddPage(http.StatusOK, armnetwork.InterfacesClientListResponse{InterfaceListResult: armnetwork.InterfaceListResult{Value: nics}}, nil)
+			return
+		},
+	}
+
+	publicIPServer := &netfake.PublicIPAddressesServer{
+		NewListPager: func(resourceGroupName string, options *armnetwork.PublicIPAddressesClientListOptions) (resp azfake.PagerResponder[armnetwork.PublicIPAddressesClientListResponse]) {
+			resp.AddPage(http.StatusOK, armnetwork.PublicIPAddressesClientListResponse{}, nil)
+			return
+		},
+		Get: func(ctx context.Context, resourceGroupName string, publicIPAddressName string, options *armnetwork.PublicIPAddressesClientGetOptions) (resp azfake.Responder[armnetwork.PublicIPAddressesClientGetResponse], errResp azfake.ErrorResponder) {
+			mu.Lock()
+			publicIPGets = append(publicIPGets, resourceGroupName+"/"+publicIPAddressName)
+			mu.Unlock()
+
+			ip := armnetwork.PublicIPAddress{ID: to.Ptr(publicIPID), Properties: &armnetwork.PublicIPAddressPropertiesFormat{IPAddress: to.Ptr("20.0.0.1")}}
+			resp.SetResponse(http.StatusOK, armnetwork.PublicIPAddressesClientGetResponse{PublicIPAddress: ip}, nil)
+			return
+		},
+	}
+
+	opts := func(transport policy.Transporter) *arm.ClientOptions {
+		return &arm.ClientOptions{ClientOptions: azcore.ClientOptions{Transport: transport}}
+	}
+
+	nicClient, err := armnetwork.NewInterfacesClient(subID, &azfake.TokenCredential{}, opts(netfake.NewInterfacesServerTransport(nicServer)))
+	if err != nil {
+		t.Fatalf("failed to create fake network interface client: %v", err)
+	}
+
+	publicIPClient, err := armnetwork.NewPublicIPAddressesClient(subID, &azfake.TokenCredential{}, opts(netfake.NewPublicIPAddressesServerTransport(publicIPServer)))
+	if err != nil {
+		t.Fatalf("failed to create fake public IP client: %v", err)
+	}
+
+	subscription := sub.subscription(t, "rg-app")
+	subscription.NICClient = nicClient
+	subscription.PublicIPClient = publicIPClient
+
+	svc := newAzureService(env, subscription)
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	// Network listed once in the scraped resource group, public IP outside it fetched directly
+	if fmt.Sprint(nicLists) != "[rg-app]" {
+		t.Errorf("want network interfaces listed in rg-app only, got %v", nicLists)
+	}
+	if fmt.Sprint(publicIPGets) != "[rg-net/qa-app-ip]" {
+		t.Errorf("want public IP fetched from rg-net, got %v", publicIPGets)
+	}
+
+	var privateIP, publicIP, zone, region string
+	if err := env.DB.QueryRow("SELECT private_ip, public_ip, zone, region FROM servers WHERE name = $1", "qa-app").Scan(&privateIP, &publicIP, &zone, &region); err != nil {
+		t.Fatalf("failed to query server: %v", err)
+	}
+
+	got := []string{privateIP, publicIP, zone, region}
+	want := []string{"10.0.0.4", "20.0.0.1", "2", "uksouth"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("metadata mismatch, want: %v, got: %v", want, got)
+	}
+}
@@ -0,0 +1,115 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Minimal Engine API client over HTTP, connecting through a unix socket or TCP
+type engineClient struct {
+	baseURL string
+	http    *http.Client
+}
+
+// Build a client from a DOCKER_HOST style address eg unix:///var/run/docker.sock, tcp://127.0.0.1:2375
+func NewEngineClient(host string) (EngineClient, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Docker host %q: %w", host, err)
+	}
+
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		}
+
+		// Host is ignored when dialing the socket, but must be present in the URL
+		return &engineClient{baseURL: "http://docker", http: &http.Client{Transport: transport, Timeout: 30 * time.Second}}, nil
+	case "tcp", "http":
+		return &engineClient{baseURL: "http://" + u.Host, http: &http.Client{Timeout: 30 * time.Second}}, nil
+	case "https":
+		return &engineClient{baseURL: "https://" + u.Host, http: &http.Client{Timeout: 30 * time.Second}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported Docker host scheme %q", u.Scheme)
+	}
+}
+
+// List all containers, running or not, carrying the label key
+func (c *engineClient) ListContainers(ctx context.Context, labelKey string) ([]Container, error) {
+	filters, err := json.Marshal(map[string][]string{"label": {labelKey}})
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Set("all", "1")
+	query.Set("filters", string(filters))
+
+	resp, err := c.do(ctx, http.MethodGet, "/containers/json?"+query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	containers := []Container{}
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return nil, fmt.Errorf("failed to decode container list: %w", err)
+	}
+
+	return containers, nil
+}
+
+func (c *engineClient) StartContainer(ctx context.Context, id string) error {
+	resp, err := c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/start")
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+func (c *engineClient) StopContainer(ctx context.Context, id string) error {
+	resp, err := c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/stop")
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+// Send a request and turn error status codes into errors. 304 means the container was already in the requested state
+func (c *engineClient) do(ctx context.Context, method string, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+
+		return nil, fmt.Errorf("%s %s returned %d: %s", method, strings.SplitN(path, "?", 2)[0], resp.StatusCode, apiErr.Message)
+	}
+
+	return resp, nil
+}
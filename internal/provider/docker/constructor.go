@@ -0,0 +1,30 @@
+package docker
+
+import (
+	"ez2boot/internal/config"
+	"ez2boot/internal/db"
+	"ez2boot/internal/server"
+	"fmt"
+	"log/slog"
+)
+
+func NewService(dockerRepo *Repository, cfg *config.Config, serverService *server.Service, logger *slog.Logger) (*Service, error) {
+	client, err := NewEngineClient(cfg.DockerHost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Docker client: %w", err)
+	}
+
+	return &Service{
+		Repo:          dockerRepo,
+		Config:        cfg,
+		ServerService: serverService,
+		Client:        client,
+		Logger:        logger,
+	}, nil
+}
+
+func NewRepository(base *db.Repository) *Repository {
+	return &Repository{
+		Base: base,
+	}
+}
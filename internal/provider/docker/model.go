@@ -0,0 +1,47 @@
+package docker
+
+import (
+	"context"
+	"ez2boot/internal/config"
+	"ez2boot/internal/db"
+	"ez2boot/internal/server"
+	"log/slog"
+)
+
+type Repository struct {
+	Base *db.Repository
+}
+
+type Service struct {
+	Repo          *Repository
+	Config        *config.Config
+	ServerService *server.Service
+	Client        EngineClient
+	Logger        *slog.Logger
+}
+
+// Subset of the Docker Engine API used by this provider. Podman serves the same API
+type EngineClient interface {
+	ListContainers(ctx context.Context, labelKey string) ([]Container, error)
+	StartContainer(ctx context.Context, id string) error
+	StopContainer(ctx context.Context, id string) error
+}
+
+// Container as returned by the Engine API container list
+type Container struct {
+	ID              string            `json:"Id"`
+	Names           []string          `json:"Names"`
+	Image           string            `json:"Image"`
+	State           string            `json:"State"`
+	Created         int64             `json:"Created"`
+	Labels          map[string]string `json:"Labels"`
+	NetworkSettings NetworkSettings   `json:"NetworkSettings"`
+}
+
+type NetworkSettings struct {
+	Networks map[string]Network `json:"Networks"`
+}
+
+type Network struct {
+	IPAddress string `json:"IPAddress"`
+}
@@ -0,0 +1,59 @@
+package docker
+
+import (
+	"ez2boot/internal/server"
+	"sort"
+	"strings"
+)
+
+// Map container states to generic
+func mapState(state string) server.ServerState {
+	switch state {
+	case "running", "paused":
+		return server.ServerOn
+	case "restarting", "removing":
+		return server.ServerTransitioning
+	default: // created, exited, dead
+		return server.ServerOff
+	}
+}
+
+// Container names are returned with a leading slash, fall back to the short ID if unnamed
+func getContainerName(c Container) string {
+	if len(c.Names) > 0 {
+		return strings.TrimPrefix(c.Names[0], "/")
+	}
+
+	if len(c.ID) > 12 {
+		return c.ID[:12]
+	}
+
+	return c.ID
+}
+
+// Collect the descriptive details of a container. The image stands in for the instance type
+func getMetadata(c Container) server.Metadata {
+	return server.Metadata{
+		InstanceType: c.Image,
+		PrivateIP:    getContainerIP(c),
+		Platform:     "container",
+		LaunchTime:   c.Created,
+	}
+}
+
+// Containers may join several networks, use the first by name so the address is stable between scrapes
+func getContainerIP(c Container) string {
+	names := make([]string, 0, len(c.NetworkSettings.Networks))
+	for name := range c.NetworkSettings.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if ip := c.NetworkSettings.Networks[name].IPAddress; ip != "" {
+			return ip
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,109 @@
+package docker
+
+import (
+	"context"
+	"ez2boot/internal/server"
+	"ez2boot/internal/shared"
+	"time"
+)
+
+// Scrape the Docker Engine to retrieve servers.
+func (s *Service) Scrape() error {
+	s.Logger.Debug("Scraping Docker", "domain", "docker")
+
+	containers, err := s.Client.ListContainers(context.Background(), s.Config.TagKey)
+	if err != nil {
+		s.Logger.Error("Failed to list Docker containers", "domain", "docker", "error", err)
+		return err
+	}
+
+	servers := []server.Server{}
+	for _, c := range containers {
+		// Filter by label key
+		group, ok := c.Labels[s.Config.TagKey]
+		if !ok {
+			continue
+		}
+
+		svr := server.Server{
+			UniqueID:    c.ID,
+			Name:        getContainerName(c),
+			State:       mapState(c.State),
+			ServerGroup: group,
+			TimeAdded:   time.Now().Unix(),
+			Metadata:    getMetadata(c),
+		}
+
+		servers = append(servers, svr)
+	}
+
+	s.Logger.Debug("Scraped and found number of matching containers", "domain", "docker", "count", len(servers))
+	s.ServerService.UpdateServers(shared.CloudProviderDocker, servers)
+
+	return nil
+}
+
+// Start required containers
+func (s *Service) Start() error {
+	s.Logger.Debug("Starting requested containers", "domain", "docker")
+
+	// Get start container IDs
+	containerIDs, err := s.ServerService.GetPending(shared.CloudProviderDocker, "off", "on")
+	if err != nil {
+		s.Logger.Error("Failed to get container IDs pending on", "domain", "docker", "error", err)
+		return err
+	}
+
+	// Nothing to do
+	if len(containerIDs) == 0 {
+		s.Logger.Debug("No containers to start", "domain", "docker")
+		return nil
+	}
+
+	// Loop and turn each on
+	for _, id := range containerIDs {
+		s.Logger.Debug("Starting container", "id", id, "domain", "docker")
+
+		if err := s.Client.StartContainer(context.Background(), id); err != nil {
+			s.Logger.Error("Failed to start container", "id", id, "domain", "docker", "error", err)
+			s.ServerService.RecordFailure(id, "start", err)
+			continue
+		}
+
+		s.ServerService.ClearFailure(id)
+		s.Logger.Info("Container started", "id", id, "domain", "docker")
+	}
+
+	return nil
+}
+
+// Stop no longer required containers
+func (s *Service) Stop() error {
+	s.Logger.Debug("Stopping requested containers", "domain", "docker")
+
+	containerIDs, err := s.ServerService.GetPending(shared.CloudProviderDocker, "on", "off")
+	if err != nil {
+		s.Logger.Error("Failed to get container IDs pending off", "domain", "docker", "error", err)
+		return err
+	}
+
+	if len(containerIDs) == 0 {
+		s.Logger.Debug("No containers to stop", "domain", "docker")
+		return nil
+	}
+
+	for _, id := range containerIDs {
+		s.Logger.Debug("Stopping container", "id", id, "domain", "docker")
+
+		if err := s.Client.StopContainer(context.Background(), id); err != nil {
+			s.Logger.Error("Failed to stop container", "id", id, "domain", "docker", "error", err)
+			s.ServerService.RecordFailure(id, "stop", err)
+			continue
+		}
+
+		s.ServerService.ClearFailure(id)
+		s.Logger.Info("Container stopped", "id", id, "domain", "docker")
+	}
+
+	return nil
+}
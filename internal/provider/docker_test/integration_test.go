@@ -0,0 +1,171 @@
+package docker_test
+
+import (
+	"encoding/json"
+	"ez2boot/internal/provider/docker"
+	"ez2boot/internal/testutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Fake Docker Engine serving a fixed container list over HTTP and recording start/stop calls
+type fakeEngine struct {
+	Containers []docker.Container
+	FailStart  map[string]bool // Container IDs which fail to start
+	mu         sync.Mutex
+	Filters    []string
+	Started    []string
+	Stopped    []string
+}
+
+func (f *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/containers/json":
+		f.Filters = append(f.Filters, r.URL.Query().Get("filters"))
+		json.NewEncoder(w).Encode(f.Containers)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/start"):
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/containers/"), "/start")
+		f.Started = append(f.Started, id)
+		if f.FailStart[id] {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"message": "port is already allocated"})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/stop"):
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/containers/"), "/stop")
+		f.Stopped = append(f.Stopped, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newDockerService(t *testing.T, env *testutil.TestEnv, engine *fakeEngine) *docker.Service {
+	t.Helper()
+
+	env.Cfg.TagKey = "ez2boot"
+
+	srv := httptest.NewServer(engine)
+	t.Cleanup(srv.Close)
+
+	client, err := docker.NewEngineClient("tcp://" + strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatalf("failed to create engine client: %v", err)
+	}
+
+	return &docker.Service{
+		Repo:          docker.NewRepository(env.Base),
+		Config:        env.Cfg,
+		ServerService: env.ServerService,
+		Client:        client,
+		Logger:        env.Logger,
+	}
+}
+
+func TestDockerScrape_Success(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	engine := &fakeEngine{
+		Containers: []docker.Container{
+			{ID: "aaa111", Names: []string{"/qa-app"}, State: "running", Labels: map[string]string{"ez2boot": "qa"}},
+			{ID: "bbb222", Names: []string{"/qa-db"}, State: "exited", Labels: map[string]string{"ez2boot": "qa"}},
+			{ID: "ccc333", Names: []string{"/dev-app"}, State: "restarting", Labels: map[string]string{"ez2boot": "dev"}},
+		},
+	}
+
+	svc := newDockerService(t, env, engine)
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	// Label filter applied by the engine
+	if len(engine.Filters) != 1 || engine.Filters[0] != `{"label":["ez2boot"]}` {
+		t.Errorf("want label filter on list, got %v", engine.Filters)
+	}
+
+	want := map[string]string{
+		"aaa111": "qa-app/on/qa/docker",
+		"bbb222": "qa-db/off/qa/docker",
+		"ccc333": "dev-app/transitioning/dev/docker",
+	}
+
+	rows, err := env.DB.Query("SELECT unique_id, name, state, server_group, provider FROM servers")
+	if err != nil {
+		t.Fatalf("failed to query servers: %v", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var id, name, state, group, provider string
+		if err := rows.Scan(&id, &name, &state, &group, &provider); err != nil {
+			t.Fatalf("failed to scan server row: %v", err)
+		}
+
+		got := name + "/" + state + "/" + group + "/" + provider
+		if want[id] != got {
+			t.Errorf("server %s mismatch, want: %s, got: %s", id, want[id], got)
+		}
+		count++
+	}
+
+	if count != len(want) {
+		t.Fatalf("want %d servers, got %d", len(want), count)
+	}
+}
+
+func TestDockerStartStop_Success(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	engine := &fakeEngine{FailStart: map[string]bool{"bbb222": true}}
+	svc := newDockerService(t, env, engine)
+
+	testutil.InsertUser(t, env.DB, "user@example.com", nil, true, false, false, true, "local")
+
+	// QA group off and pending on, one container fails to start
+	testutil.InsertServer(t, env.DB, "aaa111", "qa-app", "off", "qa", time.Now().Unix())
+	testutil.InsertServer(t, env.DB, "bbb222", "qa-db", "off", "qa", time.Now().Unix())
+	testutil.SetServerProvider(t, env.DB, "qa", "docker")
+	testutil.InsertServerSession(t, env.DB, 1, "qa", time.Now().Add(1*time.Hour).Unix())
+
+	// DEV group on and pending off
+	testutil.InsertServer(t, env.DB, "ccc333", "dev-app", "on", "dev", time.Now().Unix())
+	testutil.SetServerProvider(t, env.DB, "dev", "docker")
+	if _, err := env.DB.Exec("UPDATE servers SET next_state = $1 WHERE server_group = $2", "off", "dev"); err != nil {
+		t.Fatalf("failed to update server: %v", err)
+	}
+
+	if err := svc.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+
+	if err := svc.Stop(); err != nil {
+		t.Fatalf("stop failed: %v", err)
+	}
+
+	if len(engine.Started) != 2 {
+		t.Errorf("want start calls for both qa containers, got %v", engine.Started)
+	}
+
+	if len(engine.Stopped) != 1 || engine.Stopped[0] != "ccc333" {
+		t.Errorf("want stop call for dev-app, got %v", engine.Stopped)
+	}
+
+	// Failed start recorded with the engine error
+	var lastError *string
+	if err := env.DB.QueryRow("SELECT last_error FROM servers WHERE unique_id = $1", "bbb222").Scan(&lastError); err != nil {
+		t.Fatalf("failed to query server: %v", err)
+	}
+	if lastError == nil || !strings.Contains(*lastError, "port is already allocated") {
+		t.Errorf("want engine error recorded against qa-db, got %v", lastError)
+	}
+}
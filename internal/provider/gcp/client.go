@@ -0,0 +1,63 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+
+	compute "cloud.google.com/go/compute/apiv1"
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/api/iterator"
+	"google.golang.org/protobuf/proto"
+)
+
+// Wraps the Compute Engine REST client to satisfy ComputeClient
+type instancesClient struct {
+	client *compute.InstancesClient
+}
+
+// List instances carrying the label key across all zones of the project
+func (c *instancesClient) ListInstances(ctx context.Context, project string, labelKey string) ([]*computepb.Instance, error) {
+	it := c.client.AggregatedList(ctx, &computepb.AggregatedListInstancesRequest{
+		Project:              project,
+		Filter:               proto.String(getLabelFilter(labelKey)),
+		ReturnPartialSuccess: proto.Bool(true),
+	})
+
+	instances := []*computepb.Instance{}
+	for {
+		pair, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Zones without matching instances return no value
+		if pair.Value == nil {
+			continue
+		}
+
+		instances = append(instances, pair.Value.Instances...)
+	}
+
+	return instances, nil
+}
+
+// Start returns a long running operation which is not waited on, the next scrape reflects the result
+func (c *instancesClient) StartInstance(ctx context.Context, project string, zone string, name string) error {
+	if _, err := c.client.Start(ctx, &computepb.StartInstanceRequest{Project: project, Zone: zone, Instance: name}); err != nil {
+		return fmt.Errorf("start request failed: %w", err)
+	}
+
+	return nil
+}
+
+// Stop returns a long running operation which is not waited on, the next scrape reflects the result
+func (c *instancesClient) StopInstance(ctx context.Context, project string, zone string, name string) error {
+	if _, err := c.client.Stop(ctx, &computepb.StopInstanceRequest{Project: project, Zone: zone, Instance: name}); err != nil {
+		return fmt.Errorf("stop request failed: %w", err)
+	}
+
+	return nil
+}
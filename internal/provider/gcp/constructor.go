@@ -0,0 +1,34 @@
+package gcp
+
+import (
+	"context"
+	"ez2boot/internal/config"
+	"ez2boot/internal/db"
+	"ez2boot/internal/server"
+	"fmt"
+	"log/slog"
+
+	compute "cloud.google.com/go/compute/apiv1"
+)
+
+func NewService(gcpRepo *Repository, cfg *config.Config, serverService *server.Service, logger *slog.Logger) (*Service, error) {
+	// Uses application default credentials
+	client, err := compute.NewInstancesRESTClient(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCP instances client: %w", err)
+	}
+
+	return &Service{
+		Repo:          gcpRepo,
+		Config:        cfg,
+		ServerService: serverService,
+		Client:        &instancesClient{client: client},
+		Logger:        logger,
+	}, nil
+}
+
+func NewRepository(base *db.Repository) *Repository {
+	return &Repository{
+		Base: base,
+	}
+}
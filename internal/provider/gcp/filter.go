@@ -0,0 +1,8 @@
+package gcp
+
+import "fmt"
+
+// Filter for opt-in instances, matches any instance which has the label key regardless of value
+func getLabelFilter(labelKey string) string {
+	return fmt.Sprintf("labels.%s:*", labelKey)
+}
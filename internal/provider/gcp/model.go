@@ -0,0 +1,30 @@
+package gcp
+
+import (
+	"context"
+	"ez2boot/internal/config"
+	"ez2boot/internal/db"
+	"ez2boot/internal/server"
+	"log/slog"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+)
+
+type Repository struct {
+	Base *db.Repository
+}
+
+type Service struct {
+	Repo          *Repository
+	Config        *config.Config
+	ServerService *server.Service
+	Client        ComputeClient
+	Logger        *slog.Logger
+}
+
+// Subset of the Compute Engine instances API used by this provider. Allows a fake client to be used in tests
+type ComputeClient interface {
+	ListInstances(ctx context.Context, project string, labelKey string) ([]*computepb.Instance, error)
+	StartInstance(ctx context.Context, project string, zone string, name string) error
+	StopInstance(ctx context.Context, project string, zone string, name string) error
+}
@@ -0,0 +1,65 @@
+package gcp
+
+import (
+	"ez2boot/internal/server"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+)
+
+// Map provider specific states to generic
+func mapState(state string) server.ServerState {
+	switch state {
+	case "RUNNING":
+		return server.ServerOn
+	case "PROVISIONING", "STAGING", "STOPPING", "SUSPENDING", "REPAIRING":
+		return server.ServerTransitioning
+	default:
+		return server.ServerOff
+	}
+}
+
+// Instance zone is returned as a full URL, only the last segment is needed for API calls
+// https://www.googleapis.com/compute/v1/projects/{project}/zones/{zone}
+func getZoneName(zoneURL string) string {
+	return path.Base(zoneURL)
+}
+
+// Build an ID in the same form as the instance resource path
+// projects/{project}/zones/{zone}/instances/{name}
+func buildInstanceID(project string, zone string, name string) string {
+	return fmt.Sprintf("projects/%s/zones/%s/instances/%s", project, zone, name)
+}
+
+// Extract the values required for start and stop calls from the instance ID
+func parseInstanceID(id string) (project, zone, name string, err error) {
+	parts := strings.Split(id, "/")
+	if len(parts) != 6 || parts[0] != "projects" || parts[2] != "zones" || parts[4] != "instances" {
+		return "", "", "", fmt.Errorf("invalid instance ID: %s", id)
+	}
+	return parts[1], parts[3], parts[5], nil
+}
+
+// Collect the descriptive details of an instance. Machine type is returned as a full URL like the zone
+func getMetadata(inst *computepb.Instance) server.Metadata {
+	m := server.Metadata{
+		InstanceType: path.Base(inst.GetMachineType()),
+		Zone:         getZoneName(inst.GetZone()),
+	}
+
+	if interfaces := inst.GetNetworkInterfaces(); len(interfaces) > 0 {
+		m.PrivateIP = interfaces[0].GetNetworkIP()
+		if configs := interfaces[0].GetAccessConfigs(); len(configs) > 0 {
+			m.PublicIP = configs[0].GetNatIP()
+		}
+	}
+
+	if t, err := time.Parse(time.RFC3339, inst.GetLastStartTimestamp()); err == nil {
+		m.LaunchTime = t.Unix()
+	}
+
+	return m
+}
@@ -0,0 +1,123 @@
+package gcp
+
+import (
+	"context"
+	"ez2boot/internal/server"
+	"ez2boot/internal/shared"
+	"time"
+)
+
+// Scrape GCP to retrieve servers.
+func (s *Service) Scrape() error {
+	s.Logger.Debug("Scraping GCP", "domain", "gcp")
+
+	instances, err := s.Client.ListInstances(context.Background(), s.Config.GCPProjectID, s.Config.TagKey)
+	if err != nil {
+		s.Logger.Error("Failed to list GCP instances", "domain", "gcp", "error", err)
+		return err
+	}
+
+	servers := []server.Server{}
+	for _, inst := range instances {
+		// Filter by label key
+		group, ok := inst.GetLabels()[s.Config.TagKey]
+		if !ok {
+			continue
+		}
+
+		svr := server.Server{
+			UniqueID:    buildInstanceID(s.Config.GCPProjectID, getZoneName(inst.GetZone()), inst.GetName()),
+			Name:        inst.GetName(),
+			State:       mapState(inst.GetStatus()),
+			ServerGroup: group,
+			TimeAdded:   time.Now().Unix(),
+			Metadata:    getMetadata(inst),
+		}
+
+		servers = append(servers, svr)
+	}
+
+	s.Logger.Debug("Scraped and found number of matching instances", "domain", "gcp", "count", len(servers))
+	s.ServerService.UpdateServers(shared.CloudProviderGCP, servers)
+
+	return nil
+}
+
+// Start required GCP servers
+func (s *Service) Start() error {
+	s.Logger.Debug("Starting requested GCP instances", "domain", "gcp")
+
+	// Get start instance IDs
+	instanceIDs, err := s.ServerService.GetPending(shared.CloudProviderGCP, "off", "on")
+	if err != nil {
+		s.Logger.Error("Failed to get instance IDs pending on", "domain", "gcp", "error", err)
+		return err
+	}
+
+	// Nothing to do
+	if len(instanceIDs) == 0 {
+		s.Logger.Debug("No instances to start", "domain", "gcp")
+		return nil
+	}
+
+	// Loop and turn each on
+	for _, id := range instanceIDs {
+		project, zone, name, err := parseInstanceID(id)
+		if err != nil {
+			s.Logger.Error("Failed to parse instance ID", "id", id, "domain", "gcp", "error", err)
+			continue
+		}
+
+		s.Logger.Debug("Starting instance", "name", name, "zone", zone, "domain", "gcp")
+
+		if err := s.Client.StartInstance(context.Background(), project, zone, name); err != nil {
+			s.Logger.Error("Failed to start instance", "name", name, "zone", zone, "domain", "gcp", "error", err)
+			s.ServerService.RecordFailure(id, "start", err)
+			continue
+		}
+
+		s.ServerService.ClearFailure(id)
+
+		s.Logger.Info("Instance start initiated", "name", name, "zone", zone, "domain", "gcp")
+	}
+
+	return nil
+}
+
+// Stop no longer required GCP servers
+func (s *Service) Stop() error {
+	s.Logger.Debug("Stopping requested GCP instances", "domain", "gcp")
+
+	instanceIDs, err := s.ServerService.GetPending(shared.CloudProviderGCP, "on", "off")
+	if err != nil {
+		s.Logger.Error("Failed to get instance IDs pending off", "domain", "gcp", "error", err)
+		return err
+	}
+
+	if len(instanceIDs) == 0 {
+		s.Logger.Debug("No instances to stop", "domain", "gcp")
+		return nil
+	}
+
+	for _, id := range instanceIDs {
+		project, zone, name, err := parseInstanceID(id)
+		if err != nil {
+			s.Logger.Error("Failed to parse instance ID", "id", id, "domain", "gcp", "error", err)
+			continue
+		}
+
+		s.Logger.Debug("Stopping instance", "name", name, "zone", zone, "domain", "gcp")
+
+		if err := s.Client.StopInstance(context.Background(), project, zone, name); err != nil {
+			s.Logger.Error("Failed to stop instance", "name", name, "zone", zone, "domain", "gcp", "error", err)
+			s.ServerService.RecordFailure(id, "stop", err)
+			continue
+		}
+
+		s.ServerService.ClearFailure(id)
+
+		s.Logger.Info("Instance stop initiated", "name", name, "zone", zone, "domain", "gcp")
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package gcp_test
+
+import (
+	"context"
+	"ez2boot/internal/provider/gcp"
+	"ez2boot/internal/testutil"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/protobuf/proto"
+)
+
+// Fake Compute client which serves a fixed instance list and records start/stop calls
+type fakeComputeClient struct {
+	Instances []*computepb.Instance
+	Started   []string
+	Stopped   []string
+}
+
+func (f *fakeComputeClient) ListInstances(ctx context.Context, project string, labelKey string) ([]*computepb.Instance, error) {
+	return f.Instances, nil
+}
+
+func (f *fakeComputeClient) StartInstance(ctx context.Context, project string, zone string, name string) error {
+	f.Started = append(f.Started, project+"/"+zone+"/"+name)
+	return nil
+}
+
+func (f *fakeComputeClient) StopInstance(ctx context.Context, project string, zone string, name string) error {
+	f.Stopped = append(f.Stopped, project+"/"+zone+"/"+name)
+	return nil
+}
+
+func newInstance(name string, zone string, status string, labels map[string]string) *computepb.Instance {
+	return &computepb.Instance{
+		Name:   proto.String(name),
+		Zone:   proto.String("https://www.googleapis.com/compute/v1/projects/test-project/zones/" + zone),
+		Status: proto.String(status),
+		Labels: labels,
+	}
+}
+
+func newGCPService(env *testutil.TestEnv, client gcp.ComputeClient) *gcp.Service {
+	env.Cfg.TagKey = "ez2boot"
+	env.Cfg.GCPProjectID = "test-project"
+
+	return &gcp.Service{
+		Repo:          gcp.NewRepository(env.Base),
+		Config:        env.Cfg,
+		ServerService: env.ServerService,
+		Client:        client,
+		Logger:        env.Logger,
+	}
+}
+
+func TestGCPScrape_Success(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	client := &fakeComputeClient{
+		Instances: []*computepb.Instance{
+			newInstance("qa-app", "australia-southeast1-a", "RUNNING", map[string]string{"ez2boot": "qa"}),
+			newInstance("qa-db", "australia-southeast1-b", "TERMINATED", map[string]string{"ez2boot": "qa"}),
+			newInstance("dev-app", "australia-southeast1-a", "STAGING", map[string]string{"ez2boot": "dev"}),
+			newInstance("untagged", "australia-southeast1-a", "RUNNING", map[string]string{"team": "ops"}),
+		},
+	}
+
+	svc := newGCPService(env, client)
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	want := map[string]struct {
+		name  string
+		state string
+		group string
+	}{
+		"projects/test-project/zones/australia-southeast1-a/instances/qa-app":  {"qa-app", "on", "qa"},
+		"projects/test-project/zones/australia-southeast1-b/instances/qa-db":   {"qa-db", "off", "qa"},
+		"projects/test-project/zones/australia-southeast1-a/instances/dev-app": {"dev-app", "transitioning", "dev"},
+	}
+
+	rows, err := env.DB.Query("SELECT unique_id, name, state, server_group FROM servers")
+	if err != nil {
+		t.Fatalf("failed to query servers: %v", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var id, name, state, group string
+		if err := rows.Scan(&id, &name, &state, &group); err != nil {
+			t.Fatalf("failed to scan server row: %v", err)
+		}
+
+		w, ok := want[id]
+		if !ok {
+			t.Errorf("unexpected server scraped: %s", id)
+			continue
+		}
+
+		if w.name != name || w.state != state || w.group != group {
+			t.Errorf("server %s mismatch, want: %s/%s/%s, got: %s/%s/%s", id, w.name, w.state, w.group, name, state, group)
+		}
+		count++
+	}
+
+	if count != len(want) {
+		t.Fatalf("want %d servers, got %d", len(want), count)
+	}
+}
+
+func TestGCPStartStop_Success(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	client := &fakeComputeClient{}
+	svc := newGCPService(env, client)
+
+	testutil.InsertUser(t, env.DB, "user@example.com", nil, true, false, false, true, "local")
+
+	// QA group off and pending on
+	testutil.InsertServer(t, env.DB, "projects/test-project/zones/australia-southeast1-a/instances/qa-app", "qa-app", "off", "qa", time.Now().Unix())
+	testutil.SetServerProvider(t, env.DB, "qa", "gcp")
+	testutil.InsertServerSession(t, env.DB, 1, "qa", time.Now().Add(1*time.Hour).Unix())
+
+	// DEV group on and pending off
+	testutil.InsertServer(t, env.DB, "projects/test-project/zones/australia-southeast1-b/instances/dev-app", "dev-app", "on", "dev", time.Now().Unix())
+	testutil.SetServerProvider(t, env.DB, "dev", "gcp")
+	if _, err := env.DB.Exec("UPDATE servers SET next_state = $1 WHERE server_group = $2", "off", "dev"); err != nil {
+		t.Fatalf("failed to update server: %v", err)
+	}
+
+	if err := svc.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+
+	if err := svc.Stop(); err != nil {
+		t.Fatalf("stop failed: %v", err)
+	}
+
+	if len(client.Started) != 1 || client.Started[0] != "test-project/australia-southeast1-a/qa-app" {
+		t.Errorf("want start call for qa-app, got %v", client.Started)
+	}
+
+	if len(client.Stopped) != 1 || client.Stopped[0] != "test-project/australia-southeast1-b/dev-app" {
+		t.Errorf("want stop call for dev-app, got %v", client.Stopped)
+	}
+}
@@ -0,0 +1,39 @@
+package kubernetes
+
+import (
+	"ez2boot/internal/config"
+	"ez2boot/internal/db"
+	"ez2boot/internal/server"
+	"fmt"
+	"log/slog"
+
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func NewService(kubernetesRepo *Repository, cfg *config.Config, serverService *server.Service, logger *slog.Logger) (*Service, error) {
+	// Uses KUBECONFIG or ~/.kube/config, falling back to the in-cluster service account
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Kubernetes config: %w", err)
+	}
+
+	client, err := clientset.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	return &Service{
+		Repo:          kubernetesRepo,
+		Config:        cfg,
+		ServerService: serverService,
+		Client:        client,
+		Logger:        logger,
+	}, nil
+}
+
+func NewRepository(base *db.Repository) *Repository {
+	return &Repository{
+		Base: base,
+	}
+}
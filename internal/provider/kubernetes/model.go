@@ -0,0 +1,42 @@
+package kubernetes
+
+import (
+	"ez2boot/internal/config"
+	"ez2boot/internal/db"
+	"ez2boot/internal/server"
+	"log/slog"
+
+	clientset "k8s.io/client-go/kubernetes"
+)
+
+type Repository struct {
+	Base *db.Repository
+}
+
+type Service struct {
+	Repo          *Repository
+	Config        *config.Config
+	ServerService *server.Service
+	Client        clientset.Interface
+	Logger        *slog.Logger
+}
+
+const (
+	kindDeployment  = "deployment"
+	kindStatefulSet = "statefulset"
+)
+
+// Annotation holding the replica count a workload had before it was scaled to zero
+const previousReplicasAnnotation = "ez2boot/previous-replicas"
+
+// Common view of a Deployment or StatefulSet
+type workload struct {
+	Namespace     string
+	Kind          string
+	Name          string
+	Group         string
+	Replicas      int32 // Desired
+	Current       int32 // Pods which exist, may still be terminating after scale down
+	ReadyReplicas int32
+	Image         string // First container image in the pod template
+}
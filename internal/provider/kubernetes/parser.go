@@ -0,0 +1,73 @@
+package kubernetes
+
+import (
+	"errors"
+	"ez2boot/internal/server"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Scaled to zero with no pods left is off, all desired replicas ready is on, anything between is transitioning
+func mapState(w workload) server.ServerState {
+	switch {
+	case w.Replicas == 0 && w.Current == 0:
+		return server.ServerOff
+	case w.Replicas > 0 && w.ReadyReplicas >= w.Replicas:
+		return server.ServerOn
+	default:
+		return server.ServerTransitioning
+	}
+}
+
+func buildWorkloadID(namespace string, kind string, name string) string {
+	return fmt.Sprintf("%s/%s/%s", namespace, kind, name)
+}
+
+// Split a workload ID eg preview-42/deployment/web into namespace, kind and name
+func parseWorkloadID(id string) (string, string, string, error) {
+	parts := strings.Split(id, "/")
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return "", "", "", errors.New("invalid workload ID")
+	}
+
+	if parts[1] != kindDeployment && parts[1] != kindStatefulSet {
+		return "", "", "", fmt.Errorf("unsupported workload kind: %s", parts[1])
+	}
+
+	return parts[0], parts[1], parts[2], nil
+}
+
+// Replica count to restore on start, defaults to one if the workload was never scaled down by ez2boot
+func getPreviousReplicas(annotations map[string]string) int32 {
+	value, ok := annotations[previousReplicasAnnotation]
+	if !ok {
+		return 1
+	}
+
+	replicas, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || replicas < 1 {
+		return 1
+	}
+
+	return int32(replicas)
+}
+
+// Collect the descriptive details of a workload. The image stands in for the instance type and the namespace for the zone
+func getMetadata(w workload) server.Metadata {
+	return server.Metadata{
+		InstanceType: w.Image,
+		Zone:         w.Namespace,
+		Platform:     w.Kind,
+	}
+}
+
+func getImage(spec corev1.PodSpec) string {
+	if len(spec.Containers) == 0 {
+		return ""
+	}
+
+	return spec.Containers[0].Image
+}
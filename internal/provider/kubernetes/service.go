@@ -0,0 +1,257 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"ez2boot/internal/server"
+	"ez2boot/internal/shared"
+	"fmt"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Scrape Kubernetes to retrieve labelled workloads as servers.
+func (s *Service) Scrape() error {
+	s.Logger.Debug("Scraping Kubernetes", "domain", "kubernetes")
+
+	workloads := []workload{}
+	for _, namespace := range s.getNamespaces() {
+		found, err := s.listWorkloads(namespace)
+		if err != nil {
+			s.Logger.Error("Failed to list Kubernetes workloads", "namespace", namespace, "domain", "kubernetes", "error", err)
+			return err
+		}
+
+		workloads = append(workloads, found...)
+	}
+
+	servers := []server.Server{}
+	for _, w := range workloads {
+		svr := server.Server{
+			UniqueID:    buildWorkloadID(w.Namespace, w.Kind, w.Name),
+			Name:        w.Namespace + "/" + w.Name,
+			State:       mapState(w),
+			ServerGroup: w.Group,
+			TimeAdded:   time.Now().Unix(),
+			Metadata:    getMetadata(w),
+		}
+
+		servers = append(servers, svr)
+	}
+
+	s.Logger.Debug("Scraped and found number of matching workloads", "domain", "kubernetes", "count", len(servers))
+	s.ServerService.UpdateServers(shared.CloudProviderKubernetes, servers)
+
+	return nil
+}
+
+// Start required workloads by restoring their previous replica count
+func (s *Service) Start() error {
+	s.Logger.Debug("Starting requested workloads", "domain", "kubernetes")
+
+	ids, err := s.ServerService.GetPending(shared.CloudProviderKubernetes, "off", "on")
+	if err != nil {
+		s.Logger.Error("Failed to get workload IDs pending on", "domain", "kubernetes", "error", err)
+		return err
+	}
+
+	if len(ids) == 0 {
+		s.Logger.Debug("No workloads to start", "domain", "kubernetes")
+		return nil
+	}
+
+	for _, id := range ids {
+		if err := s.scaleUp(id); err != nil {
+			s.Logger.Error("Failed to start workload", "id", id, "domain", "kubernetes", "error", err)
+			s.ServerService.RecordFailure(id, "start", err)
+			continue
+		}
+
+		s.ServerService.ClearFailure(id)
+		s.Logger.Info("Workload scale up initiated", "id", id, "domain", "kubernetes")
+	}
+
+	return nil
+}
+
+// Stop no longer required workloads by scaling them to zero
+func (s *Service) Stop() error {
+	s.Logger.Debug("Stopping requested workloads", "domain", "kubernetes")
+
+	ids, err := s.ServerService.GetPending(shared.CloudProviderKubernetes, "on", "off")
+	if err != nil {
+		s.Logger.Error("Failed to get workload IDs pending off", "domain", "kubernetes", "error", err)
+		return err
+	}
+
+	if len(ids) == 0 {
+		s.Logger.Debug("No workloads to stop", "domain", "kubernetes")
+		return nil
+	}
+
+	for _, id := range ids {
+		if err := s.scaleDown(id); err != nil {
+			s.Logger.Error("Failed to stop workload", "id", id, "domain", "kubernetes", "error", err)
+			s.ServerService.RecordFailure(id, "stop", err)
+			continue
+		}
+
+		s.ServerService.ClearFailure(id)
+		s.Logger.Info("Workload scale down initiated", "id", id, "domain", "kubernetes")
+	}
+
+	return nil
+}
+
+// Configured namespaces, or all namespaces when none are set
+func (s *Service) getNamespaces() []string {
+	if len(s.Config.KubernetesNamespaces) == 0 {
+		return []string{metav1.NamespaceAll}
+	}
+
+	return s.Config.KubernetesNamespaces
+}
+
+// List Deployments and StatefulSets carrying the label key
+func (s *Service) listWorkloads(namespace string) ([]workload, error) {
+	ctx := context.Background()
+	opts := metav1.ListOptions{LabelSelector: s.Config.TagKey}
+
+	workloads := []workload{}
+
+	deployments, err := s.Client.AppsV1().Deployments(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+
+	for _, d := range deployments.Items {
+		workloads = append(workloads, workload{
+			Namespace:     d.Namespace,
+			Kind:          kindDeployment,
+			Name:          d.Name,
+			Group:         d.Labels[s.Config.TagKey],
+			Replicas:      getReplicas(d.Spec.Replicas),
+			Current:       d.Status.Replicas,
+			ReadyReplicas: d.Status.ReadyReplicas,
+			Image:         getImage(d.Spec.Template.Spec),
+		})
+	}
+
+	statefulSets, err := s.Client.AppsV1().StatefulSets(namespace).List(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+
+	for _, ss := range statefulSets.Items {
+		workloads = append(workloads, workload{
+			Namespace:     ss.Namespace,
+			Kind:          kindStatefulSet,
+			Name:          ss.Name,
+			Group:         ss.Labels[s.Config.TagKey],
+			Replicas:      getReplicas(ss.Spec.Replicas),
+			Current:       ss.Status.Replicas,
+			ReadyReplicas: ss.Status.ReadyReplicas,
+			Image:         getImage(ss.Spec.Template.Spec),
+		})
+	}
+
+	return workloads, nil
+}
+
+// Restore the recorded replica count and clear the annotation
+func (s *Service) scaleUp(id string) error {
+	namespace, kind, name, err := parseWorkloadID(id)
+	if err != nil {
+		return err
+	}
+
+	_, annotations, err := s.getWorkload(namespace, kind, name)
+	if err != nil {
+		return err
+	}
+
+	patch := map[string]any{
+		"metadata": map[string]any{"annotations": map[string]any{previousReplicasAnnotation: nil}},
+		"spec":     map[string]any{"replicas": getPreviousReplicas(annotations)},
+	}
+
+	return s.patchWorkload(namespace, kind, name, patch)
+}
+
+// Record the current replica count in an annotation then scale to zero, in one patch
+func (s *Service) scaleDown(id string) error {
+	namespace, kind, name, err := parseWorkloadID(id)
+	if err != nil {
+		return err
+	}
+
+	replicas, _, err := s.getWorkload(namespace, kind, name)
+	if err != nil {
+		return err
+	}
+
+	// Already scaled down, keep whatever count was recorded before
+	if replicas == 0 {
+		return nil
+	}
+
+	patch := map[string]any{
+		"metadata": map[string]any{"annotations": map[string]any{previousReplicasAnnotation: strconv.Itoa(int(replicas))}},
+		"spec":     map[string]any{"replicas": 0},
+	}
+
+	return s.patchWorkload(namespace, kind, name, patch)
+}
+
+func (s *Service) getWorkload(namespace string, kind string, name string) (int32, map[string]string, error) {
+	ctx := context.Background()
+
+	switch kind {
+	case kindDeployment:
+		d, err := s.Client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return 0, nil, err
+		}
+		return getReplicas(d.Spec.Replicas), d.Annotations, nil
+	case kindStatefulSet:
+		ss, err := s.Client.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return 0, nil, err
+		}
+		return getReplicas(ss.Spec.Replicas), ss.Annotations, nil
+	default:
+		return 0, nil, fmt.Errorf("unsupported workload kind: %s", kind)
+	}
+}
+
+func (s *Service) patchWorkload(namespace string, kind string, name string, patch map[string]any) error {
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	switch kind {
+	case kindDeployment:
+		_, err = s.Client.AppsV1().Deployments(namespace).Patch(ctx, name, types.MergePatchType, data, metav1.PatchOptions{})
+	case kindStatefulSet:
+		_, err = s.Client.AppsV1().StatefulSets(namespace).Patch(ctx, name, types.MergePatchType, data, metav1.PatchOptions{})
+	default:
+		err = fmt.Errorf("unsupported workload kind: %s", kind)
+	}
+
+	return err
+}
+
+// Kubernetes defaults unset replicas to one
+func getReplicas(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+
+	return *replicas
+}
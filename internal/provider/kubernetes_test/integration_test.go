@@ -0,0 +1,164 @@
+package kubernetes_test
+
+import (
+	"context"
+	"ez2boot/internal/provider/kubernetes"
+	"ez2boot/internal/testutil"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newDeployment(namespace string, name string, group string, replicas int32, ready int32) *appsv1.Deployment {
+	labels := map[string]string{}
+	if group != "" {
+		labels["ez2boot"] = group
+	}
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     appsv1.DeploymentStatus{Replicas: ready, ReadyReplicas: ready},
+	}
+}
+
+func newStatefulSet(namespace string, name string, group string, replicas int32, current int32) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: map[string]string{"ez2boot": group}},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+		Status:     appsv1.StatefulSetStatus{Replicas: current},
+	}
+}
+
+func newKubernetesService(env *testutil.TestEnv, client *fake.Clientset) *kubernetes.Service {
+	env.Cfg.TagKey = "ez2boot"
+
+	return &kubernetes.Service{
+		Repo:          kubernetes.NewRepository(env.Base),
+		Config:        env.Cfg,
+		ServerService: env.ServerService,
+		Client:        client,
+		Logger:        env.Logger,
+	}
+}
+
+func TestKubernetesScrape_Success(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	client := fake.NewClientset(
+		newDeployment("preview-1", "web", "preview-1", 2, 2),
+		newDeployment("preview-1", "worker", "preview-1", 0, 0),
+		newDeployment("preview-2", "web", "preview-2", 3, 1),
+		newDeployment("preview-2", "unlabelled", "", 1, 1),
+		newStatefulSet("preview-2", "db", "preview-2", 0, 1), // Scaled down, pod still terminating
+	)
+
+	svc := newKubernetesService(env, client)
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	want := map[string]string{
+		"preview-1/deployment/web":    "preview-1/web/on/preview-1",
+		"preview-1/deployment/worker": "preview-1/worker/off/preview-1",
+		"preview-2/deployment/web":    "preview-2/web/transitioning/preview-2",
+		"preview-2/statefulset/db":    "preview-2/db/transitioning/preview-2",
+	}
+
+	rows, err := env.DB.Query("SELECT unique_id, name, state, server_group FROM servers WHERE provider = $1", "kubernetes")
+	if err != nil {
+		t.Fatalf("failed to query servers: %v", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var id, name, state, group string
+		if err := rows.Scan(&id, &name, &state, &group); err != nil {
+			t.Fatalf("failed to scan server row: %v", err)
+		}
+
+		if got := name + "/" + state + "/" + group; want[id] != got {
+			t.Errorf("server %s mismatch, want: %s, got: %s", id, want[id], got)
+		}
+		count++
+	}
+
+	if count != len(want) {
+		t.Fatalf("want %d servers, got %d", len(want), count)
+	}
+}
+
+// Stop scales to zero recording the replica count, start restores it
+func TestKubernetesStopStart_RestoresReplicas(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	client := fake.NewClientset(
+		newDeployment("preview-1", "web", "preview-1", 3, 3),
+		newStatefulSet("preview-1", "db", "preview-1", 2, 2),
+	)
+
+	svc := newKubernetesService(env, client)
+	ctx := context.Background()
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+	testutil.UpdateServerState(t, env.DB, "preview-1", "on")
+
+	if _, err := env.DB.Exec("UPDATE servers SET next_state = $1 WHERE server_group = $2", "off", "preview-1"); err != nil {
+		t.Fatalf("failed to update server: %v", err)
+	}
+
+	if err := svc.Stop(); err != nil {
+		t.Fatalf("stop failed: %v", err)
+	}
+
+	d, err := client.AppsV1().Deployments("preview-1").Get(ctx, "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get deployment: %v", err)
+	}
+	if *d.Spec.Replicas != 0 || d.Annotations["ez2boot/previous-replicas"] != "3" {
+		t.Fatalf("want deployment scaled to 0 with 3 recorded, got %d %v", *d.Spec.Replicas, d.Annotations)
+	}
+
+	ss, err := client.AppsV1().StatefulSets("preview-1").Get(ctx, "db", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get statefulset: %v", err)
+	}
+	if *ss.Spec.Replicas != 0 || ss.Annotations["ez2boot/previous-replicas"] != "2" {
+		t.Fatalf("want statefulset scaled to 0 with 2 recorded, got %d %v", *ss.Spec.Replicas, ss.Annotations)
+	}
+
+	// Now off and requested on
+	testutil.InsertUser(t, env.DB, "user@example.com", nil, true, false, false, true, "local")
+	testutil.UpdateServerState(t, env.DB, "preview-1", "off")
+	testutil.InsertServerSession(t, env.DB, 1, "preview-1", time.Now().Add(1*time.Hour).Unix())
+
+	if err := svc.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+
+	d, err = client.AppsV1().Deployments("preview-1").Get(ctx, "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get deployment: %v", err)
+	}
+	if *d.Spec.Replicas != 3 {
+		t.Errorf("want deployment restored to 3 replicas, got %d", *d.Spec.Replicas)
+	}
+	if _, ok := d.Annotations["ez2boot/previous-replicas"]; ok {
+		t.Errorf("want annotation removed after restore, got %v", d.Annotations)
+	}
+
+	ss, err = client.AppsV1().StatefulSets("preview-1").Get(ctx, "db", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get statefulset: %v", err)
+	}
+	if *ss.Spec.Replicas != 2 {
+		t.Errorf("want statefulset restored to 2 replicas, got %d", *ss.Spec.Replicas)
+	}
+}
@@ -0,0 +1,105 @@
+package proxmox
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Proxmox VE API client authenticating with an API token
+type apiClient struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func NewAPIClient(baseURL string, tokenID string, tokenSecret string, skipTLSVerify bool) (APIClient, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid Proxmox URL %q", baseURL)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if skipTLSVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true} // Self signed certificates are the default on Proxmox
+	}
+
+	return &apiClient{
+		baseURL: strings.TrimSuffix(u.String(), "/") + "/api2/json",
+		token:   fmt.Sprintf("PVEAPIToken=%s=%s", tokenID, tokenSecret),
+		http:    &http.Client{Transport: transport, Timeout: 30 * time.Second},
+	}, nil
+}
+
+// List every guest across all cluster nodes
+func (c *apiClient) ListVMs(ctx context.Context) ([]Resource, error) {
+	var resources []Resource
+	if err := c.do(ctx, http.MethodGet, "/cluster/resources?type=vm", nil, &resources); err != nil {
+		return nil, err
+	}
+
+	return resources, nil
+}
+
+// Start returns a task ID which is not waited on, the next scrape reflects the result
+func (c *apiClient) StartVM(ctx context.Context, node string, vmType string, vmID int) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/nodes/%s/%s/%d/status/start", url.PathEscape(node), vmType, vmID), nil, nil)
+}
+
+// Graceful shutdown, forced off if the guest does not respond in time
+func (c *apiClient) ShutdownVM(ctx context.Context, node string, vmType string, vmID int) error {
+	form := url.Values{}
+	form.Set("forceStop", "1")
+
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/nodes/%s/%s/%d/status/shutdown", url.PathEscape(node), vmType, vmID), form, nil)
+}
+
+// Send a request and unwrap the data envelope into out
+func (c *apiClient) do(ctx context.Context, method string, path string, form url.Values, out any) error {
+	var body *strings.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	} else {
+		body = strings.NewReader("")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", c.token)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Proxmox puts the reason in the status line
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("%s %s returned %s", method, strings.SplitN(path, "?", 2)[0], resp.Status)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	envelope := struct {
+		Data any `json:"data"`
+	}{Data: out}
+
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
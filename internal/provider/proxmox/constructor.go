@@ -0,0 +1,30 @@
+package proxmox
+
+import (
+	"ez2boot/internal/config"
+	"ez2boot/internal/db"
+	"ez2boot/internal/server"
+	"fmt"
+	"log/slog"
+)
+
+func NewService(proxmoxRepo *Repository, cfg *config.Config, serverService *server.Service, logger *slog.Logger) (*Service, error) {
+	client, err := NewAPIClient(cfg.ProxmoxURL, cfg.ProxmoxTokenID, cfg.ProxmoxTokenSecret, cfg.ProxmoxSkipTLSVerify)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Proxmox client: %w", err)
+	}
+
+	return &Service{
+		Repo:          proxmoxRepo,
+		Config:        cfg,
+		ServerService: serverService,
+		Client:        client,
+		Logger:        logger,
+	}, nil
+}
+
+func NewRepository(base *db.Repository) *Repository {
+	return &Repository{
+		Base: base,
+	}
+}
@@ -0,0 +1,41 @@
+package proxmox
+
+import (
+	"context"
+	"ez2boot/internal/config"
+	"ez2boot/internal/db"
+	"ez2boot/internal/server"
+	"log/slog"
+)
+
+type Repository struct {
+	Base *db.Repository
+}
+
+type Service struct {
+	Repo          *Repository
+	Config        *config.Config
+	ServerService *server.Service
+	Client        APIClient
+	Logger        *slog.Logger
+}
+
+// Subset of the Proxmox VE REST API used by this provider
+type APIClient interface {
+	ListVMs(ctx context.Context) ([]Resource, error)
+	StartVM(ctx context.Context, node string, vmType string, vmID int) error
+	ShutdownVM(ctx context.Context, node string, vmType string, vmID int) error
+}
+
+// Guest as returned by the cluster resources endpoint, covers both qemu VMs and lxc containers
+type Resource struct {
+	ID       string `json:"id"` // eg qemu/100, unique across the cluster
+	VMID     int    `json:"vmid"`
+	Name     string `json:"name"`
+	Node     string `json:"node"`
+	Type     string `json:"type"`   // qemu or lxc
+	Status   string `json:"status"` // running, stopped
+	Tags     string `json:"tags"`   // Semicolon separated
+	Lock     string `json:"lock"`   // Set while an operation such as backup or migrate holds the guest
+	Template int    `json:"template"`
+}
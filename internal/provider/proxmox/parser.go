@@ -0,0 +1,61 @@
+package proxmox
+
+import (
+	"errors"
+	"ez2boot/internal/server"
+	"strconv"
+	"strings"
+)
+
+// Collect the descriptive details of a guest. The cluster listing carries no addresses, the node stands in for the zone
+func getMetadata(r Resource) server.Metadata {
+	return server.Metadata{
+		Zone:     r.Node,
+		Platform: r.Type,
+	}
+}
+
+// Map guest states to generic. A lock means an operation is in progress on the guest
+func mapState(r Resource) server.ServerState {
+	if r.Lock != "" {
+		return server.ServerTransitioning
+	}
+
+	switch r.Status {
+	case "running":
+		return server.ServerOn
+	case "stopped":
+		return server.ServerOff
+	default:
+		return server.ServerTransitioning
+	}
+}
+
+// Proxmox tags have no values, so the server group is carried in the tag itself as <tagKey>-<group> eg ez2boot-qa
+func getGroup(tags string, tagKey string) (string, bool) {
+	prefix := strings.ToLower(tagKey) + "-"
+
+	for tag := range strings.SplitSeq(tags, ";") {
+		tag = strings.TrimSpace(tag)
+		if group, ok := strings.CutPrefix(tag, prefix); ok && group != "" {
+			return group, true
+		}
+	}
+
+	return "", false
+}
+
+// Split a resource ID eg qemu/100 into its type and VM ID
+func parseResourceID(id string) (string, int, error) {
+	vmType, vmIDStr, ok := strings.Cut(id, "/")
+	if !ok || (vmType != "qemu" && vmType != "lxc") {
+		return "", 0, errors.New("invalid Proxmox resource ID")
+	}
+
+	vmID, err := strconv.Atoi(vmIDStr)
+	if err != nil {
+		return "", 0, errors.New("invalid Proxmox VM ID")
+	}
+
+	return vmType, vmID, nil
+}
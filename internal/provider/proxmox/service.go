@@ -0,0 +1,173 @@
+package proxmox
+
+import (
+	"context"
+	"ez2boot/internal/server"
+	"ez2boot/internal/shared"
+	"fmt"
+	"time"
+)
+
+// Scrape Proxmox to retrieve servers.
+func (s *Service) Scrape() error {
+	s.Logger.Debug("Scraping Proxmox", "domain", "proxmox")
+
+	resources, err := s.Client.ListVMs(context.Background())
+	if err != nil {
+		s.Logger.Error("Failed to list Proxmox guests", "domain", "proxmox", "error", err)
+		return err
+	}
+
+	servers := []server.Server{}
+	for _, r := range resources {
+		// Templates cannot be started
+		if r.Template == 1 {
+			continue
+		}
+
+		// Filter by tag
+		group, ok := getGroup(r.Tags, s.Config.TagKey)
+		if !ok {
+			continue
+		}
+
+		name := r.Name
+		if name == "" {
+			name = r.ID
+		}
+
+		svr := server.Server{
+			UniqueID:    r.ID,
+			Name:        name,
+			State:       mapState(r),
+			ServerGroup: group,
+			TimeAdded:   time.Now().Unix(),
+			Metadata:    getMetadata(r),
+		}
+
+		servers = append(servers, svr)
+	}
+
+	s.Logger.Debug("Scraped and found number of matching guests", "domain", "proxmox", "count", len(servers))
+	s.ServerService.UpdateServers(shared.CloudProviderProxmox, servers)
+
+	return nil
+}
+
+// Start required Proxmox servers
+func (s *Service) Start() error {
+	s.Logger.Debug("Starting requested Proxmox guests", "domain", "proxmox")
+
+	// Get start resource IDs
+	ids, err := s.ServerService.GetPending(shared.CloudProviderProxmox, "off", "on")
+	if err != nil {
+		s.Logger.Error("Failed to get guest IDs pending on", "domain", "proxmox", "error", err)
+		return err
+	}
+
+	// Nothing to do
+	if len(ids) == 0 {
+		s.Logger.Debug("No guests to start", "domain", "proxmox")
+		return nil
+	}
+
+	nodes, err := s.getNodes()
+	if err != nil {
+		s.Logger.Error("Failed to find nodes for guests", "domain", "proxmox", "error", err)
+		return err
+	}
+
+	// Loop and turn each on
+	for _, id := range ids {
+		vmType, vmID, err := parseResourceID(id)
+		if err != nil {
+			s.Logger.Error("Failed to parse guest ID", "id", id, "domain", "proxmox", "error", err)
+			continue
+		}
+
+		node, ok := nodes[id]
+		if !ok {
+			s.Logger.Error("Failed to find node for guest", "id", id, "domain", "proxmox")
+			s.ServerService.RecordFailure(id, "start", fmt.Errorf("guest %s not found on any node", id))
+			continue
+		}
+
+		s.Logger.Debug("Starting guest", "id", id, "node", node, "domain", "proxmox")
+
+		if err := s.Client.StartVM(context.Background(), node, vmType, vmID); err != nil {
+			s.Logger.Error("Failed to start guest", "id", id, "node", node, "domain", "proxmox", "error", err)
+			s.ServerService.RecordFailure(id, "start", err)
+			continue
+		}
+
+		s.ServerService.ClearFailure(id)
+		s.Logger.Info("Guest start initiated", "id", id, "node", node, "domain", "proxmox")
+	}
+
+	return nil
+}
+
+// Stop no longer required Proxmox servers
+func (s *Service) Stop() error {
+	s.Logger.Debug("Stopping requested Proxmox guests", "domain", "proxmox")
+
+	ids, err := s.ServerService.GetPending(shared.CloudProviderProxmox, "on", "off")
+	if err != nil {
+		s.Logger.Error("Failed to get guest IDs pending off", "domain", "proxmox", "error", err)
+		return err
+	}
+
+	if len(ids) == 0 {
+		s.Logger.Debug("No guests to stop", "domain", "proxmox")
+		return nil
+	}
+
+	nodes, err := s.getNodes()
+	if err != nil {
+		s.Logger.Error("Failed to find nodes for guests", "domain", "proxmox", "error", err)
+		return err
+	}
+
+	for _, id := range ids {
+		vmType, vmID, err := parseResourceID(id)
+		if err != nil {
+			s.Logger.Error("Failed to parse guest ID", "id", id, "domain", "proxmox", "error", err)
+			continue
+		}
+
+		node, ok := nodes[id]
+		if !ok {
+			s.Logger.Error("Failed to find node for guest", "id", id, "domain", "proxmox")
+			s.ServerService.RecordFailure(id, "stop", fmt.Errorf("guest %s not found on any node", id))
+			continue
+		}
+
+		s.Logger.Debug("Stopping guest", "id", id, "node", node, "domain", "proxmox")
+
+		if err := s.Client.ShutdownVM(context.Background(), node, vmType, vmID); err != nil {
+			s.Logger.Error("Failed to stop guest", "id", id, "node", node, "domain", "proxmox", "error", err)
+			s.ServerService.RecordFailure(id, "stop", err)
+			continue
+		}
+
+		s.ServerService.ClearFailure(id)
+		s.Logger.Info("Guest stop initiated", "id", id, "node", node, "domain", "proxmox")
+	}
+
+	return nil
+}
+
+// Guests can migrate between nodes, so look up the current node for each guest before acting
+func (s *Service) getNodes() (map[string]string, error) {
+	resources, err := s.Client.ListVMs(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]string, len(resources))
+	for _, r := range resources {
+		nodes[r.ID] = r.Node
+	}
+
+	return nodes, nil
+}
@@ -0,0 +1,168 @@
+package proxmox_test
+
+import (
+	"encoding/json"
+	"ez2boot/internal/provider/proxmox"
+	"ez2boot/internal/testutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testToken = "PVEAPIToken=ez2boot@pve!ez2boot=secret"
+
+// Fake Proxmox API serving cluster resources and recording start/shutdown calls
+type fakeCluster struct {
+	Resources []proxmox.Resource
+	mu        sync.Mutex
+	Calls     []string
+}
+
+func (f *fakeCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != testToken {
+		http.Error(w, "authentication failure", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodGet && r.URL.Path == "/api2/json/cluster/resources" {
+		json.NewEncoder(w).Encode(map[string]any{"data": f.Resources})
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		r.ParseForm()
+		call := r.URL.Path
+		if r.PostForm.Get("forceStop") == "1" {
+			call += "?forceStop"
+		}
+		f.Calls = append(f.Calls, call)
+		json.NewEncoder(w).Encode(map[string]any{"data": "UPID:pve1:0001:task"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNotImplemented)
+}
+
+func newProxmoxService(t *testing.T, env *testutil.TestEnv, cluster *fakeCluster) *proxmox.Service {
+	t.Helper()
+
+	env.Cfg.TagKey = "ez2boot"
+
+	srv := httptest.NewServer(cluster)
+	t.Cleanup(srv.Close)
+
+	client, err := proxmox.NewAPIClient(srv.URL, "ez2boot@pve!ez2boot", "secret", false)
+	if err != nil {
+		t.Fatalf("failed to create API client: %v", err)
+	}
+
+	return &proxmox.Service{
+		Repo:          proxmox.NewRepository(env.Base),
+		Config:        env.Cfg,
+		ServerService: env.ServerService,
+		Client:        client,
+		Logger:        env.Logger,
+	}
+}
+
+func TestProxmoxScrape_Success(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	cluster := &fakeCluster{
+		Resources: []proxmox.Resource{
+			{ID: "qemu/100", VMID: 100, Name: "qa-app", Node: "pve1", Type: "qemu", Status: "running", Tags: "linux;ez2boot-qa"},
+			{ID: "lxc/101", VMID: 101, Name: "qa-cache", Node: "pve2", Type: "lxc", Status: "stopped", Tags: "ez2boot-qa"},
+			{ID: "qemu/102", VMID: 102, Name: "dev-app", Node: "pve1", Type: "qemu", Status: "stopped", Tags: "ez2boot-dev", Lock: "backup"},
+			{ID: "qemu/103", VMID: 103, Name: "untagged", Node: "pve1", Type: "qemu", Status: "running", Tags: "linux"},
+			{ID: "qemu/9000", VMID: 9000, Name: "template", Node: "pve1", Type: "qemu", Status: "stopped", Tags: "ez2boot-qa", Template: 1},
+		},
+	}
+
+	svc := newProxmoxService(t, env, cluster)
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	want := map[string]string{
+		"qemu/100": "qa-app/on/qa",
+		"lxc/101":  "qa-cache/off/qa",
+		"qemu/102": "dev-app/transitioning/dev",
+	}
+
+	rows, err := env.DB.Query("SELECT unique_id, name, state, server_group FROM servers WHERE provider = $1", "proxmox")
+	if err != nil {
+		t.Fatalf("failed to query servers: %v", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var id, name, state, group string
+		if err := rows.Scan(&id, &name, &state, &group); err != nil {
+			t.Fatalf("failed to scan server row: %v", err)
+		}
+
+		if got := name + "/" + state + "/" + group; want[id] != got {
+			t.Errorf("server %s mismatch, want: %s, got: %s", id, want[id], got)
+		}
+		count++
+	}
+
+	if count != len(want) {
+		t.Fatalf("want %d servers, got %d", len(want), count)
+	}
+}
+
+func TestProxmoxStartStop_Success(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	// Guest migrated to pve2 since it was recorded
+	cluster := &fakeCluster{
+		Resources: []proxmox.Resource{
+			{ID: "lxc/101", VMID: 101, Name: "qa-cache", Node: "pve2", Type: "lxc", Status: "stopped", Tags: "ez2boot-qa"},
+			{ID: "qemu/102", VMID: 102, Name: "dev-app", Node: "pve1", Type: "qemu", Status: "running", Tags: "ez2boot-dev"},
+		},
+	}
+
+	svc := newProxmoxService(t, env, cluster)
+
+	testutil.InsertUser(t, env.DB, "user@example.com", nil, true, false, false, true, "local")
+
+	testutil.InsertServer(t, env.DB, "lxc/101", "qa-cache", "off", "qa", time.Now().Unix())
+	testutil.SetServerProvider(t, env.DB, "qa", "proxmox")
+	testutil.InsertServerSession(t, env.DB, 1, "qa", time.Now().Add(1*time.Hour).Unix())
+
+	testutil.InsertServer(t, env.DB, "qemu/102", "dev-app", "on", "dev", time.Now().Unix())
+	testutil.SetServerProvider(t, env.DB, "dev", "proxmox")
+	if _, err := env.DB.Exec("UPDATE servers SET next_state = $1 WHERE server_group = $2", "off", "dev"); err != nil {
+		t.Fatalf("failed to update server: %v", err)
+	}
+
+	if err := svc.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+
+	if err := svc.Stop(); err != nil {
+		t.Fatalf("stop failed: %v", err)
+	}
+
+	want := []string{
+		"/api2/json/nodes/pve2/lxc/101/status/start",
+		"/api2/json/nodes/pve1/qemu/102/status/shutdown?forceStop",
+	}
+
+	if len(cluster.Calls) != len(want) {
+		t.Fatalf("want calls %v, got %v", want, cluster.Calls)
+	}
+	for i := range want {
+		if cluster.Calls[i] != want[i] {
+			t.Errorf("call %d mismatch, want: %s, got: %s", i, want[i], cluster.Calls[i])
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package simulated
+
+import (
+	"ez2boot/internal/config"
+	"ez2boot/internal/db"
+	"ez2boot/internal/server"
+	"log/slog"
+)
+
+func NewService(simulatedRepo *Repository, cfg *config.Config, serverService *server.Service, logger *slog.Logger) (*Service, error) {
+	fleet, err := LoadFleet(cfg.SimulatedFleetFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		Repo:          simulatedRepo,
+		Config:        cfg,
+		ServerService: serverService,
+		Fleet:         fleet,
+		Logger:        logger,
+	}, nil
+}
+
+func NewRepository(base *db.Repository) *Repository {
+	return &Repository{
+		Base: base,
+	}
+}
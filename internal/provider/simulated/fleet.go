@@ -0,0 +1,178 @@
+package simulated
+
+import (
+	"errors"
+	"ez2boot/internal/server"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInstanceNotFound = errors.New("simulated instance not found")
+
+// Used when no fleet file is provided, enough to demo the UI
+var defaultFleet = FleetDefinition{
+	StartDelay: 20 * time.Second,
+	StopDelay:  10 * time.Second,
+	Servers: []InstanceDefinition{
+		{ID: "sim-qa-app", Name: "qa-app", Group: "qa"},
+		{ID: "sim-qa-db", Name: "qa-db", Group: "qa"},
+		{ID: "sim-dev-app", Name: "dev-app", Group: "dev"},
+		{ID: "sim-uat-app", Name: "uat-app", Group: "uat", State: "on"},
+	},
+}
+
+// Load a fleet definition from a YAML or JSON file. An empty path loads the default demo fleet
+func LoadFleet(path string) (*Fleet, error) {
+	if path == "" {
+		return NewFleet(defaultFleet)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fleet file: %w", err)
+	}
+
+	// JSON is valid YAML, one decoder handles both
+	var def FleetDefinition
+	if err := yaml.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("failed to parse fleet file: %w", err)
+	}
+
+	return NewFleet(def)
+}
+
+func NewFleet(def FleetDefinition) (*Fleet, error) {
+	f := &Fleet{
+		StartDelay: def.StartDelay,
+		StopDelay:  def.StopDelay,
+		Now:        time.Now,
+		instances:  make(map[string]*Instance),
+	}
+
+	for _, d := range def.Servers {
+		if d.ID == "" || d.Group == "" {
+			return nil, errors.New("simulated servers require an id and group")
+		}
+
+		if _, ok := f.instances[d.ID]; ok {
+			return nil, fmt.Errorf("duplicate simulated server id: %s", d.ID)
+		}
+
+		state := server.ServerOff
+		switch d.State {
+		case "", "off":
+		case "on":
+			state = server.ServerOn
+		default:
+			return nil, fmt.Errorf("invalid initial state for simulated server %s: %s", d.ID, d.State)
+		}
+
+		name := d.Name
+		if name == "" {
+			name = d.ID
+		}
+
+		f.instances[d.ID] = &Instance{
+			ID:        d.ID,
+			Name:      name,
+			Group:     d.Group,
+			State:     state,
+			FailStart: d.FailStart,
+			FailStop:  d.FailStop,
+			Metadata: server.Metadata{
+				InstanceType: d.InstanceType,
+				PrivateIP:    d.PrivateIP,
+				PublicIP:     d.PublicIP,
+				Zone:         d.Zone,
+				Platform:     d.Platform,
+			},
+		}
+		f.order = append(f.order, d.ID)
+	}
+
+	return f, nil
+}
+
+// Current view of every instance, completing any transitions whose delay has elapsed
+func (f *Fleet) List() []Instance {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.Now()
+
+	instances := make([]Instance, 0, len(f.order))
+	for _, id := range f.order {
+		inst := f.instances[id]
+		if inst.State == server.ServerTransitioning && !now.Before(inst.readyAt) {
+			inst.State = inst.target
+			if inst.State == server.ServerOn {
+				inst.Metadata.LaunchTime = inst.readyAt.Unix()
+			}
+		}
+
+		instances = append(instances, *inst)
+	}
+
+	return instances
+}
+
+func (f *Fleet) Start(id string) error {
+	return f.transition(id, server.ServerOn)
+}
+
+func (f *Fleet) Stop(id string) error {
+	return f.transition(id, server.ServerOff)
+}
+
+// Inject or clear failures for an instance at runtime
+func (f *Fleet) SetFailures(id string, failStart bool, failStop bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	inst, ok := f.instances[id]
+	if !ok {
+		return ErrInstanceNotFound
+	}
+
+	inst.FailStart = failStart
+	inst.FailStop = failStop
+
+	return nil
+}
+
+// Begin moving an instance towards the target state. A zero delay completes on the next list
+func (f *Fleet) transition(id string, target server.ServerState) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	inst, ok := f.instances[id]
+	if !ok {
+		return ErrInstanceNotFound
+	}
+
+	delay := f.StartDelay
+	if target == server.ServerOn {
+		if inst.FailStart {
+			return fmt.Errorf("simulated start failure for %s", inst.Name)
+		}
+	} else {
+		delay = f.StopDelay
+		if inst.FailStop {
+			return fmt.Errorf("simulated stop failure for %s", inst.Name)
+		}
+	}
+
+	// Already there or on the way
+	if inst.State == target || (inst.State == server.ServerTransitioning && inst.target == target) {
+		return nil
+	}
+
+	inst.State = server.ServerTransitioning
+	inst.target = target
+	inst.readyAt = f.Now().Add(delay)
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package simulated
+
+import (
+	"ez2boot/internal/config"
+	"ez2boot/internal/db"
+	"ez2boot/internal/server"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type Repository struct {
+	Base *db.Repository
+}
+
+type Service struct {
+	Repo          *Repository
+	Config        *config.Config
+	ServerService *server.Service
+	Fleet         *Fleet
+	Logger        *slog.Logger
+}
+
+// Fleet file layout, YAML or JSON
+type FleetDefinition struct {
+	StartDelay time.Duration        `yaml:"start_delay"` // Time spent transitioning when starting
+	StopDelay  time.Duration        `yaml:"stop_delay"`  // Time spent transitioning when stopping
+	Servers    []InstanceDefinition `yaml:"servers"`
+}
+
+type InstanceDefinition struct {
+	ID        string `yaml:"id"`
+	Name      string `yaml:"name"`
+	Group     string `yaml:"group"`
+	State     string `yaml:"state"`      // Initial state, on or off. Defaults to off
+	FailStart bool   `yaml:"fail_start"` // Start requests are rejected
+	FailStop  bool   `yaml:"fail_stop"`  // Stop requests are rejected
+
+	// Optional details shown alongside the server
+	InstanceType string `yaml:"instance_type"`
+	PrivateIP    string `yaml:"private_ip"`
+	PublicIP     string `yaml:"public_ip"`
+	Zone         string `yaml:"zone"`
+	Platform     string `yaml:"platform"`
+}
+
+// In-memory fleet of simulated instances
+type Fleet struct {
+	StartDelay time.Duration
+	StopDelay  time.Duration
+	Now        func() time.Time // Replaceable clock for deterministic tests
+	instances  map[string]*Instance
+	order      []string // Definition order, keeps scrapes stable
+	mu         sync.Mutex
+}
+
+type Instance struct {
+	ID        string
+	Name      string
+	Group     string
+	State     server.ServerState
+	FailStart bool
+	FailStop  bool
+	Metadata  server.Metadata
+	target    server.ServerState // State reached once the transition completes
+	readyAt   time.Time
+}
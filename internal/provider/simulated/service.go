@@ -0,0 +1,89 @@
+package simulated
+
+import (
+	"ez2boot/internal/server"
+	"ez2boot/internal/shared"
+	"time"
+)
+
+// Scrape the simulated fleet to retrieve servers.
+func (s *Service) Scrape() error {
+	s.Logger.Debug("Scraping simulated fleet", "domain", "simulated")
+
+	servers := []server.Server{}
+	for _, inst := range s.Fleet.List() {
+		svr := server.Server{
+			UniqueID:    inst.ID,
+			Name:        inst.Name,
+			State:       inst.State,
+			ServerGroup: inst.Group,
+			TimeAdded:   time.Now().Unix(),
+			Metadata:    inst.Metadata,
+		}
+
+		servers = append(servers, svr)
+	}
+
+	s.Logger.Debug("Scraped and found number of simulated servers", "domain", "simulated", "count", len(servers))
+	s.ServerService.UpdateServers(shared.CloudProviderSimulated, servers)
+
+	return nil
+}
+
+// Start required simulated servers
+func (s *Service) Start() error {
+	s.Logger.Debug("Starting requested simulated servers", "domain", "simulated")
+
+	ids, err := s.ServerService.GetPending(shared.CloudProviderSimulated, "off", "on")
+	if err != nil {
+		s.Logger.Error("Failed to get simulated server IDs pending on", "domain", "simulated", "error", err)
+		return err
+	}
+
+	if len(ids) == 0 {
+		s.Logger.Debug("No simulated servers to start", "domain", "simulated")
+		return nil
+	}
+
+	for _, id := range ids {
+		if err := s.Fleet.Start(id); err != nil {
+			s.Logger.Error("Failed to start simulated server", "id", id, "domain", "simulated", "error", err)
+			s.ServerService.RecordFailure(id, "start", err)
+			continue
+		}
+
+		s.ServerService.ClearFailure(id)
+		s.Logger.Info("Simulated server start initiated", "id", id, "domain", "simulated")
+	}
+
+	return nil
+}
+
+// Stop no longer required simulated servers
+func (s *Service) Stop() error {
+	s.Logger.Debug("Stopping requested simulated servers", "domain", "simulated")
+
+	ids, err := s.ServerService.GetPending(shared.CloudProviderSimulated, "on", "off")
+	if err != nil {
+		s.Logger.Error("Failed to get simulated server IDs pending off", "domain", "simulated", "error", err)
+		return err
+	}
+
+	if len(ids) == 0 {
+		s.Logger.Debug("No simulated servers to stop", "domain", "simulated")
+		return nil
+	}
+
+	for _, id := range ids {
+		if err := s.Fleet.Stop(id); err != nil {
+			s.Logger.Error("Failed to stop simulated server", "id", id, "domain", "simulated", "error", err)
+			s.ServerService.RecordFailure(id, "stop", err)
+			continue
+		}
+
+		s.ServerService.ClearFailure(id)
+		s.Logger.Info("Simulated server stop initiated", "id", id, "domain", "simulated")
+	}
+
+	return nil
+}
@@ -0,0 +1,204 @@
+package simulated_test
+
+import (
+	"context"
+	"ez2boot/internal/provider/simulated"
+	"ez2boot/internal/testutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Controllable clock so transitions complete exactly when a test says so
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newSimulatedService(t *testing.T, env *testutil.TestEnv, def simulated.FleetDefinition) (*simulated.Service, *fakeClock) {
+	t.Helper()
+
+	fleet, err := simulated.NewFleet(def)
+	if err != nil {
+		t.Fatalf("failed to create fleet: %v", err)
+	}
+
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	fleet.Now = clock.Now
+
+	return &simulated.Service{
+		Repo:          simulated.NewRepository(env.Base),
+		Config:        env.Cfg,
+		ServerService: env.ServerService,
+		Fleet:         fleet,
+		Logger:        env.Logger,
+	}, clock
+}
+
+func getState(t *testing.T, env *testutil.TestEnv, id string) string {
+	t.Helper()
+
+	var state string
+	if err := env.DB.QueryRow("SELECT state FROM servers WHERE unique_id = $1", id).Scan(&state); err != nil {
+		t.Fatalf("failed to query server %s: %v", id, err)
+	}
+
+	return state
+}
+
+func TestLoadFleet_YAMLAndJSON(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"fleet.yaml": "start_delay: 30s\nstop_delay: 5s\nservers:\n  - id: a\n    name: qa-app\n    group: qa\n  - id: b\n    group: qa\n    state: on\n    fail_stop: true\n",
+		"fleet.json": `{"start_delay": "30s", "stop_delay": "5s", "servers": [{"id": "a", "name": "qa-app", "group": "qa"}, {"id": "b", "group": "qa", "state": "on", "fail_stop": true}]}`,
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+
+		fleet, err := simulated.LoadFleet(path)
+		if err != nil {
+			t.Fatalf("failed to load %s: %v", name, err)
+		}
+
+		if fleet.StartDelay != 30*time.Second || fleet.StopDelay != 5*time.Second {
+			t.Errorf("%s: want delays 30s/5s, got %s/%s", name, fleet.StartDelay, fleet.StopDelay)
+		}
+
+		instances := fleet.List()
+		if len(instances) != 2 {
+			t.Fatalf("%s: want 2 instances, got %d", name, len(instances))
+		}
+
+		if instances[0].Name != "qa-app" || instances[0].State != "off" {
+			t.Errorf("%s: want qa-app off, got %s %s", name, instances[0].Name, instances[0].State)
+		}
+
+		// Name defaults to ID
+		if instances[1].Name != "b" || instances[1].State != "on" || !instances[1].FailStop {
+			t.Errorf("%s: want b on with stop failure, got %+v", name, instances[1])
+		}
+	}
+}
+
+func TestSimulatedSessionLifecycle_Success(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	svc, clock := newSimulatedService(t, env, simulated.FleetDefinition{
+		StartDelay: 30 * time.Second,
+		StopDelay:  10 * time.Second,
+		Servers: []simulated.InstanceDefinition{
+			{ID: "sim-qa-app", Name: "qa-app", Group: "qa"},
+			{ID: "sim-qa-db", Name: "qa-db", Group: "qa"},
+		},
+	})
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	testutil.InsertUser(t, env.DB, "user@example.com", nil, true, false, false, true, "local")
+	testutil.InsertServerSession(t, env.DB, 1, "qa", time.Now().Add(1*time.Hour).Unix())
+
+	// Start accepted, servers transitioning until the delay passes
+	if err := svc.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+
+	clock.Advance(29 * time.Second)
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	if state := getState(t, env, "sim-qa-app"); state != "transitioning" {
+		t.Fatalf("want transitioning before delay elapses, got %s", state)
+	}
+
+	env.Worker.SessionService.ProcessServerSessions(context.Background())
+
+	var onNotified int
+	if err := env.DB.QueryRow("SELECT on_notified FROM server_sessions WHERE server_group = $1", "qa").Scan(&onNotified); err != nil {
+		t.Fatalf("failed to query session: %v", err)
+	}
+	if onNotified != 0 {
+		t.Fatal("want session not ready while servers transitioning")
+	}
+
+	// Delay elapsed, servers on and session becomes ready
+	clock.Advance(1 * time.Second)
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	if state := getState(t, env, "sim-qa-db"); state != "on" {
+		t.Fatalf("want on after delay, got %s", state)
+	}
+
+	env.Worker.SessionService.ProcessServerSessions(context.Background())
+
+	if err := env.DB.QueryRow("SELECT on_notified FROM server_sessions WHERE server_group = $1", "qa").Scan(&onNotified); err != nil {
+		t.Fatalf("failed to query session: %v", err)
+	}
+	if onNotified != 1 {
+		t.Fatal("want session ready once servers on")
+	}
+}
+
+func TestSimulatedStartFailure_Recorded(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	svc, clock := newSimulatedService(t, env, simulated.FleetDefinition{
+		Servers: []simulated.InstanceDefinition{
+			{ID: "sim-qa-app", Name: "qa-app", Group: "qa", FailStart: true},
+		},
+	})
+
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	testutil.InsertUser(t, env.DB, "user@example.com", nil, true, false, false, true, "local")
+	testutil.InsertServerSession(t, env.DB, 1, "qa", time.Now().Add(1*time.Hour).Unix())
+
+	if err := svc.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+
+	var lastError *string
+	if err := env.DB.QueryRow("SELECT last_error FROM servers WHERE unique_id = $1", "sim-qa-app").Scan(&lastError); err != nil {
+		t.Fatalf("failed to query server: %v", err)
+	}
+	if lastError == nil {
+		t.Fatal("want injected failure recorded")
+	}
+
+	// Clear the failure, the next attempt goes through
+	if err := svc.Fleet.SetFailures("sim-qa-app", false, false); err != nil {
+		t.Fatalf("failed to clear failures: %v", err)
+	}
+
+	if err := svc.Start(); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+
+	clock.Advance(time.Second)
+	if err := svc.Scrape(); err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+
+	if state := getState(t, env, "sim-qa-app"); state != "on" {
+		t.Errorf("want on after failure cleared, got %s", state)
+	}
+}
@@ -0,0 +1,27 @@
+package readiness
+
+import (
+	"ez2boot/internal/config"
+	"ez2boot/internal/db"
+	"log/slog"
+)
+
+func NewService(readinessRepo *Repository, cfg *config.Config, logger *slog.Logger) (*Service, error) {
+	probes, err := LoadProbes(cfg.ReadinessProbeFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		Repo:   readinessRepo,
+		Config: cfg,
+		Probes: probes,
+		Logger: logger,
+	}, nil
+}
+
+func NewRepository(base *db.Repository) *Repository {
+	return &Repository{
+		Base: base,
+	}
+}
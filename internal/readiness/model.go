@@ -0,0 +1,48 @@
+package readiness
+
+import (
+	"ez2boot/internal/config"
+	"ez2boot/internal/db"
+	"log/slog"
+	"time"
+)
+
+type Repository struct {
+	Base *db.Repository
+}
+
+type Service struct {
+	Repo   *Repository
+	Config *config.Config
+	Probes map[string][]Probe // Keyed by server group
+	Logger *slog.Logger
+}
+
+const (
+	ProbeTCP     = "tcp"
+	ProbeHTTP    = "http"
+	ProbeCommand = "command"
+)
+
+// Probes file, read once at startup
+type ProbeFile struct {
+	Groups map[string][]Probe `yaml:"groups"`
+}
+
+// Check which must pass before a server group is reported ready
+type Probe struct {
+	Type           string        `yaml:"type"`            // tcp, http or command
+	Address        string        `yaml:"address"`         // host:port for tcp
+	URL            string        `yaml:"url"`             // URL for http
+	ExpectedStatus int           `yaml:"expected_status"` // Defaults to 200
+	Command        []string      `yaml:"command"`         // Program and arguments, run without a shell. Passes on exit code 0
+	Timeout        time.Duration `yaml:"timeout"`         // Defaults to 5s
+}
+
+// Latest outcome of a probe, shown in the session summary
+type ProbeResult struct {
+	Probe     string `json:"probe"`
+	Success   bool   `json:"success"`
+	Message   string `json:"message"`
+	CheckedAt int64  `json:"checked_at"`
+}
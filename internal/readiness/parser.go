@@ -0,0 +1,89 @@
+package readiness
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultTimeout = 5 * time.Second
+
+// Load probes from a YAML or JSON file. An empty path means no probes, groups are ready once their servers are on
+func LoadProbes(path string) (map[string][]Probe, error) {
+	if path == "" {
+		return map[string][]Probe{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read readiness probe file: %w", err)
+	}
+
+	// JSON is valid YAML, one decoder handles both
+	var file ProbeFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse readiness probe file: %w", err)
+	}
+
+	for group, probes := range file.Groups {
+		for i := range probes {
+			if err := validateProbe(&probes[i]); err != nil {
+				return nil, fmt.Errorf("invalid readiness probe for server group %s: %w", group, err)
+			}
+		}
+	}
+
+	if file.Groups == nil {
+		file.Groups = map[string][]Probe{}
+	}
+
+	return file.Groups, nil
+}
+
+// Check required fields and fill defaults
+func validateProbe(p *Probe) error {
+	if p.Timeout <= 0 {
+		p.Timeout = defaultTimeout
+	}
+
+	switch p.Type {
+	case ProbeTCP:
+		if _, _, err := net.SplitHostPort(p.Address); err != nil {
+			return fmt.Errorf("tcp probe address must be host:port: %w", err)
+		}
+	case ProbeHTTP:
+		u, err := url.Parse(p.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("http probe requires an http or https url: %s", p.URL)
+		}
+
+		if p.ExpectedStatus == 0 {
+			p.ExpectedStatus = 200
+		}
+	case ProbeCommand:
+		if len(p.Command) == 0 {
+			return fmt.Errorf("command probe requires a command")
+		}
+	default:
+		return fmt.Errorf("unknown probe type: %s", p.Type)
+	}
+
+	return nil
+}
+
+// Short label for a probe, used as its name in results
+func describeProbe(p Probe) string {
+	switch p.Type {
+	case ProbeTCP:
+		return "tcp " + p.Address
+	case ProbeHTTP:
+		return fmt.Sprintf("http %s %d", p.URL, p.ExpectedStatus)
+	default:
+		return "command " + strings.Join(p.Command, " ")
+	}
+}
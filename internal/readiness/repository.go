@@ -0,0 +1,22 @@
+package readiness
+
+// Replace the recorded results for a server group
+func (r *Repository) saveResults(serverGroup string, results []ProbeResult) error {
+	tx, err := r.Base.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM probe_results WHERE server_group = $1", serverGroup); err != nil {
+		return err
+	}
+
+	for i, result := range results {
+		if _, err := tx.Exec("INSERT INTO probe_results (server_group, position, probe, success, message, checked_at) VALUES ($1, $2, $3, $4, $5, $6)", serverGroup, i, result.Probe, result.Success, result.Message, result.CheckedAt); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,137 @@
+package readiness
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Max length of command output kept in a result
+const maxMessageLength = 200
+
+// Longest a round of probes may hold up the session worker, probes still running then fail
+const checkDeadline = 10 * time.Second
+
+// Run the probes for each server group and record the results, returning whether each group is ready. Every probe runs in
+// parallel under one deadline, so slow probes do not add up. Groups without probes are always ready
+func (s *Service) CheckGroups(serverGroups []string) map[string]bool {
+	ctx, cancel := context.WithTimeout(context.Background(), checkDeadline)
+	defer cancel()
+
+	ready := make(map[string]bool, len(serverGroups))
+	results := make(map[string][]ProbeResult)
+
+	var wg sync.WaitGroup
+	for _, serverGroup := range serverGroups {
+		ready[serverGroup] = true
+
+		probes := s.Probes[serverGroup]
+		if _, ok := results[serverGroup]; ok || len(probes) == 0 {
+			continue
+		}
+
+		groupResults := make([]ProbeResult, len(probes))
+		results[serverGroup] = groupResults
+
+		for i, p := range probes {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				groupResults[i] = checkProbe(ctx, p)
+			}()
+		}
+	}
+	wg.Wait()
+
+	// Saved here rather than from each probe, keeping writes on the worker goroutine
+	for serverGroup, groupResults := range results {
+		for _, result := range groupResults {
+			if !result.Success {
+				ready[serverGroup] = false
+			}
+		}
+
+		if err := s.Repo.saveResults(serverGroup, groupResults); err != nil {
+			s.Logger.Error("Failed to save readiness probe results", "domain", "readiness", "server_group", serverGroup, "error", err)
+		}
+
+		if !ready[serverGroup] {
+			s.Logger.Debug("Server group not ready, readiness probes failing", "domain", "readiness", "server_group", serverGroup)
+		}
+	}
+
+	return ready
+}
+
+func checkProbe(ctx context.Context, p Probe) ProbeResult {
+	result := ProbeResult{
+		Probe:     describeProbe(p),
+		Success:   true,
+		Message:   "ok",
+		CheckedAt: time.Now().Unix(),
+	}
+
+	if err := runProbe(ctx, p); err != nil {
+		result.Success = false
+		result.Message = err.Error()
+	}
+
+	return result
+}
+
+func runProbe(ctx context.Context, p Probe) error {
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	switch p.Type {
+	case ProbeTCP:
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", p.Address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+
+	case ProbeHTTP:
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != p.ExpectedStatus {
+			return fmt.Errorf("want status %d, got %d", p.ExpectedStatus, resp.StatusCode)
+		}
+		return nil
+
+	case ProbeCommand:
+		output, err := exec.CommandContext(ctx, p.Command[0], p.Command[1:]...).CombinedOutput()
+		if err != nil {
+			if msg := strings.TrimSpace(string(output)); msg != "" {
+				return fmt.Errorf("%w: %s", err, truncate(msg))
+			}
+			return err
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown probe type: %s", p.Type)
+	}
+}
+
+func truncate(s string) string {
+	if len(s) > maxMessageLength {
+		return s[:maxMessageLength] + "..."
+	}
+	return s
+}
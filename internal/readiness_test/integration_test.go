@@ -0,0 +1,151 @@
+package readiness_test
+
+import (
+	"context"
+	"ez2boot/internal/readiness"
+	"ez2boot/internal/testutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadProbes_Success(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "probes.yaml")
+	data := `groups:
+  qa:
+    - type: tcp
+      address: 10.0.1.10:5432
+    - type: http
+      url: http://10.0.1.11:8080/health
+    - type: command
+      command: ["/usr/local/bin/check-qa", "--quick"]
+      timeout: 30s
+`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("failed to write probe file: %v", err)
+	}
+
+	probes, err := readiness.LoadProbes(path)
+	if err != nil {
+		t.Fatalf("failed to load probes: %v", err)
+	}
+
+	qa := probes["qa"]
+	if len(qa) != 3 {
+		t.Fatalf("want 3 probes for qa, got %d", len(qa))
+	}
+
+	// Defaults filled
+	if qa[0].Timeout != 5*time.Second || qa[1].ExpectedStatus != 200 || qa[2].Timeout != 30*time.Second {
+		t.Errorf("defaults mismatch, got %+v", qa)
+	}
+}
+
+func TestLoadProbes_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "probes.yaml")
+	if err := os.WriteFile(path, []byte("groups:\n  qa:\n    - type: ping\n"), 0600); err != nil {
+		t.Fatalf("failed to write probe file: %v", err)
+	}
+
+	if _, err := readiness.LoadProbes(path); err == nil {
+		t.Fatal("want error for unknown probe type")
+	}
+}
+
+// Ready notification waits for the probes, results are shown in the summary meanwhile
+func TestReadyNotification_WaitsForProbes(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	var healthy atomic.Bool
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer app.Close()
+
+	env.Worker.SessionService.ReadinessService.Probes = map[string][]readiness.Probe{
+		"QA": {{Type: readiness.ProbeHTTP, URL: app.URL, ExpectedStatus: http.StatusOK, Timeout: time.Second}},
+	}
+
+	testutil.InsertUser(t, env.DB, "user@example.com", nil, true, false, false, true, "local")
+	testutil.InsertServer(t, env.DB, "i-3728hvi2vn2u4vn2", "test01", "on", "QA", time.Now().Unix())
+	testutil.InsertServerSession(t, env.DB, 1, "QA", time.Now().Add(1*time.Hour).Unix())
+
+	assertResult := func(wantNotified int, wantSuccess bool) {
+		t.Helper()
+
+		env.Worker.SessionService.ProcessServerSessions(context.Background())
+
+		var onNotified int
+		if err := env.DB.QueryRow("SELECT on_notified FROM server_sessions WHERE server_group = $1", "QA").Scan(&onNotified); err != nil {
+			t.Fatalf("failed to query session: %v", err)
+		}
+		if onNotified != wantNotified {
+			t.Errorf("want on_notified %d, got %d", wantNotified, onNotified)
+		}
+
+		var success bool
+		if err := env.DB.QueryRow("SELECT success FROM probe_results WHERE server_group = $1", "QA").Scan(&success); err != nil {
+			t.Fatalf("failed to query probe results: %v", err)
+		}
+		if success != wantSuccess {
+			t.Errorf("want probe success %v, got %v", wantSuccess, success)
+		}
+	}
+
+	// Servers on but application still starting
+	assertResult(0, false)
+
+	healthy.Store(true)
+	assertResult(1, true)
+}
+
+// Slow probes across groups run together, so a round takes as long as the slowest probe rather than all of them
+func TestCheckGroups_Parallel(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(1 * time.Second)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer slow.Close()
+
+	probe := readiness.Probe{Type: readiness.ProbeHTTP, URL: slow.URL, ExpectedStatus: http.StatusOK, Timeout: 3 * time.Second}
+	failing := readiness.Probe{Type: readiness.ProbeHTTP, URL: slow.URL, ExpectedStatus: http.StatusOK, Timeout: 100 * time.Millisecond}
+
+	svc := env.Worker.SessionService.ReadinessService
+	svc.Probes = map[string][]readiness.Probe{
+		"QA":  {probe, probe},
+		"UAT": {probe, failing},
+	}
+
+	started := time.Now()
+	ready := svc.CheckGroups([]string{"QA", "UAT", "DEV"})
+	elapsed := time.Since(started)
+
+	if elapsed > 2*time.Second {
+		t.Errorf("want probes run in parallel, took %v", elapsed)
+	}
+
+	want := map[string]bool{"QA": true, "UAT": false, "DEV": true}
+	for group, wantReady := range want {
+		if ready[group] != wantReady {
+			t.Errorf("%s: want ready %v, got %v", group, wantReady, ready[group])
+		}
+	}
+
+	var count int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM probe_results").Scan(&count); err != nil {
+		t.Fatalf("failed to query probe results: %v", err)
+	}
+	if count != 4 {
+		t.Errorf("want 4 probe results saved, got %d", count)
+	}
+}
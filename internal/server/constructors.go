@@ -1,7 +1,10 @@
 package server
 
 import (
+	"ez2boot/internal/audit"
+	"ez2boot/internal/config"
 	"ez2boot/internal/db"
+	"ez2boot/internal/notification"
 	"log/slog"
 )
 
@@ -12,10 +15,13 @@ func NewHandler(serverService *Service, logger *slog.Logger) *Handler {
 	}
 }
 
-func NewService(serverRepo *Repository, logger *slog.Logger) *Service {
+func NewService(serverRepo *Repository, cfg *config.Config, notificationService *notification.Service, auditService *audit.Service, logger *slog.Logger) *Service {
 	return &Service{
-		Repo:   serverRepo,
-		Logger: logger,
+		Repo:                serverRepo,
+		Config:              cfg,
+		NotificationService: notificationService,
+		Audit:               auditService,
+		Logger:              logger,
 	}
 }
 
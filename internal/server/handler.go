@@ -0,0 +1,47 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"ez2boot/internal/ctxutil"
+	"ez2boot/internal/shared"
+	"net/http"
+
+	"github.com/gorilla/schema"
+)
+
+func (h *Handler) GetServerHistory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		_, email := ctxutil.GetActor(ctx)
+
+		var req ServerHistoryRequest
+
+		decoder := schema.NewDecoder()
+		decoder.IgnoreUnknownKeys(true)
+
+		// Parse query values into struct
+		if err := decoder.Decode(&req, r.URL.Query()); err != nil {
+			h.Logger.Error("Failed to decode request", "user", email, "domain", "server", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Invalid query parameters"})
+			return
+		}
+
+		history, err := h.Service.GetServerHistory(req)
+		if err != nil {
+			if errors.Is(err, shared.ErrInvalidTimeRange) {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "From must be before to"})
+				return
+			}
+
+			h.Logger.Error("Failed to get server history", "user", email, "domain", "server", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Failed to get server history"})
+			return
+		}
+
+		json.NewEncoder(w).Encode(shared.ApiResponse[ServerHistoryResponse]{Success: true, Data: history})
+	}
+}
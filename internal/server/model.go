@@ -1,7 +1,10 @@
 package server
 
 import (
+	"ez2boot/internal/audit"
+	"ez2boot/internal/config"
 	"ez2boot/internal/db"
+	"ez2boot/internal/notification"
 	"log/slog"
 )
 
@@ -10,8 +13,11 @@ type Repository struct {
 }
 
 type Service struct {
-	Repo   *Repository
-	Logger *slog.Logger
+	Repo                *Repository
+	Config              *config.Config
+	NotificationService *notification.Service
+	Audit               *audit.Service
+	Logger              *slog.Logger
 }
 
 type Handler struct {
@@ -28,9 +34,125 @@ const (
 )
 
 type Server struct {
-	UniqueID    string      `json:"unique_id"`
-	Name        string      `json:"name"`
-	State       ServerState `json:"state"`
-	ServerGroup string      `json:"server_group"`
-	TimeAdded   int64       `json:"time_added"`
+	UniqueID    string            `json:"unique_id"`
+	Name        string            `json:"name"`
+	State       ServerState       `json:"state"`
+	ServerGroup string            `json:"server_group"`
+	TimeAdded   int64             `json:"time_added"`
+	Provider    string            `json:"provider"`
+	Target      string            `json:"target"` // Provider location which reported the server eg AWS account and region, empty for single target providers
+	Metadata    Metadata          `json:"metadata"`
+	BootOrder   int64             `json:"boot_order"` // Servers in a group start in ascending and stop in descending order
+	GroupTags   map[string]string `json:"group_tags"` // Companion tags describing the server group, keyed by name without the tag key prefix
+}
+
+// Descriptive details captured by a scrape. Providers fill what they can, unknown values are left empty
+type Metadata struct {
+	InstanceType string `json:"instance_type"` // Instance type, VM size or machine type
+	PrivateIP    string `json:"private_ip"`
+	PublicIP     string `json:"public_ip"`
+	Zone         string `json:"zone"`        // Availability zone, region or node. On Azure the zone number within the region, eg 1
+	Region       string `json:"region"`      // Region when the provider reports it apart from the zone, eg Azure location uksouth
+	Platform     string `json:"platform"`    // Operating system or engine
+	LaunchTime   int64  `json:"launch_time"` // Unix time the server last started, 0 if unknown
+}
+
+// Companion tags read alongside the tag key, eg ez2boot:description
+const (
+	GroupTagDescription = "description"
+	GroupTagOwner       = "owner"
+	GroupTagOrder       = "order"
+	GroupTagLinks       = "links"
+
+	// Session policy
+	GroupTagMaxDuration     = "max-duration"
+	GroupTagDefaultDuration = "default-duration"
+	GroupTagAllowedUsers    = "allowed-users"
+	GroupTagDriftPolicy     = "drift-policy"
+)
+
+// Companion tags recognised by the scrape, others are ignored
+var GroupTagNames = []string{GroupTagDescription, GroupTagOwner, GroupTagOrder, GroupTagLinks, GroupTagMaxDuration, GroupTagDefaultDuration, GroupTagAllowedUsers, GroupTagDriftPolicy}
+
+// Companion tag read per server rather than per group
+const TagBootOrder = "boot-order"
+
+// Entry in the allowed users tag which admits every admin
+const GroupPolicyAdmins = "admins"
+
+// Roles accepted in the allowed users tag alongside emails
+var GroupPolicyRoles = []string{GroupPolicyAdmins}
+
+// Details about a server group, maintained through companion tags on its servers
+type GroupMetadata struct {
+	Description string      `json:"description"`
+	Owner       string      `json:"owner"`
+	Order       *int64      `json:"order"` // Dashboard position, can be null
+	Links       []string    `json:"links"`
+	Policy      GroupPolicy `json:"policy"`
+}
+
+// Session limits for a server group. Empty values fall back to the global settings
+type GroupPolicy struct {
+	MaxDuration     string   `json:"max_duration"`     // Capped by MAX_SERVER_SESSION_DURATION
+	DefaultDuration string   `json:"default_duration"` // Used when a session request has no duration
+	AllowedUsers    []string `json:"allowed_users"`    // Emails, or roles in GroupPolicyRoles. Empty allows everyone
+	DriftPolicy     string   `json:"drift_policy"`     // Handling when running without a session, overrides DRIFT_POLICY
+}
+
+// A server a provider failed to start or stop
+type ServerFailure struct {
+	Name        string
+	ServerGroup string
+	Provider    string
+	OwnerID     *int64 // User holding a session for the server group, nil if none
+	Attempts    int    // Failed attempts since the last success or state request
+}
+
+// State recorded when a server is removed from a scrape, it no longer runs or costs anything as far as ez2boot knows
+const ServerRemoved ServerState = "removed"
+
+// A state change observed by a scrape
+type StateChange struct {
+	UniqueID    string
+	Name        string
+	ServerGroup string
+	Provider    string
+	State       ServerState
+	TimeStamp   int64
+}
+
+type ServerHistoryRequest struct {
+	From        int64  `schema:"from"`         // Unix time, defaults to 30 days before to
+	To          int64  `schema:"to"`           // Unix time, defaults to now
+	ServerGroup string `schema:"server_group"` // Optional, all groups if empty
+}
+
+type ServerHistoryResponse struct {
+	From   int64          `json:"from"`
+	To     int64          `json:"to"`
+	Groups []GroupHistory `json:"groups"`
+}
+
+type GroupHistory struct {
+	ServerGroup    string          `json:"server_group"`
+	RunningSeconds int64           `json:"running_seconds"`
+	RunningHours   float64         `json:"running_hours"`
+	Servers        []ServerHistory `json:"servers"`
+}
+
+type ServerHistory struct {
+	UniqueID       string        `json:"unique_id"`
+	Name           string        `json:"name"`
+	Provider       string        `json:"provider"`
+	RunningSeconds int64         `json:"running_seconds"`
+	RunningHours   float64       `json:"running_hours"`
+	Timeline       []StatePeriod `json:"timeline"`
+}
+
+// Time spent in a state, clipped to the requested range
+type StatePeriod struct {
+	State ServerState `json:"state"`
+	From  int64       `json:"from"`
+	To    int64       `json:"to"`
 }
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"ez2boot/internal/shared"
+	"math"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Build the full key of a companion tag, eg ez2boot:description
+func GetGroupTagKey(tagKey string, name string) string {
+	return tagKey + ":" + name
+}
+
+// Pick recognised companion tags from a provider's tags. Returns nil when there are none
+func GetGroupTags(tagKey string, tags map[string]string) map[string]string {
+	var groupTags map[string]string
+	for _, name := range GroupTagNames {
+		value := strings.TrimSpace(tags[GetGroupTagKey(tagKey, name)])
+		if value == "" {
+			continue
+		}
+
+		if groupTags == nil {
+			groupTags = make(map[string]string)
+		}
+		groupTags[name] = value
+	}
+
+	return groupTags
+}
+
+// Parse the value of the boot order companion tag. Untagged or invalid values are 0, the first tier
+func ParseBootOrder(value string) int64 {
+	order, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return order
+}
+
+// Interpret stored companion tags. Invalid orders and durations are ignored. Links and allowed users are space separated, AWS does not allow commas in tag values
+func NewGroupMetadata(tags map[string]string) GroupMetadata {
+	m := GroupMetadata{
+		Description: tags[GroupTagDescription],
+		Owner:       tags[GroupTagOwner],
+		Links:       []string{},
+	}
+
+	if order, err := strconv.ParseInt(tags[GroupTagOrder], 10, 64); err == nil {
+		m.Order = &order
+	}
+
+	m.Policy = GroupPolicy{
+		MaxDuration:     parsePolicyDuration(tags[GroupTagMaxDuration]),
+		DefaultDuration: parsePolicyDuration(tags[GroupTagDefaultDuration]),
+		DriftPolicy:     parseDriftPolicy(tags[GroupTagDriftPolicy]),
+	}
+
+	m.Policy.AllowedUsers, _ = ParseAllowedUsers(tags[GroupTagAllowedUsers])
+
+	// Links are rendered in the dashboard, only allow web URLs
+	for _, link := range strings.Fields(tags[GroupTagLinks]) {
+		if u, err := url.Parse(link); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			m.Links = append(m.Links, link)
+		}
+	}
+
+	return m
+}
+
+// Split the allowed users tag into emails and known roles, returning unknown roles separately. A tag left with only unknown
+// roles admits admins rather than everyone, so a mistyped role does not open the group
+func ParseAllowedUsers(value string) ([]string, []string) {
+	users := []string{}
+	var unknown []string
+	for _, entry := range strings.Fields(strings.ToLower(value)) {
+		if strings.Contains(entry, "@") || slices.Contains(GroupPolicyRoles, entry) {
+			users = append(users, entry)
+			continue
+		}
+
+		unknown = append(unknown, entry)
+	}
+
+	if len(users) == 0 && len(unknown) > 0 {
+		users = append(users, GroupPolicyAdmins)
+	}
+
+	return users, unknown
+}
+
+// Durations are kept in the same form as session requests, eg 2h
+func parsePolicyDuration(value string) string {
+	if dur, err := time.ParseDuration(value); err != nil || dur <= 0 {
+		return ""
+	}
+
+	return value
+}
+
+// Unknown drift policies are ignored so the global setting applies
+func parseDriftPolicy(value string) string {
+	policy := strings.ToLower(value)
+	if !slices.Contains(shared.DriftPolicies, policy) {
+		return ""
+	}
+
+	return policy
+}
+
+// Turn state changes, ordered by server then time, into periods clipped to the range. A server's group is the one recorded
+// with each change, so a server moved between groups is charged to each for its time there
+func buildHistory(changes []StateChange, from int64, to int64, serverGroup string) []GroupHistory {
+	groups := []GroupHistory{}
+	groupIndex := make(map[string]int)
+	serverIndex := make(map[string]int) // keyed by group and unique ID
+
+	for i, c := range changes {
+		start := max(c.TimeStamp, from)
+		end := to
+		if i+1 < len(changes) && changes[i+1].UniqueID == c.UniqueID {
+			end = min(changes[i+1].TimeStamp, to)
+		}
+
+		// Removed servers are gone, and a change at the start is superseded by a later one at the same time
+		if c.State == ServerRemoved || end <= start {
+			continue
+		}
+
+		// Timelines are clipped by changes in any group, then only the requested group is attributed
+		if serverGroup != "" && c.ServerGroup != serverGroup {
+			continue
+		}
+
+		gi, ok := groupIndex[c.ServerGroup]
+		if !ok {
+			gi = len(groups)
+			groupIndex[c.ServerGroup] = gi
+			groups = append(groups, GroupHistory{ServerGroup: c.ServerGroup, Servers: []ServerHistory{}})
+		}
+		group := &groups[gi]
+
+		key := c.ServerGroup + "/" + c.UniqueID
+		si, ok := serverIndex[key]
+		if !ok {
+			si = len(group.Servers)
+			serverIndex[key] = si
+			group.Servers = append(group.Servers, ServerHistory{UniqueID: c.UniqueID, Provider: c.Provider, Timeline: []StatePeriod{}})
+		}
+		server := &group.Servers[si]
+		server.Name = c.Name // Latest name wins
+
+		server.Timeline = append(server.Timeline, StatePeriod{State: c.State, From: start, To: end})
+		if c.State == ServerOn {
+			server.RunningSeconds += end - start
+			group.RunningSeconds += end - start
+		}
+	}
+
+	for gi := range groups {
+		groups[gi].RunningHours = toHours(groups[gi].RunningSeconds)
+		for si := range groups[gi].Servers {
+			groups[gi].Servers[si].RunningHours = toHours(groups[gi].Servers[si].RunningSeconds)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ServerGroup < groups[j].ServerGroup })
+
+	return groups
+}
+
+// Hours rounded to two decimal places
+func toHours(seconds int64) float64 {
+	return math.Round(float64(seconds)/36) / 100
+}
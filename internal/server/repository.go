@@ -1,48 +1,151 @@
 package server
 
 import (
+	"database/sql"
 	"fmt"
 	"strings"
 )
 
-func (r *Repository) deleteObsolete(ids []any) error {
-	// Successful scrape returned nothing, means remove all
-	if len(ids) == 0 {
-		_, err := r.Base.DB.Exec("DELETE FROM servers")
-		return err
+// Delete servers no longer in the provider's scrape, recording their removal in the history. Servers of failed targets are kept. Runs within the scrape transaction
+func (r *Repository) deleteObsolete(tx *sql.Tx, provider string, ids []any, failedTargets []any, now int64) error {
+	// Successful scrape returned nothing, means remove all for this provider
+	filter := ""
+	args := []any{provider}
+
+	if len(ids) > 0 {
+		// Build string of positional placeholders eg $2, $3, $4 - $1 is the provider
+		filter = fmt.Sprintf(" AND unique_id NOT IN (%s)", getPlaceholders(len(args)+1, len(ids)))
+		args = append(args, ids...)
 	}
 
-	// Build string of positional placeholders eg $1, $2, $3
-	placeholders := make([]string, len(ids))
-	for i := range ids {
-		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	if len(failedTargets) > 0 {
+		filter += fmt.Sprintf(" AND target NOT IN (%s)", getPlaceholders(len(args)+1, len(failedTargets)))
+		args = append(args, failedTargets...)
 	}
 
-	query := fmt.Sprintf("DELETE FROM servers WHERE unique_id NOT IN (%s)", strings.Join(placeholders, ", "))
-	if _, err := r.Base.DB.Exec(query, ids...); err != nil {
+	history := fmt.Sprintf("INSERT INTO server_history (unique_id, name, server_group, provider, state, time_stamp) SELECT unique_id, name, server_group, provider, '%s', %d FROM servers WHERE provider = $1%s", ServerRemoved, now, filter)
+	if _, err := tx.Exec(history, args...); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM servers WHERE provider = $1"+filter, args...); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// Insert new server records, if conflict update the name, server group or state
-func (r *Repository) addOrUpdate(server Server) error {
-	query := `INSERT INTO servers (unique_id, name, state, server_group, time_added) VALUES ($1, $2, $3, $4, $5) 
+// Build a string of count positional placeholders starting at first eg $2, $3, $4
+func getPlaceholders(first int, count int) string {
+	placeholders := make([]string, count)
+	for i := range count {
+		placeholders[i] = fmt.Sprintf("$%d", first+i)
+	}
+
+	return strings.Join(placeholders, ", ")
+}
+
+// Insert new server records, if conflict update the name, server group, state, provider, target, boot order or metadata - runs as a transaction per scrape
+func (r *Repository) addOrUpdate(tx *sql.Tx, server Server, now int64) error {
+	query := `INSERT INTO servers (unique_id, name, state, server_group, time_added, provider, instance_type, private_ip, public_ip, zone, platform, launch_time, boot_order, region, target) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) 
 			ON CONFLICT (unique_id) DO UPDATE 
-			SET name = EXCLUDED.name, state = EXCLUDED.state, server_group = EXCLUDED.server_group
-			WHERE servers.name <> EXCLUDED.name OR servers.state <> EXCLUDED.state OR servers.server_group <> EXCLUDED.server_group`
+			SET name = EXCLUDED.name, state = EXCLUDED.state, server_group = EXCLUDED.server_group, provider = EXCLUDED.provider,
+				instance_type = EXCLUDED.instance_type, private_ip = EXCLUDED.private_ip, public_ip = EXCLUDED.public_ip, zone = EXCLUDED.zone, platform = EXCLUDED.platform, launch_time = EXCLUDED.launch_time, boot_order = EXCLUDED.boot_order, region = EXCLUDED.region, target = EXCLUDED.target
+			WHERE servers.name <> EXCLUDED.name OR servers.state <> EXCLUDED.state OR servers.server_group <> EXCLUDED.server_group OR servers.provider <> EXCLUDED.provider
+				OR servers.instance_type <> EXCLUDED.instance_type OR servers.private_ip <> EXCLUDED.private_ip OR servers.public_ip <> EXCLUDED.public_ip OR servers.zone <> EXCLUDED.zone OR servers.platform <> EXCLUDED.platform OR servers.launch_time <> EXCLUDED.launch_time
+				OR servers.boot_order <> EXCLUDED.boot_order OR servers.region <> EXCLUDED.region OR servers.target <> EXCLUDED.target`
+
+	// New servers, state and group changes go to the history, compared before the update
+	history := `INSERT INTO server_history (unique_id, name, server_group, provider, state, time_stamp) SELECT $1, $2, $3, $4, $5, $6
+				WHERE NOT EXISTS (SELECT 1 FROM servers WHERE unique_id = $1 AND state = $5 AND server_group = $3)`
+	if _, err := tx.Exec(history, server.UniqueID, server.Name, server.ServerGroup, server.Provider, server.State, now); err != nil {
+		return err
+	}
 
-	if _, err := r.Base.DB.Exec(query, server.UniqueID, server.Name, server.State, server.ServerGroup, server.TimeAdded); err != nil {
+	m := server.Metadata
+	if _, err := tx.Exec(query, server.UniqueID, server.Name, server.State, server.ServerGroup, server.TimeAdded, server.Provider, m.InstanceType, m.PrivateIP, m.PublicIP, m.Zone, m.Platform, m.LaunchTime, server.BootOrder, m.Region, server.Target); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// Get server IDs which are pending a state change
-func (r *Repository) getPending(currentState string, nextState string) ([]string, error) {
-	rows, err := r.Base.DB.Query("SELECT unique_id FROM servers WHERE state = $1 AND next_state = $2", currentState, nextState)
+// Replace the companion tags recorded for the provider's server groups, within the scrape transaction
+func (r *Repository) replaceGroupTags(tx *sql.Tx, provider string, groupTags map[string]map[string]string) error {
+	if _, err := tx.Exec("DELETE FROM server_group_tags WHERE provider = $1", provider); err != nil {
+		return err
+	}
+
+	for group, tags := range groupTags {
+		for name, value := range tags {
+			if _, err := tx.Exec("INSERT INTO server_group_tags (server_group, provider, name, value) VALUES ($1, $2, $3, $4)", group, provider, name, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Get the target recorded for each of the provider's servers, keyed by unique ID
+func (r *Repository) getServerTargets(provider string) (map[string]string, error) {
+	rows, err := r.Base.DB.Query("SELECT unique_id, target FROM servers WHERE provider = $1", provider)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	targets := make(map[string]string)
+	for rows.Next() {
+		var id, target string
+		if err := rows.Scan(&id, &target); err != nil {
+			return nil, err
+		}
+
+		targets[id] = target
+	}
+
+	return targets, rows.Err()
+}
+
+// Get the state recorded for each of the provider's servers, keyed by unique ID
+func (r *Repository) getServerStates(provider string) (map[string]ServerState, error) {
+	rows, err := r.Base.DB.Query("SELECT unique_id, state FROM servers WHERE provider = $1", provider)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	states := make(map[string]ServerState)
+	for rows.Next() {
+		var id string
+		var state ServerState
+		if err := rows.Scan(&id, &state); err != nil {
+			return nil, err
+		}
+
+		states[id] = state
+	}
+
+	return states, rows.Err()
+}
+
+// Get server IDs owned by the provider which are pending a state change. A server waits while an earlier
+// tier in its group has not reached the next state, tiers start in ascending and stop in descending boot order.
+// Servers backing off after a failure, or out of attempts, are skipped. A max attempts of 0 means no limit
+func (r *Repository) getPending(provider string, currentState string, nextState string, maxAttempts int, now int64) ([]string, error) {
+	earlier := "p.boot_order < s.boot_order"
+	if nextState == string(ServerOff) {
+		earlier = "p.boot_order > s.boot_order"
+	}
+
+	query := fmt.Sprintf(`SELECT s.unique_id FROM servers AS s WHERE s.provider = $1 AND s.state = $2 AND s.next_state = $3
+			AND ($4 <= 0 OR s.attempts < $4) AND s.next_attempt <= $5
+			AND NOT EXISTS (SELECT 1 FROM servers AS p WHERE p.server_group = s.server_group AND p.next_state = $3 AND p.state <> $3 AND %s)`, earlier)
+
+	rows, err := r.Base.DB.Query(query, provider, currentState, nextState, maxAttempts, now)
 	if err != nil {
 		return nil, err
 	}
@@ -63,3 +166,111 @@ func (r *Repository) getPending(currentState string, nextState string) ([]string
 
 	return serverIDs, nil
 }
+
+// Delete servers and group tags owned by providers no longer configured, recording the removal in the history. Unowned servers are left
+func (r *Repository) deleteUnconfigured(providers []string, now int64) (int64, error) {
+	args := make([]any, len(providers))
+	for i, provider := range providers {
+		args[i] = provider
+	}
+
+	filter := fmt.Sprintf("provider <> '' AND provider NOT IN (%s)", getPlaceholders(1, len(providers)))
+
+	tx, err := r.Base.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	history := fmt.Sprintf("INSERT INTO server_history (unique_id, name, server_group, provider, state, time_stamp) SELECT unique_id, name, server_group, provider, '%s', %d FROM servers WHERE %s", ServerRemoved, now, filter)
+	if _, err := tx.Exec(history, args...); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec("DELETE FROM servers WHERE "+filter, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM server_group_tags WHERE "+filter, args...); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// Set provider on servers which have none recorded
+func (r *Repository) assignUnownedServers(provider string) (int64, error) {
+	result, err := r.Base.DB.Exec("UPDATE servers SET provider = $1 WHERE provider = ''", provider)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// Set the last error on a server, count the attempt and return its details along with any session owner
+func (r *Repository) recordFailure(tx *sql.Tx, uniqueID string, reason string, now int64) (ServerFailure, error) {
+	var f ServerFailure
+	if err := tx.QueryRow("UPDATE servers SET last_error = $1, last_error_time = $2, attempts = attempts + 1 WHERE unique_id = $3 RETURNING name, server_group, provider, attempts", reason, now, uniqueID).Scan(&f.Name, &f.ServerGroup, &f.Provider, &f.Attempts); err != nil {
+		return ServerFailure{}, err
+	}
+
+	var ownerID int64
+	err := tx.QueryRow("SELECT user_id FROM server_sessions WHERE server_group = $1", f.ServerGroup).Scan(&ownerID)
+	switch {
+	case err == sql.ErrNoRows:
+		// No session, nobody to notify
+	case err != nil:
+		return ServerFailure{}, err
+	default:
+		f.OwnerID = &ownerID
+	}
+
+	return f, nil
+}
+
+// Hold off further attempts on a server until the given time
+func (r *Repository) setNextAttempt(tx *sql.Tx, uniqueID string, nextAttempt int64) error {
+	_, err := tx.Exec("UPDATE servers SET next_attempt = $1 WHERE unique_id = $2", nextAttempt, uniqueID)
+	return err
+}
+
+func (r *Repository) clearFailure(uniqueID string) error {
+	_, err := r.Base.DB.Exec("UPDATE servers SET last_error = NULL, last_error_time = NULL, attempts = 0, next_attempt = 0 WHERE unique_id = $1 AND (last_error IS NOT NULL OR attempts > 0)", uniqueID)
+	return err
+}
+
+// Get state changes overlapping a time range, including the last change before it which gives the starting state. Ordered by server then time.
+// Filtering by server group selects servers which have been in the group, with their whole timeline so a move to another group ends their time in it
+func (r *Repository) getStateChanges(from int64, to int64, serverGroup string) ([]StateChange, error) {
+	query := `SELECT h.unique_id, h.name, h.server_group, h.provider, h.state, h.time_stamp
+			FROM server_history AS h
+			WHERE h.time_stamp >= COALESCE((SELECT MAX(p.time_stamp) FROM server_history AS p WHERE p.unique_id = h.unique_id AND p.time_stamp <= $1), 0)
+			AND h.time_stamp < $2 AND ($3 = '' OR h.unique_id IN (SELECT g.unique_id FROM server_history AS g WHERE g.server_group = $3))
+			ORDER BY h.unique_id, h.time_stamp, h.id`
+
+	rows, err := r.Base.DB.Query(query, from, to, serverGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	changes := []StateChange{}
+
+	for rows.Next() {
+		var c StateChange
+		if err := rows.Scan(&c.UniqueID, &c.Name, &c.ServerGroup, &c.Provider, &c.State, &c.TimeStamp); err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, c)
+	}
+
+	return changes, nil
+}
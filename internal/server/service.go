@@ -1,29 +1,275 @@
 package server
 
-// Update servers from cloud provider
-func (s *Service) UpdateServers(servers []Server) {
+import (
+	"ez2boot/internal/audit"
+	"ez2boot/internal/notification"
+	"ez2boot/internal/shared"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Update servers from a cloud provider scrape. Only servers owned by the scraped provider are affected
+func (s *Service) UpdateServers(provider string, servers []Server) {
+	s.UpdateServersPartial(provider, servers, nil)
+}
+
+// Update servers from a scrape where some of the provider's targets failed. Servers last seen on a failed target are left as
+// recorded rather than removed, and group tags are kept until a scrape of every target succeeds
+func (s *Service) UpdateServersPartial(provider string, servers []Server, failedTargets []string) {
 	// Extract UniqueIDs into a slice of interface{}
 	ids := make([]any, len(servers))
 	for i, s := range servers {
 		ids[i] = s.UniqueID
 	}
 
-	// Delete servers from DB not in scrape
-	err := s.Repo.deleteObsolete(ids)
+	// Servers recorded before targets were tracked could belong to a failed target, so they are kept too
+	failed := []any{}
+	for _, target := range failedTargets {
+		failed = append(failed, target)
+	}
+	if len(failed) > 0 {
+		failed = append(failed, "")
+	}
+
+	now := time.Now().Unix()
+
+	// Process update, the whole scrape in one transaction so removals are never committed without the matching adds
+	tx, err := s.Repo.Base.DB.Begin()
 	if err != nil {
-		s.Logger.Error("Failed to delete obsolete servers from DB", "domain", "server", "error", err)
+		s.Logger.Error("Failed to create transaction for scrape", "domain", "server", "provider", provider, "error", err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Delete servers from DB not in scrape
+	if err := s.Repo.deleteObsolete(tx, provider, ids, failed, now); err != nil {
+		s.Logger.Error("Failed to delete obsolete servers from DB", "domain", "server", "provider", provider, "error", err)
+		return
 	}
 
-	// Process update
 	for _, server := range servers {
-		if err := s.Repo.addOrUpdate(server); err != nil {
+		server.Provider = provider
+		if err := s.Repo.addOrUpdate(tx, server, now); err != nil {
 			s.Logger.Error("Failed to add or update server from scrape", "domain", "server", "server", server, "error", err) // Log here to show error and continue
 			continue
 		}
 	}
+
+	// Groups on a failed target would lose their tags
+	if len(failedTargets) == 0 {
+		groupTags := mergeGroupTags(servers)
+		s.validateGroupTags(provider, groupTags)
+
+		if err := s.Repo.replaceGroupTags(tx, provider, groupTags); err != nil {
+			s.Logger.Error("Failed to update server group tags from scrape", "domain", "server", "provider", provider, "error", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		s.Logger.Error("Failed to commit servers from scrape", "domain", "server", "provider", provider, "error", err)
+	}
+}
+
+// Combine companion tags across each group's servers. Only one server needs tagging, the first value seen wins
+func mergeGroupTags(servers []Server) map[string]map[string]string {
+	merged := make(map[string]map[string]string)
+	for _, server := range servers {
+		for name, value := range server.GroupTags {
+			if merged[server.ServerGroup] == nil {
+				merged[server.ServerGroup] = make(map[string]string)
+			}
+
+			if _, ok := merged[server.ServerGroup][name]; !ok {
+				merged[server.ServerGroup][name] = value
+			}
+		}
+	}
+
+	return merged
+}
+
+// Reject unknown roles in allowed users tags so they are reported at scrape time, only valid entries are stored
+func (s *Service) validateGroupTags(provider string, groupTags map[string]map[string]string) {
+	for group, tags := range groupTags {
+		value, ok := tags[GroupTagAllowedUsers]
+		if !ok {
+			continue
+		}
+
+		users, unknown := ParseAllowedUsers(value)
+		if len(unknown) == 0 {
+			continue
+		}
+
+		s.Logger.Warn("Ignoring unknown roles in allowed users tag", "domain", "server", "provider", provider, "group", group, "roles", unknown, "allowed", users)
+		tags[GroupTagAllowedUsers] = strings.Join(users, " ")
+	}
+}
+
+// Get the target recorded for each of the provider's servers by the last scrape which saw them, keyed by unique ID.
+// Lets providers route start and stop before their first scrape after a restart
+func (s *Service) GetServerTargets(provider string) (map[string]string, error) {
+	return s.Repo.getServerTargets(provider)
+}
+
+// Get the state recorded for each of the provider's servers, keyed by unique ID. Lets a provider keep a server it could not read
+// the state of, rather than leaving it out of the scrape and having it removed
+func (s *Service) GetServerStates(provider string) (map[string]ServerState, error) {
+	return s.Repo.getServerStates(provider)
+}
+
+// Get server IDs owned by the provider which are pending a state change and due an attempt
+func (s *Service) GetPending(provider string, currentState string, nextState string) ([]string, error) {
+	return s.Repo.getPending(provider, currentState, nextState, s.Config.ServerMaxAttempts, time.Now().Unix())
+}
+
+// Servers recorded before provider ownership was tracked have no provider. Assign them when only one provider is configured
+func (s *Service) AssignUnownedServers(provider string) error {
+	rows, err := s.Repo.assignUnownedServers(provider)
+	if err != nil {
+		return err
+	}
+
+	if rows > 0 {
+		s.Logger.Info("Assigned unowned servers to provider", "domain", "server", "provider", provider, "count", rows)
+	}
+
+	return nil
 }
 
-// Get server IDs which are pending a state change
-func (s *Service) GetPending(currentState string, nextState string) ([]string, error) {
-	return s.Repo.getPending(currentState, nextState)
+// Remove servers owned by providers no longer configured. No scrape covers them, so they would stay on the dashboard
+// and any session on their groups would never start or stop
+func (s *Service) RemoveUnconfiguredServers(providers []string) error {
+	if len(providers) == 0 {
+		return nil
+	}
+
+	rows, err := s.Repo.deleteUnconfigured(providers, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+
+	if rows > 0 {
+		s.Logger.Info("Removed servers of providers no longer configured", "domain", "server", "providers", providers, "count", rows)
+	}
+
+	return nil
+}
+
+// Record a failed start or stop against a server and back off before the next attempt. The session owner for the server group is notified when the
+// server first fails so they are not left waiting, retries of the same request are not re-sent. A server out of attempts fails the session, which notifies again
+func (s *Service) RecordFailure(uniqueID string, action string, cause error) {
+	tx, err := s.Repo.Base.DB.Begin()
+	if err != nil {
+		s.Logger.Error("Failed to create transaction for recording server failure", "domain", "server", "id", uniqueID, "error", err)
+		return
+	}
+	defer tx.Rollback()
+
+	reason := fmt.Sprintf("%s failed: %v", action, cause)
+	now := time.Now()
+
+	failure, err := s.Repo.recordFailure(tx, uniqueID, reason, now.Unix())
+	if err != nil {
+		s.Logger.Error("Failed to record server failure", "domain", "server", "id", uniqueID, "error", err)
+		return
+	}
+
+	if err := s.Repo.setNextAttempt(tx, uniqueID, now.Add(s.getBackoff(failure.Attempts)).Unix()); err != nil {
+		s.Logger.Error("Failed to set next server attempt", "domain", "server", "id", uniqueID, "error", err)
+		return
+	}
+
+	// Say whether another attempt will be made
+	retry := "retrying"
+	if maxAttempts := s.Config.ServerMaxAttempts; maxAttempts > 0 {
+		retry = fmt.Sprintf("attempt %d of %d", failure.Attempts, maxAttempts)
+		if failure.Attempts >= maxAttempts {
+			retry = fmt.Sprintf("giving up after %d attempts", failure.Attempts)
+		}
+	}
+
+	if failure.OwnerID != nil && failure.Attempts == 1 {
+		n := notification.NewNotification{
+			UserID: *failure.OwnerID,
+			Msg:    fmt.Sprintf("Server %s in Server Group %s failed to %s, %s: %v", failure.Name, failure.ServerGroup, action, retry, cause),
+			Title:  fmt.Sprintf("Server failed: %s", failure.ServerGroup),
+		}
+
+		if err := s.NotificationService.QueueNotification(tx, n); err != nil {
+			s.Logger.Error("Failed to queue server failure notification", "domain", "server", "id", uniqueID, "server_group", failure.ServerGroup, "error", err)
+			return
+		}
+	}
+
+	s.Audit.LogTx(tx, audit.Event{
+		ActorUserID: 0,
+		ActorEmail:  "system",
+		Action:      action,
+		Resource:    "server",
+		Success:     false,
+		Reason:      cause.Error(),
+		Metadata: map[string]any{
+			"server":       failure.Name,
+			"server_group": failure.ServerGroup,
+			"provider":     failure.Provider,
+			"attempts":     failure.Attempts,
+		},
+	})
+
+	if err := tx.Commit(); err != nil {
+		s.Logger.Error("Failed to commit server failure", "domain", "server", "id", uniqueID, "error", err)
+		return
+	}
+
+	s.Logger.Warn("Recorded server failure", "domain", "server", "id", uniqueID, "server_group", failure.ServerGroup, "action", action, "error", cause)
+}
+
+// Wait before the next attempt, doubling with each failure and capped at the transition timeout when set
+func (s *Service) getBackoff(attempts int) time.Duration {
+	backoff := s.Config.ServerRetryBackoff
+	for i := 1; i < attempts && backoff < time.Hour; i++ {
+		backoff *= 2
+	}
+
+	if timeout := s.Config.ServerTransitionTimeout; timeout > 0 && backoff > timeout {
+		backoff = timeout
+	}
+
+	return backoff
+}
+
+// Clear a previously recorded failure once an operation on the server succeeds
+func (s *Service) ClearFailure(uniqueID string) {
+	if err := s.Repo.clearFailure(uniqueID); err != nil {
+		s.Logger.Error("Failed to clear server failure", "domain", "server", "id", uniqueID, "error", err)
+	}
+}
+
+// Build per server and per group timelines and running time over a time range
+func (s *Service) GetServerHistory(req ServerHistoryRequest) (ServerHistoryResponse, error) {
+	if req.To == 0 {
+		req.To = time.Now().Unix()
+	}
+
+	if req.From == 0 {
+		req.From = time.Unix(req.To, 0).AddDate(0, 0, -30).Unix()
+	}
+
+	if req.From >= req.To {
+		return ServerHistoryResponse{}, shared.ErrInvalidTimeRange
+	}
+
+	changes, err := s.Repo.getStateChanges(req.From, req.To, req.ServerGroup)
+	if err != nil {
+		return ServerHistoryResponse{}, err
+	}
+
+	return ServerHistoryResponse{
+		From:   req.From,
+		To:     req.To,
+		Groups: buildHistory(changes, req.From, req.To, req.ServerGroup),
+	}, nil
 }
@@ -0,0 +1,635 @@
+package server_test
+
+import (
+	"encoding/json"
+	"errors"
+	"ez2boot/internal/server"
+	"ez2boot/internal/session"
+	"ez2boot/internal/shared"
+	"ez2boot/internal/testutil"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Servers of a provider removed from the configuration are removed at startup, unowned servers are left to be assigned
+func TestRemoveUnconfiguredServers(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	env.ServerService.UpdateServers("aws", []server.Server{
+		{UniqueID: "i-0000000000000000a", Name: "aws01", State: server.ServerOff, ServerGroup: "AWS", TimeAdded: time.Now().Unix()},
+	})
+	env.ServerService.UpdateServers("gcp", []server.Server{
+		{UniqueID: "projects/p/zones/z/instances/gcp01", Name: "gcp01", State: server.ServerOff, ServerGroup: "GCP", TimeAdded: time.Now().Unix(), GroupTags: map[string]string{
+			server.GroupTagDescription: "Old project",
+		}},
+	})
+	testutil.InsertServer(t, env.DB, "i-0000000000000000c", "legacy01", "off", "LEGACY", time.Now().Unix())
+
+	if err := env.ServerService.RemoveUnconfiguredServers([]string{"aws"}); err != nil {
+		t.Fatalf("failed to remove unconfigured servers: %v", err)
+	}
+
+	rows, err := env.DB.Query("SELECT name FROM servers ORDER BY name")
+	if err != nil {
+		t.Fatalf("failed to query servers: %v", err)
+	}
+	defer rows.Close()
+
+	got := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("failed to scan server row: %v", err)
+		}
+		got = append(got, name)
+	}
+
+	if fmt.Sprint(got) != "[aws01 legacy01]" {
+		t.Errorf("want gcp server removed, got %v", got)
+	}
+
+	var removed, tags int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM server_history WHERE name = $1 AND state = $2", "gcp01", server.ServerRemoved).Scan(&removed); err != nil {
+		t.Fatalf("failed to query history: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("want removal recorded in history, got %d", removed)
+	}
+
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM server_group_tags WHERE provider = $1", "gcp").Scan(&tags); err != nil {
+		t.Fatalf("failed to query group tags: %v", err)
+	}
+	if tags != 0 {
+		t.Errorf("want gcp group tags removed, got %d", tags)
+	}
+}
+
+// Scrape from one provider must not remove or claim servers owned by another
+func TestUpdateServers_ProviderIsolation(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	serverService := env.ServerService
+
+	serverService.UpdateServers("aws", []server.Server{
+		{UniqueID: "i-3728hvi2vn2u4vn2", Name: "test01", State: server.ServerOff, ServerGroup: "QA", TimeAdded: time.Now().Unix()},
+	})
+
+	serverService.UpdateServers("azure", []server.Server{
+		{UniqueID: "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/dev01", Name: "dev01", State: server.ServerOff, ServerGroup: "DEV", TimeAdded: time.Now().Unix()},
+	})
+
+	// Empty azure scrape removes azure servers only
+	serverService.UpdateServers("azure", []server.Server{})
+
+	var count int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM servers WHERE provider = $1", "aws").Scan(&count); err != nil {
+		t.Fatalf("failed to query servers: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("want 1 aws server retained, got %d", count)
+	}
+
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM servers WHERE provider = $1", "azure").Scan(&count); err != nil {
+		t.Fatalf("failed to query servers: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("want azure servers removed, got %d", count)
+	}
+}
+
+func TestGetPending_ProviderScoped(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	serverService := env.ServerService
+
+	testutil.InsertUser(t, env.DB, "user@example.com", nil, true, false, false, true, "local")
+
+	testutil.InsertServer(t, env.DB, "i-3728hvi2vn2u4vn2", "test01", "off", "QA", time.Now().Unix())
+	testutil.SetServerProvider(t, env.DB, "QA", "aws")
+	testutil.InsertServerSession(t, env.DB, 1, "QA", time.Now().Add(1*time.Hour).Unix())
+
+	testutil.InsertServer(t, env.DB, "projects/p/zones/z/instances/dev01", "dev01", "off", "DEV", time.Now().Unix())
+	testutil.SetServerProvider(t, env.DB, "DEV", "gcp")
+	testutil.InsertServerSession(t, env.DB, 1, "DEV", time.Now().Add(1*time.Hour).Unix())
+
+	awsIDs, err := serverService.GetPending("aws", "off", "on")
+	if err != nil {
+		t.Fatalf("failed to get pending: %v", err)
+	}
+	if len(awsIDs) != 1 || awsIDs[0] != "i-3728hvi2vn2u4vn2" {
+		t.Errorf("want only aws server pending for aws, got %v", awsIDs)
+	}
+
+	azureIDs, err := serverService.GetPending("azure", "off", "on")
+	if err != nil {
+		t.Fatalf("failed to get pending: %v", err)
+	}
+	if len(azureIDs) != 0 {
+		t.Errorf("want no servers pending for azure, got %v", azureIDs)
+	}
+}
+
+// Metadata from a scrape is stored, refreshed on change and returned in the session summary
+func TestUpdateServers_MetadataInSummary(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	adminEmail := "admin@example.com"
+	adminPassword := "testpassword123"
+	adminHash := "$argon2id$v=19$m=131072,t=4,p=1$bBVby41uAKJ7KghSdCEt8g$80aCufSfLP2tAZ9bxAjbs8mArxgjmgrP3UkPn8MKCJY"
+	testutil.InsertUser(t, env.DB, adminEmail, &adminHash, true, true, true, true, "local")
+
+	metadata := server.Metadata{
+		InstanceType: "t3.medium",
+		PrivateIP:    "10.0.1.10",
+		Zone:         "ap-southeast-2a",
+		Platform:     "Linux/UNIX",
+		LaunchTime:   1700000000,
+	}
+
+	env.ServerService.UpdateServers("aws", []server.Server{
+		{UniqueID: "i-3728hvi2vn2u4vn2", Name: "test01", State: server.ServerOn, ServerGroup: "QA", TimeAdded: time.Now().Unix(), Metadata: metadata},
+	})
+
+	// Public IP assigned on a later scrape with no other change
+	metadata.PublicIP = "203.0.113.10"
+	env.ServerService.UpdateServers("aws", []server.Server{
+		{UniqueID: "i-3728hvi2vn2u4vn2", Name: "test01", State: server.ServerOn, ServerGroup: "QA", TimeAdded: time.Now().Unix(), Metadata: metadata},
+	})
+
+	cookies := testutil.LoginAndGetCookies(t, env.Router, adminEmail, adminPassword)
+
+	req := httptest.NewRequest("GET", "/ui/sessions/summary", nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+
+	w := httptest.NewRecorder()
+	env.Router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	var got shared.ApiResponse[[]session.ServerSessionSummaryResponse]
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if len(got.Data) != 1 || len(got.Data[0].Servers) != 1 {
+		t.Fatalf("want 1 group with 1 server, got %+v", got.Data)
+	}
+
+	if got.Data[0].Servers[0].Metadata != metadata {
+		t.Errorf("metadata mismatch, want: %+v, got: %+v", metadata, got.Data[0].Servers[0].Metadata)
+	}
+}
+
+// Companion tags become group metadata in the summary, ordered groups first
+func TestUpdateServers_GroupMetadataInSummary(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	adminEmail := "admin@example.com"
+	adminPassword := "testpassword123"
+	adminHash := "$argon2id$v=19$m=131072,t=4,p=1$bBVby41uAKJ7KghSdCEt8g$80aCufSfLP2tAZ9bxAjbs8mArxgjmgrP3UkPn8MKCJY"
+	testutil.InsertUser(t, env.DB, adminEmail, &adminHash, true, true, true, true, "local")
+
+	env.ServerService.UpdateServers("aws", []server.Server{
+		{UniqueID: "i-0000000000000000a", Name: "alpha01", State: server.ServerOff, ServerGroup: "ALPHA", TimeAdded: time.Now().Unix()},
+		{UniqueID: "i-0000000000000000b", Name: "qa01", State: server.ServerOff, ServerGroup: "QA", TimeAdded: time.Now().Unix(), GroupTags: map[string]string{
+			server.GroupTagDescription: "QA environment",
+			server.GroupTagOwner:       "qa-team@example.com",
+			server.GroupTagOrder:       "1",
+			server.GroupTagLinks:       "https://wiki.example.com/qa javascript:alert(1)",
+		}},
+		{UniqueID: "i-0000000000000000c", Name: "qa02", State: server.ServerOff, ServerGroup: "QA", TimeAdded: time.Now().Unix(), GroupTags: map[string]string{
+			server.GroupTagDescription: "Ignored, first value wins",
+		}},
+	})
+
+	cookies := testutil.LoginAndGetCookies(t, env.Router, adminEmail, adminPassword)
+
+	req := httptest.NewRequest("GET", "/ui/sessions/summary", nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+
+	w := httptest.NewRecorder()
+	env.Router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	var got shared.ApiResponse[[]session.ServerSessionSummaryResponse]
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if len(got.Data) != 2 || got.Data[0].ServerGroup != "QA" {
+		t.Fatalf("want ordered QA group first, got %+v", got.Data)
+	}
+
+	qa := got.Data[0].Metadata
+	if qa.Description != "QA environment" || qa.Owner != "qa-team@example.com" || qa.Order == nil || *qa.Order != 1 {
+		t.Errorf("QA metadata mismatch, got: %+v", qa)
+	}
+
+	if len(qa.Links) != 1 || qa.Links[0] != "https://wiki.example.com/qa" {
+		t.Errorf("want only the web link, got %v", qa.Links)
+	}
+
+	alpha := got.Data[1].Metadata
+	if alpha.Description != "" || alpha.Order != nil || len(alpha.Links) != 0 {
+		t.Errorf("want empty ALPHA metadata, got: %+v", alpha)
+	}
+
+	// Tags removed from the cloud are removed on the next scrape
+	env.ServerService.UpdateServers("aws", []server.Server{
+		{UniqueID: "i-0000000000000000b", Name: "qa01", State: server.ServerOff, ServerGroup: "QA", TimeAdded: time.Now().Unix()},
+	})
+
+	var count int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM server_group_tags").Scan(&count); err != nil {
+		t.Fatalf("failed to query group tags: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("want group tags cleared, got %d", count)
+	}
+}
+
+// Unknown roles in allowed users are dropped at scrape, a group left with none is restricted to admins rather than opened
+func TestUpdateServers_AllowedUsersRoles(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	env.ServerService.UpdateServers("aws", []server.Server{
+		{UniqueID: "i-0000000000000000a", Name: "qa01", State: server.ServerOff, ServerGroup: "QA", TimeAdded: time.Now().Unix(), GroupTags: map[string]string{
+			server.GroupTagAllowedUsers: "QA@example.com developers Admins",
+		}},
+		{UniqueID: "i-0000000000000000b", Name: "secret01", State: server.ServerOff, ServerGroup: "SECRET", TimeAdded: time.Now().Unix(), GroupTags: map[string]string{
+			server.GroupTagAllowedUsers: "developers",
+		}},
+	})
+
+	want := map[string]string{"QA": "qa@example.com admins", "SECRET": "admins"}
+	for group, value := range want {
+		var got string
+		if err := env.DB.QueryRow("SELECT value FROM server_group_tags WHERE server_group = $1 AND name = $2", group, server.GroupTagAllowedUsers).Scan(&got); err != nil {
+			t.Fatalf("failed to query group tag: %v", err)
+		}
+		if got != value {
+			t.Errorf("%s: want allowed users %q, got %q", group, value, got)
+		}
+	}
+}
+
+// Each tier waits for the previous tier, stop runs in reverse
+func TestGetPending_BootOrder(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	serverService := env.ServerService
+
+	testutil.InsertUser(t, env.DB, "user@example.com", nil, true, false, false, true, "local")
+
+	serverService.UpdateServers("aws", []server.Server{
+		{UniqueID: "i-db", Name: "db01", State: server.ServerOff, ServerGroup: "QA", TimeAdded: time.Now().Unix(), BootOrder: 1},
+		{UniqueID: "i-app1", Name: "app01", State: server.ServerOff, ServerGroup: "QA", TimeAdded: time.Now().Unix(), BootOrder: 2},
+		{UniqueID: "i-app2", Name: "app02", State: server.ServerOff, ServerGroup: "QA", TimeAdded: time.Now().Unix(), BootOrder: 2},
+	})
+	testutil.InsertServerSession(t, env.DB, 1, "QA", time.Now().Add(1*time.Hour).Unix())
+
+	assertPending := func(current string, next string, want ...string) {
+		t.Helper()
+
+		got, err := serverService.GetPending("aws", current, next)
+		if err != nil {
+			t.Fatalf("failed to get pending: %v", err)
+		}
+
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			t.Errorf("want pending %v, got %v", want, got)
+		}
+	}
+
+	// Database tier first
+	assertPending("off", "on", "i-db")
+
+	// App tier once the database is on, transitioning is not enough
+	if _, err := env.DB.Exec("UPDATE servers SET state = $1 WHERE unique_id = $2", "transitioning", "i-db"); err != nil {
+		t.Fatalf("failed to update server: %v", err)
+	}
+	assertPending("off", "on")
+
+	if _, err := env.DB.Exec("UPDATE servers SET state = $1 WHERE unique_id = $2", "on", "i-db"); err != nil {
+		t.Fatalf("failed to update server: %v", err)
+	}
+	assertPending("off", "on", "i-app1", "i-app2")
+
+	// Stop in reverse, app tier first
+	testutil.UpdateServerState(t, env.DB, "QA", "on")
+	if _, err := env.DB.Exec("UPDATE servers SET next_state = $1 WHERE server_group = $2", "off", "QA"); err != nil {
+		t.Fatalf("failed to update server: %v", err)
+	}
+	assertPending("on", "off", "i-app1", "i-app2")
+
+	if _, err := env.DB.Exec("UPDATE servers SET state = $1 WHERE unique_id IN ($2, $3)", "off", "i-app1", "i-app2"); err != nil {
+		t.Fatalf("failed to update server: %v", err)
+	}
+	assertPending("on", "off", "i-db")
+}
+
+// Failed servers back off between attempts and are no longer pending once out of attempts
+func TestRecordFailure_RetryAndGiveUp(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+	env.Cfg.ServerMaxAttempts = 2
+	env.Cfg.ServerRetryBackoff = 1 * time.Hour
+
+	serverService := env.ServerService
+
+	testutil.InsertUser(t, env.DB, "user@example.com", nil, true, false, false, true, "local")
+
+	serverService.UpdateServers("aws", []server.Server{
+		{UniqueID: "i-app", Name: "app01", State: server.ServerOff, ServerGroup: "QA", TimeAdded: time.Now().Unix()},
+	})
+	testutil.InsertServerSession(t, env.DB, 1, "QA", time.Now().Add(1*time.Hour).Unix())
+
+	assertPending := func(want ...string) {
+		t.Helper()
+
+		got, err := serverService.GetPending("aws", "off", "on")
+		if err != nil {
+			t.Fatalf("failed to get pending: %v", err)
+		}
+
+		if !slices.Equal(got, want) {
+			t.Errorf("want pending %v, got %v", want, got)
+		}
+	}
+
+	// Skip the wait, as if the backoff had passed
+	endBackoff := func() {
+		t.Helper()
+
+		if _, err := env.DB.Exec("UPDATE servers SET next_attempt = 0 WHERE unique_id = $1", "i-app"); err != nil {
+			t.Fatalf("failed to update server: %v", err)
+		}
+	}
+
+	assertPending("i-app")
+
+	// Backing off after the first failure
+	serverService.RecordFailure("i-app", "start", errors.New("insufficient capacity"))
+	assertPending()
+
+	endBackoff()
+	assertPending("i-app")
+
+	// Out of attempts, even once the backoff has passed
+	serverService.RecordFailure("i-app", "start", errors.New("insufficient capacity"))
+	endBackoff()
+	assertPending()
+
+	var attempts int
+	var lastError *string
+	if err := env.DB.QueryRow("SELECT attempts, last_error FROM servers WHERE unique_id = $1", "i-app").Scan(&attempts, &lastError); err != nil {
+		t.Fatalf("failed to query server: %v", err)
+	}
+
+	if attempts != 2 {
+		t.Errorf("want 2 attempts, got %d", attempts)
+	}
+
+	if lastError == nil || *lastError != "start failed: insufficient capacity" {
+		t.Errorf("want last error recorded, got %v", lastError)
+	}
+
+	// Owner notified once, when the server first failed
+	var count int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM notification_queue WHERE user_id = $1", 1).Scan(&count); err != nil {
+		t.Fatalf("failed to query notifications: %v", err)
+	}
+
+	if count != 1 {
+		t.Errorf("want 1 failure notification, got %d", count)
+	}
+
+	// A success starts afresh
+	serverService.ClearFailure("i-app")
+	assertPending("i-app")
+}
+
+// Scrapes record new servers, state changes and removals, but not unchanged states
+func TestUpdateServers_RecordsHistory(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	serverService := env.ServerService
+
+	scrape := func(state server.ServerState) {
+		serverService.UpdateServers("aws", []server.Server{
+			{UniqueID: "i-app", Name: "app01", State: state, ServerGroup: "QA", TimeAdded: time.Now().Unix()},
+		})
+	}
+
+	scrape(server.ServerOff)
+	scrape(server.ServerOff)
+	scrape(server.ServerOn)
+	scrape(server.ServerOn)
+	serverService.UpdateServers("aws", []server.Server{})
+
+	rows, err := env.DB.Query("SELECT state FROM server_history WHERE unique_id = $1 ORDER BY id", "i-app")
+	if err != nil {
+		t.Fatalf("failed to query history: %v", err)
+	}
+	defer rows.Close()
+
+	got := []string{}
+	for rows.Next() {
+		var state string
+		if err := rows.Scan(&state); err != nil {
+			t.Fatalf("failed to scan history row: %v", err)
+		}
+		got = append(got, state)
+	}
+
+	want := []string{"off", "on", "removed"}
+	if !slices.Equal(got, want) {
+		t.Errorf("want history %v, got %v", want, got)
+	}
+}
+
+// Timelines are clipped to the requested range and running hours summed per server and group
+func TestGetServerHistory_RunningHours(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	adminEmail := "admin@example.com"
+	adminPassword := "testpassword123"
+	adminHash := "$argon2id$v=19$m=131072,t=4,p=1$bBVby41uAKJ7KghSdCEt8g$80aCufSfLP2tAZ9bxAjbs8mArxgjmgrP3UkPn8MKCJY"
+	testutil.InsertUser(t, env.DB, adminEmail, &adminHash, true, true, true, true, "local")
+
+	hour := int64(3600)
+	from := int64(1700000000)
+	to := from + 10*hour
+
+	// app01 on before the range until 2h in, then on again 6h to 7h. db01 on from 4h in until removed at 5h
+	history := []struct {
+		id    string
+		name  string
+		state string
+		at    int64
+	}{
+		{"i-app", "app01", "on", from - 5*hour},
+		{"i-app", "app01", "off", from + 2*hour},
+		{"i-app", "app01", "on", from + 6*hour},
+		{"i-app", "app01", "off", from + 7*hour},
+		{"i-db", "db01", "on", from + 4*hour},
+		{"i-db", "db01", "removed", from + 5*hour},
+		{"i-old", "old01", "on", from - 9*hour},
+		{"i-old", "old01", "removed", from - 8*hour},
+	}
+
+	for _, h := range history {
+		if _, err := env.DB.Exec("INSERT INTO server_history (unique_id, name, server_group, provider, state, time_stamp) VALUES ($1, $2, $3, $4, $5, $6)", h.id, h.name, "QA", "aws", h.state, h.at); err != nil {
+			t.Fatalf("failed to insert history: %v", err)
+		}
+	}
+
+	cookies := testutil.LoginAndGetCookies(t, env.Router, adminEmail, adminPassword)
+
+	req := httptest.NewRequest("GET", fmt.Sprintf("/ui/servers/history?from=%d&to=%d&server_group=QA", from, to), nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+
+	w := httptest.NewRecorder()
+	env.Router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	var got shared.ApiResponse[server.ServerHistoryResponse]
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if len(got.Data.Groups) != 1 {
+		t.Fatalf("want 1 group, got %+v", got.Data.Groups)
+	}
+
+	group := got.Data.Groups[0]
+	if group.RunningSeconds != 4*hour || group.RunningHours != 4 {
+		t.Errorf("want group running 4h, got %ds (%vh)", group.RunningSeconds, group.RunningHours)
+	}
+
+	want := map[string]struct {
+		running int64
+		periods int
+	}{
+		"app01": {3 * hour, 4},
+		"db01":  {1 * hour, 1},
+	}
+
+	if len(group.Servers) != len(want) {
+		t.Fatalf("want %d servers, got %+v", len(want), group.Servers)
+	}
+
+	for _, s := range group.Servers {
+		w, ok := want[s.Name]
+		if !ok {
+			t.Errorf("unexpected server in history: %s", s.Name)
+			continue
+		}
+
+		if s.RunningSeconds != w.running || len(s.Timeline) != w.periods {
+			t.Errorf("server %s want %ds over %d periods, got %ds over %+v", s.Name, w.running, w.periods, s.RunningSeconds, s.Timeline)
+		}
+
+	}
+
+	// Started before the range
+	if app := group.Servers[0]; app.Timeline[0].From != from || app.Timeline[0].To != from+2*hour {
+		t.Errorf("want first app01 period clipped to %d-%d, got %+v", from, from+2*hour, app.Timeline[0])
+	}
+
+	// Range the wrong way round
+	req = httptest.NewRequest("GET", fmt.Sprintf("/ui/servers/history?from=%d&to=%d", to, from), nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+
+	w = httptest.NewRecorder()
+	env.Router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("want 400 for reversed range, got %d", w.Code)
+	}
+}
+
+func TestGetServerHistory_GroupMove(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	adminEmail := "admin@example.com"
+	adminPassword := "testpassword123"
+	adminHash := "$argon2id$v=19$m=131072,t=4,p=1$bBVby41uAKJ7KghSdCEt8g$80aCufSfLP2tAZ9bxAjbs8mArxgjmgrP3UkPn8MKCJY"
+	testutil.InsertUser(t, env.DB, adminEmail, &adminHash, true, true, true, true, "local")
+
+	hour := int64(3600)
+	from := int64(1700000000)
+	to := from + 10*hour
+
+	// app01 runs the whole range, moving from QA to UAT 3h in
+	history := []struct {
+		group string
+		at    int64
+	}{
+		{"QA", from - 1*hour},
+		{"UAT", from + 3*hour},
+	}
+
+	for _, h := range history {
+		if _, err := env.DB.Exec("INSERT INTO server_history (unique_id, name, server_group, provider, state, time_stamp) VALUES ($1, $2, $3, $4, $5, $6)", "i-app", "app01", h.group, "aws", "on", h.at); err != nil {
+			t.Fatalf("failed to insert history: %v", err)
+		}
+	}
+
+	cookies := testutil.LoginAndGetCookies(t, env.Router, adminEmail, adminPassword)
+
+	tests := []struct {
+		group string
+		want  map[string]int64
+	}{
+		{"QA", map[string]int64{"QA": 3 * hour}},
+		{"UAT", map[string]int64{"UAT": 7 * hour}},
+		{"", map[string]int64{"QA": 3 * hour, "UAT": 7 * hour}},
+	}
+
+	for _, tc := range tests {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/ui/servers/history?from=%d&to=%d&server_group=%s", from, to, tc.group), nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		w := httptest.NewRecorder()
+		env.Router.ServeHTTP(w, req)
+
+		var got shared.ApiResponse[server.ServerHistoryResponse]
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+
+		if len(got.Data.Groups) != len(tc.want) {
+			t.Errorf("group %q: want %d groups, got %+v", tc.group, len(tc.want), got.Data.Groups)
+			continue
+		}
+
+		for _, g := range got.Data.Groups {
+			if g.RunningSeconds != tc.want[g.ServerGroup] {
+				t.Errorf("group %q: want %s running %ds, got %ds", tc.group, g.ServerGroup, tc.want[g.ServerGroup], g.RunningSeconds)
+			}
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"ez2boot/internal/config"
 	"ez2boot/internal/db"
 	"ez2boot/internal/notification"
+	"ez2boot/internal/readiness"
 	"ez2boot/internal/user"
 	"log/slog"
 )
@@ -17,12 +18,13 @@ func NewHandler(sessionService *Service, cfg *config.Config, logger *slog.Logger
 	}
 }
 
-func NewService(sessionRepo *Repository, cfg *config.Config, notificationService *notification.Service, userService *user.Service, audit *audit.Service, logger *slog.Logger) *Service {
+func NewService(sessionRepo *Repository, cfg *config.Config, notificationService *notification.Service, userService *user.Service, readinessService *readiness.Service, audit *audit.Service, logger *slog.Logger) *Service {
 	return &Service{
 		Repo:                sessionRepo,
 		Config:              cfg,
 		NotificationService: notificationService,
 		UserService:         userService,
+		ReadinessService:    readinessService,
 		Audit:               audit,
 		Logger:              logger,
 	}
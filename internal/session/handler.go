@@ -1,6 +1,7 @@
 package session
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"ez2boot/internal/ctxutil"
@@ -53,12 +54,33 @@ func (h *Handler) NewServerSession() http.HandlerFunc {
 					Success: false,
 					Error:   "Missing field in request",
 				}
+			case errors.Is(err, shared.ErrGroupAccessDenied):
+				h.Logger.Warn("Denied new session by server group policy", "user", email, "domain", "session", "server_group", req.ServerGroup, "error", err)
+				w.WriteHeader(http.StatusForbidden)
+				resp = shared.ApiResponse[any]{
+					Success: false,
+					Error:   "Not allowed to use this server group",
+				}
+			case errors.Is(err, shared.ErrScheduleConflict):
+				h.Logger.Warn("Failed to create new session", "user", email, "domain", "session", "server_group", req.ServerGroup, "error", err)
+				w.WriteHeader(http.StatusConflict)
+				resp = shared.ApiResponse[any]{
+					Success: false,
+					Error:   "Server group is booked before this session would end",
+				}
+			case errors.Is(err, shared.ErrGroupHeld):
+				h.Logger.Warn("Failed to create new session", "user", email, "domain", "session", "server_group", req.ServerGroup, "error", err)
+				w.WriteHeader(http.StatusConflict)
+				resp = shared.ApiResponse[any]{
+					Success: false,
+					Error:   "Server group is held for the next user on the waitlist",
+				}
 			case errors.Is(err, shared.ErrDurationTooLong):
 				h.Logger.Error("Failed to create new session", "user", email, "domain", "session", "server_group", session.ServerGroup, "error", err)
 				w.WriteHeader(http.StatusBadRequest)
 				resp = shared.ApiResponse[any]{
 					Success: false,
-					Error:   fmt.Sprintf("Max session duration is %s", h.Config.MaxServerSessionDuration),
+					Error:   fmt.Sprintf("Max session duration is %s", h.Service.getGroupMaxDuration(req.ServerGroup)),
 				}
 			default:
 				h.Logger.Error("Failed to create new session", "user", email, "domain", "session", "server_group", session.ServerGroup, "error", err)
@@ -111,12 +133,26 @@ func (h *Handler) UpdateServerSession() http.HandlerFunc {
 					Success: false,
 					Error:   "Missing field in request",
 				}
+			case errors.Is(err, shared.ErrGroupAccessDenied):
+				h.Logger.Warn("Denied session update by server group policy", "user", email, "domain", "session", "server_group", req.ServerGroup, "error", err)
+				w.WriteHeader(http.StatusForbidden)
+				resp = shared.ApiResponse[any]{
+					Success: false,
+					Error:   "Not allowed to use this server group",
+				}
+			case errors.Is(err, shared.ErrScheduleConflict):
+				h.Logger.Warn("Failed to update server session", "user", email, "domain", "session", "server_group", req.ServerGroup, "error", err)
+				w.WriteHeader(http.StatusConflict)
+				resp = shared.ApiResponse[any]{
+					Success: false,
+					Error:   "Server group is booked before this session would end",
+				}
 			case errors.Is(err, shared.ErrDurationTooLong):
 				h.Logger.Error("Failed to update server session", "user", email, "domain", "session", "server_group", session.ServerGroup, "error", err)
 				w.WriteHeader(http.StatusBadRequest)
 				resp = shared.ApiResponse[any]{
 					Success: false,
-					Error:   fmt.Sprintf("Max session duration is %s", h.Config.MaxServerSessionDuration),
+					Error:   fmt.Sprintf("Max session duration is %s", h.Service.getGroupMaxDuration(req.ServerGroup)),
 				}
 			default:
 				h.Logger.Error("Failed to update server session", "user", email, "domain", "session", "server_group", session.ServerGroup, "error", err)
@@ -174,7 +210,7 @@ func (h *Handler) UpdateServerSessionAdmin() http.HandlerFunc {
 				w.WriteHeader(http.StatusBadRequest)
 				resp = shared.ApiResponse[any]{
 					Success: false,
-					Error:   fmt.Sprintf("Max session duration is %s", h.Config.MaxServerSessionDuration),
+					Error:   fmt.Sprintf("Max session duration is %s", h.Service.getGroupMaxDuration(req.ServerGroup)),
 				}
 			default:
 				h.Logger.Error("Failed to update server session", "user", email, "domain", "session", "server_group", session.ServerGroup, "error", err)
@@ -193,3 +229,405 @@ func (h *Handler) UpdateServerSessionAdmin() http.HandlerFunc {
 		json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: true, Data: session})
 	}
 }
+
+func (h *Handler) EndServerSession() http.HandlerFunc {
+	return h.endServerSession(false)
+}
+
+func (h *Handler) EndServerSessionAdmin() http.HandlerFunc {
+	return h.endServerSession(true)
+}
+
+func (h *Handler) endServerSession(admin bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, email := ctxutil.GetActor(ctx)
+
+		var req ServerSessionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.Logger.Error("Malformed request", "user", email, "domain", "session", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Malformed request"})
+			return
+		}
+
+		req.UserID = userID
+
+		var err error
+		if admin {
+			err = h.Service.endServerSessionAdmin(req, ctx)
+		} else {
+			err = h.Service.endServerSession(req, ctx)
+		}
+
+		if err != nil {
+			switch {
+			case errors.Is(err, shared.ErrNoRowsUpdated):
+				h.Logger.Warn("Requested session to end was either not found or not owned", "user", email, "domain", "session", "server_group", req.ServerGroup, "error", err)
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Failed to find session"})
+			case errors.Is(err, shared.ErrFieldMissing):
+				h.Logger.Error("Failed to end server session", "user", email, "domain", "session", "server_group", req.ServerGroup, "error", err)
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Missing field in request"})
+			case errors.Is(err, shared.ErrGroupAccessDenied):
+				h.Logger.Warn("Denied ending session by server group policy", "user", email, "domain", "session", "server_group", req.ServerGroup, "error", err)
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Not allowed to use this server group"})
+			default:
+				h.Logger.Error("Failed to end server session", "user", email, "domain", "session", "server_group", req.ServerGroup, "error", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Failed to end server session"})
+			}
+			return
+		}
+
+		h.Logger.Info("Server session ended", "user", email, "domain", "session", "server_group", req.ServerGroup)
+		json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: true})
+	}
+}
+
+func (h *Handler) AddCoOwner() http.HandlerFunc {
+	return h.changeSessionOwner("Failed to add co-owner", "Co-owner added", h.Service.addCoOwner)
+}
+
+func (h *Handler) RemoveCoOwner() http.HandlerFunc {
+	return h.changeSessionOwner("Failed to remove co-owner", "Co-owner removed", h.Service.removeCoOwner)
+}
+
+func (h *Handler) HandOverServerSession() http.HandlerFunc {
+	return h.changeSessionOwner("Failed to hand over server session", "Server session handed over", h.Service.handOverServerSession)
+}
+
+// Shared handler for changing who owns a session, the actions only differ by service call and messages
+func (h *Handler) changeSessionOwner(failure string, success string, action func(SessionOwnerRequest, context.Context) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, email := ctxutil.GetActor(ctx)
+
+		var req SessionOwnerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.Logger.Error("Malformed request", "user", email, "domain", "session", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Malformed request"})
+			return
+		}
+
+		req.UserID = userID
+
+		if err := action(req, ctx); err != nil {
+			h.Logger.Error(failure, "user", email, "domain", "session", "server_group", req.ServerGroup, "target", req.Email, "error", err)
+
+			status, msg := http.StatusInternalServerError, failure
+			switch {
+			case errors.Is(err, shared.ErrFieldMissing):
+				status, msg = http.StatusBadRequest, "Missing field in request"
+			case errors.Is(err, shared.ErrNoRowsUpdated), errors.Is(err, shared.ErrNoRowsDeleted):
+				status, msg = http.StatusNotFound, "Failed to find session"
+			case errors.Is(err, shared.ErrUserNotFound):
+				status, msg = http.StatusNotFound, "User not found"
+			case errors.Is(err, shared.ErrGroupAccessDenied):
+				status, msg = http.StatusForbidden, "User is not allowed to use this server group"
+			case errors.Is(err, shared.ErrAlreadyOwner):
+				status, msg = http.StatusConflict, "User already owns this session"
+			}
+
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: msg})
+			return
+		}
+
+		h.Logger.Info(success, "user", email, "domain", "session", "server_group", req.ServerGroup, "target", req.Email)
+		json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: true})
+	}
+}
+
+func (h *Handler) JoinWaitlist() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, email := ctxutil.GetActor(ctx)
+
+		var req WaitlistRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.Logger.Error("Malformed request", "user", email, "domain", "session", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Malformed request"})
+			return
+		}
+
+		req.UserID = userID
+
+		entry, err := h.Service.joinWaitlist(req, ctx)
+		if err != nil {
+			h.Logger.Error("Failed to join waitlist", "user", email, "domain", "session", "server_group", req.ServerGroup, "error", err)
+
+			status, msg := http.StatusInternalServerError, "Failed to join waitlist"
+			switch {
+			case errors.Is(err, shared.ErrFieldMissing):
+				status, msg = http.StatusBadRequest, "Missing field in request"
+			case errors.Is(err, shared.ErrDurationTooLong):
+				status, msg = http.StatusBadRequest, fmt.Sprintf("Max session duration is %s", h.Service.getGroupMaxDuration(req.ServerGroup))
+			case errors.Is(err, shared.ErrGroupAccessDenied):
+				status, msg = http.StatusForbidden, "Not allowed to use this server group"
+			case errors.Is(err, shared.ErrGroupNotInUse):
+				status, msg = http.StatusBadRequest, "Server group is not in use, start a session instead"
+			case errors.Is(err, shared.ErrAlreadyWaiting):
+				status, msg = http.StatusConflict, "Already on the waitlist for this server group"
+			}
+
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: msg})
+			return
+		}
+
+		h.Logger.Info("Joined waitlist", "user", email, "domain", "session", "server_group", entry.ServerGroup, "position", entry.Position)
+		json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: true, Data: entry})
+	}
+}
+
+func (h *Handler) LeaveWaitlist() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, email := ctxutil.GetActor(ctx)
+
+		var req WaitlistRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.Logger.Error("Malformed request", "user", email, "domain", "session", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Malformed request"})
+			return
+		}
+
+		req.UserID = userID
+
+		if err := h.Service.leaveWaitlist(req, ctx); err != nil {
+			h.Logger.Error("Failed to leave waitlist", "user", email, "domain", "session", "server_group", req.ServerGroup, "error", err)
+
+			status, msg := http.StatusInternalServerError, "Failed to leave waitlist"
+			switch {
+			case errors.Is(err, shared.ErrFieldMissing):
+				status, msg = http.StatusBadRequest, "Missing field in request"
+			case errors.Is(err, shared.ErrNoRowsDeleted):
+				status, msg = http.StatusNotFound, "Not on the waitlist for this server group"
+			}
+
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: msg})
+			return
+		}
+
+		h.Logger.Info("Left waitlist", "user", email, "domain", "session", "server_group", req.ServerGroup)
+		json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: true})
+	}
+}
+
+func (h *Handler) GetScheduledSessions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		_, email := ctxutil.GetActor(ctx)
+
+		bookings, err := h.Service.getScheduledSessions()
+		if err != nil {
+			h.Logger.Error("Failed to get scheduled sessions", "user", email, "domain", "session", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Failed to get scheduled sessions"})
+			return
+		}
+
+		json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: true, Data: bookings})
+	}
+}
+
+func (h *Handler) NewScheduledSession() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, email := ctxutil.GetActor(ctx)
+
+		var req ScheduledSessionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.Logger.Error("Malformed request", "user", email, "domain", "session", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Malformed request"})
+			return
+		}
+
+		req.UserID = userID
+
+		booking, err := h.Service.newScheduledSession(req, ctx)
+		if err != nil {
+			h.Logger.Error("Failed to create scheduled session", "user", email, "domain", "session", "server_group", req.ServerGroup, "error", err)
+			h.writeScheduleError(w, err, req.ServerGroup, "Failed to create scheduled session")
+			return
+		}
+
+		h.Logger.Info("Scheduled session created", "user", email, "domain", "session", "server_group", booking.ServerGroup)
+		json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: true, Data: booking})
+	}
+}
+
+func (h *Handler) UpdateScheduledSession() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, email := ctxutil.GetActor(ctx)
+
+		var req ScheduledSessionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.Logger.Error("Malformed request", "user", email, "domain", "session", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Malformed request"})
+			return
+		}
+
+		req.UserID = userID
+
+		booking, err := h.Service.updateScheduledSession(req, ctx)
+		if err != nil {
+			h.Logger.Error("Failed to update scheduled session", "user", email, "domain", "session", "id", req.ID, "error", err)
+			h.writeScheduleError(w, err, req.ServerGroup, "Failed to update scheduled session")
+			return
+		}
+
+		h.Logger.Info("Scheduled session updated", "user", email, "domain", "session", "server_group", booking.ServerGroup)
+		json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: true, Data: booking})
+	}
+}
+
+func (h *Handler) CancelScheduledSession() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, email := ctxutil.GetActor(ctx)
+
+		var req ScheduledSessionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.Logger.Error("Malformed request", "user", email, "domain", "session", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Malformed request"})
+			return
+		}
+
+		req.UserID = userID
+
+		if err := h.Service.cancelScheduledSession(req, ctx); err != nil {
+			h.Logger.Error("Failed to cancel scheduled session", "user", email, "domain", "session", "id", req.ID, "error", err)
+			h.writeScheduleError(w, err, req.ServerGroup, "Failed to cancel scheduled session")
+			return
+		}
+
+		h.Logger.Info("Scheduled session cancelled", "user", email, "domain", "session", "id", req.ID)
+		json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: true})
+	}
+}
+
+func (h *Handler) GetRecurringSchedules() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		_, email := ctxutil.GetActor(ctx)
+
+		schedules, err := h.Service.getRecurringSchedules()
+		if err != nil {
+			h.Logger.Error("Failed to get recurring schedules", "user", email, "domain", "session", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Failed to get recurring schedules"})
+			return
+		}
+
+		json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: true, Data: schedules})
+	}
+}
+
+func (h *Handler) NewRecurringSchedule() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		userID, email := ctxutil.GetActor(ctx)
+
+		var req RecurringScheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.Logger.Error("Malformed request", "user", email, "domain", "session", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Malformed request"})
+			return
+		}
+
+		req.UserID = userID
+
+		schedule, err := h.Service.newRecurringSchedule(req, ctx)
+		if err != nil {
+			h.Logger.Error("Failed to create recurring schedule", "user", email, "domain", "session", "server_group", req.ServerGroup, "error", err)
+
+			switch {
+			case errors.Is(err, shared.ErrFieldMissing):
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Missing field in request"})
+			case errors.Is(err, shared.ErrInvalidSchedule):
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: err.Error()})
+			case errors.Is(err, shared.ErrScheduleConflict):
+				w.WriteHeader(http.StatusConflict)
+				json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Server group is booked during this schedule"})
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Failed to create recurring schedule"})
+			}
+			return
+		}
+
+		h.Logger.Info("Recurring schedule created", "user", email, "domain", "session", "server_group", schedule.ServerGroup)
+		json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: true, Data: schedule})
+	}
+}
+
+func (h *Handler) DeleteRecurringSchedule() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		_, email := ctxutil.GetActor(ctx)
+
+		var req RecurringScheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.Logger.Error("Malformed request", "user", email, "domain", "session", "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Malformed request"})
+			return
+		}
+
+		if err := h.Service.deleteRecurringSchedule(req, ctx); err != nil {
+			h.Logger.Error("Failed to delete recurring schedule", "user", email, "domain", "session", "id", req.ID, "error", err)
+
+			if errors.Is(err, shared.ErrNoRowsDeleted) {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Failed to find recurring schedule"})
+				return
+			}
+
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: "Failed to delete recurring schedule"})
+			return
+		}
+
+		h.Logger.Info("Recurring schedule deleted", "user", email, "domain", "session", "id", req.ID)
+		json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: true})
+	}
+}
+
+// Map errors from creating, modifying or cancelling a booking to a response
+func (h *Handler) writeScheduleError(w http.ResponseWriter, err error, serverGroup string, fallback string) {
+	status := http.StatusInternalServerError
+	msg := fallback
+
+	switch {
+	case errors.Is(err, shared.ErrNoRowsUpdated), errors.Is(err, shared.ErrNoRowsDeleted):
+		status, msg = http.StatusNotFound, "Failed to find scheduled session"
+	case errors.Is(err, shared.ErrFieldMissing):
+		status, msg = http.StatusBadRequest, "Missing field in request"
+	case errors.Is(err, shared.ErrStartInPast):
+		status, msg = http.StatusBadRequest, "Start time must be in the future"
+	case errors.Is(err, shared.ErrDurationTooLong):
+		status, msg = http.StatusBadRequest, fmt.Sprintf("Max session duration is %s", h.Service.getGroupMaxDuration(serverGroup))
+	case errors.Is(err, shared.ErrGroupAccessDenied):
+		status, msg = http.StatusForbidden, "Not allowed to use this server group"
+	case errors.Is(err, shared.ErrScheduleConflict):
+		status, msg = http.StatusConflict, "Server group is already booked for that time"
+	}
+
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(shared.ApiResponse[any]{Success: false, Error: msg})
+}
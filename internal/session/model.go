@@ -5,6 +5,7 @@ import (
 	"ez2boot/internal/config"
 	"ez2boot/internal/db"
 	"ez2boot/internal/notification"
+	"ez2boot/internal/readiness"
 	"ez2boot/internal/server"
 	"ez2boot/internal/user"
 	"log/slog"
@@ -20,6 +21,7 @@ type Service struct {
 	Config              *config.Config
 	NotificationService *notification.Service
 	UserService         *user.Service
+	ReadinessService    *readiness.Service
 	Audit               *audit.Service
 	Logger              *slog.Logger
 }
@@ -52,15 +54,130 @@ type ServerSessionResponse struct {
 	Expiry      time.Time `json:"expiry"`
 }
 
+type ScheduledSessionRequest struct {
+	ID          int64     `json:"id"` // Required to modify or cancel
+	UserID      int64     `json:"-"`
+	ServerGroup string    `json:"server_group"`
+	Start       time.Time `json:"start"` // When servers are started, RFC 3339
+	Duration    string    `json:"duration"`
+	Expiry      int64     `json:"-"`
+}
+
+// A server session booked to start in the future
+type ScheduledSession struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"-"`
+	Email       string    `json:"email"`
+	ServerGroup string    `json:"server_group"`
+	Start       time.Time `json:"start"`
+	Duration    string    `json:"duration"`
+	Expiry      time.Time `json:"expiry"`
+}
+
+// Add or remove a co-owner, or hand a session over, by email
+type SessionOwnerRequest struct {
+	UserID      int64  `json:"-"`
+	ServerGroup string `json:"server_group"`
+	Email       string `json:"email"`
+}
+
+type WaitlistRequest struct {
+	UserID      int64  `json:"-"`
+	ServerGroup string `json:"server_group"`
+	Duration    string `json:"duration"`   // Required for auto_start, group default applies when empty
+	AutoStart   bool   `json:"auto_start"` // Start a session when the group is free rather than only notify
+}
+
+type WaitlistEntry struct {
+	ID          int64  `json:"id"`
+	UserID      int64  `json:"-"`
+	Email       string `json:"email"`
+	ServerGroup string `json:"server_group"`
+	Position    int    `json:"position"` // 1 is next
+	Duration    string `json:"duration"`
+	AutoStart   bool   `json:"auto_start"`
+	TimeAdded   int64  `json:"time_added"`
+	NotifiedAt  int64  `json:"notified_at"` // When the group was freed and held for the user, 0 while still waiting
+}
+
+type RecurringScheduleRequest struct {
+	ID          int64  `json:"id"` // Required to delete
+	UserID      int64  `json:"-"`
+	ServerGroup string `json:"server_group"`
+	Days        string `json:"days"`      // eg mon-fri or mon,wed,fri
+	Start       string `json:"start"`     // Local time of day, eg 08:00
+	End         string `json:"end"`       // Local time of day, earlier than start for overnight windows
+	TimeZone    string `json:"time_zone"` // IANA name, eg Europe/London
+}
+
+// A weekly time window which opens a session for a server group, owned by the admin who created it
+type RecurringSchedule struct {
+	ID          int64  `json:"id"`
+	UserID      int64  `json:"-"`
+	Email       string `json:"email"`
+	ServerGroup string `json:"server_group"`
+	Days        string `json:"days"`
+	Start       string `json:"start"`
+	End         string `json:"end"`
+	TimeZone    string `json:"time_zone"`
+	LastOpened  int64  `json:"last_opened"` // Start of the last window a session was opened for, 0 if never
+}
+
+// A recurring schedule parsed for working out windows
+type recurringWindow struct {
+	days     [7]bool // Indexed by time.Weekday
+	start    int     // Minutes past midnight
+	end      int     // Minutes past midnight
+	location *time.Location
+}
+
 type ServerInfo struct {
-	Name  string             `json:"name"`
-	State server.ServerState `json:"state"`
+	Name     string             `json:"name"`
+	State    server.ServerState `json:"state"`
+	Provider string             `json:"provider"`
+	Error    *string            `json:"error"` // Last failed start or stop, can be null
+	Metadata server.Metadata    `json:"metadata"`
 }
 
 type ServerSessionSummaryResponse struct {
-	ServerGroup string       `json:"server_group"`
-	ServerCount int64        `json:"server_count"`
-	Servers     []ServerInfo `json:"servers"`
-	CurrentUser *string      `json:"current_user"` // Can be null
-	Expiry      *int64       `json:"expiry"`       // Can be null
+	ServerGroup   string                  `json:"server_group"`
+	ServerCount   int64                   `json:"server_count"`
+	Servers       []ServerInfo            `json:"servers"`
+	CurrentUser   *string                 `json:"current_user"` // Can be null
+	Expiry        *int64                  `json:"expiry"`       // Can be null
+	Failed        bool                    `json:"failed"`
+	FailureReason *string                 `json:"failure_reason"` // Can be null
+	Orphan        bool                    `json:"orphan"`         // Adopted after servers were found running without a session
+	Drift         *Drift                  `json:"drift"`          // Servers running without a session, can be null
+	Scheduled     []ScheduledSession      `json:"scheduled"`      // Upcoming bookings, soonest first
+	Schedules     []RecurringSchedule     `json:"schedules"`      // Recurring windows which open sessions automatically
+	Waitlist      []WaitlistEntry         `json:"waitlist"`       // Users waiting for the group, next first
+	CoOwners      []string                `json:"co_owners"`      // Emails of users sharing the session
+	Metadata      server.GroupMetadata    `json:"metadata"`
+	Probes        []readiness.ProbeResult `json:"probes"` // Latest readiness probe results, empty if the group has none
+}
+
+// A server holding up a session, either out of attempts or stuck past the transition timeout
+type FailedServer struct {
+	UserID      int64
+	Email       string
+	ServerGroup string
+	Name        string
+	NextState   string
+	Attempts    int
+	LastError   *string
+}
+
+// A server group found running without a session
+type Drift struct {
+	Policy     string `json:"policy"`
+	DetectedAt int64  `json:"detected_at"`
+	Enforced   bool   `json:"enforced"` // Servers have been told to stop
+}
+
+// A server group running without a session, with any drift already recorded against it
+type DriftedServerGroup struct {
+	ServerGroup string
+	Servers     string // Names of running servers
+	Drift       *Drift // Nil when newly found
 }
@@ -0,0 +1,138 @@
+package session
+
+import (
+	"ez2boot/internal/shared"
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // Time zones for recurring schedules, the container image has none
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Parse the days, times and time zone of a recurring schedule
+func parseRecurringSchedule(schedule RecurringSchedule) (recurringWindow, error) {
+	var w recurringWindow
+	var err error
+
+	if w.days, err = parseDays(schedule.Days); err != nil {
+		return recurringWindow{}, err
+	}
+
+	if w.start, err = parseClock(schedule.Start); err != nil {
+		return recurringWindow{}, err
+	}
+
+	if w.end, err = parseClock(schedule.End); err != nil {
+		return recurringWindow{}, err
+	}
+
+	if w.start == w.end {
+		return recurringWindow{}, fmt.Errorf("%w: start and end are the same", shared.ErrInvalidSchedule)
+	}
+
+	if schedule.TimeZone == "" {
+		return recurringWindow{}, fmt.Errorf("%w: time zone missing", shared.ErrInvalidSchedule)
+	}
+
+	if w.location, err = time.LoadLocation(schedule.TimeZone); err != nil {
+		return recurringWindow{}, fmt.Errorf("%w: unknown time zone %s", shared.ErrInvalidSchedule, schedule.TimeZone)
+	}
+
+	return w, nil
+}
+
+// Parse days such as mon-fri, mon,wed,fri or sat-sun. Ranges may wrap, eg fri-mon
+func parseDays(value string) ([7]bool, error) {
+	var days [7]bool
+	found := false
+
+	for _, part := range strings.Split(strings.ToLower(value), ",") {
+		from, to, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		if !isRange {
+			to = from
+		}
+
+		first, ok := weekdays[strings.TrimSpace(from)]
+		last, ok2 := weekdays[strings.TrimSpace(to)]
+		if !ok || !ok2 {
+			return days, fmt.Errorf("%w: unknown day in %s", shared.ErrInvalidSchedule, value)
+		}
+
+		for d := first; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == last {
+				break
+			}
+		}
+		found = true
+	}
+
+	if !found {
+		return days, fmt.Errorf("%w: days missing", shared.ErrInvalidSchedule)
+	}
+
+	return days, nil
+}
+
+// Parse a 24 hour time of day, eg 08:00, into minutes past midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("%w: time of day must be HH:MM, got %s", shared.ErrInvalidSchedule, value)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Find the window open at the given time. A window belongs to the day it starts, so overnight windows are
+// checked from the previous day too
+func (w recurringWindow) openAt(now time.Time) (time.Time, time.Time, bool) {
+	local := now.In(w.location)
+
+	for _, offset := range []int{-1, 0} {
+		start, end, ok := w.windowOn(local.AddDate(0, 0, offset))
+		if ok && !now.Before(start) && now.Before(end) {
+			return start, end, true
+		}
+	}
+
+	return time.Time{}, time.Time{}, false
+}
+
+// Check whether any occurrence of the window overlaps a period, eg a booking
+func (w recurringWindow) overlaps(from time.Time, to time.Time) bool {
+	last := to.In(w.location)
+
+	for day := from.In(w.location).AddDate(0, 0, -1); !day.After(last); day = day.AddDate(0, 0, 1) {
+		start, end, ok := w.windowOn(day)
+		if ok && start.Before(to) && from.Before(end) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The occurrence of the window starting on the given day, if the window runs that day
+func (w recurringWindow) windowOn(day time.Time) (time.Time, time.Time, bool) {
+	if !w.days[day.Weekday()] {
+		return time.Time{}, time.Time{}, false
+	}
+
+	start := time.Date(day.Year(), day.Month(), day.Day(), w.start/60, w.start%60, 0, 0, w.location)
+	end := time.Date(day.Year(), day.Month(), day.Day(), w.end/60, w.end%60, 0, 0, w.location)
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+
+	return start, end, true
+}
@@ -2,10 +2,14 @@ package session
 
 import (
 	"database/sql"
+	"ez2boot/internal/readiness"
 	"ez2boot/internal/server"
 	"ez2boot/internal/shared"
 	"fmt"
+	"sort"
 	"time"
+
+	"github.com/mattn/go-sqlite3"
 )
 
 // Specialised query specifically for main UI table population
@@ -17,7 +21,7 @@ func (r *Repository) getServerSessionSummary() ([]ServerSessionSummaryResponse,
 	defer tx.Rollback()
 
 	// Get all servers with their group and state
-	serverQuery := `SELECT server_group, name, state FROM servers`
+	serverQuery := `SELECT server_group, name, state, provider, last_error, instance_type, private_ip, public_ip, zone, region, platform, launch_time FROM servers`
 	serverRows, err := tx.Query(serverQuery)
 	if err != nil {
 		return nil, err
@@ -27,18 +31,135 @@ func (r *Repository) getServerSessionSummary() ([]ServerSessionSummaryResponse,
 	// Map used for lookup only
 	serverMap := make(map[string][]ServerInfo)
 	for serverRows.Next() {
-		var group, name, state string
-		if err := serverRows.Scan(&group, &name, &state); err != nil {
+		var group, name, state, provider string
+		var lastError *string // can be null
+		var m server.Metadata
+		if err := serverRows.Scan(&group, &name, &state, &provider, &lastError, &m.InstanceType, &m.PrivateIP, &m.PublicIP, &m.Zone, &m.Region, &m.Platform, &m.LaunchTime); err != nil {
 			return nil, err
 		}
 		serverMap[group] = append(serverMap[group], ServerInfo{
-			Name:  name,
-			State: server.ServerState(state),
+			Name:     name,
+			State:    server.ServerState(state),
+			Provider: provider,
+			Error:    lastError,
+			Metadata: m,
 		})
 	}
 
+	// Companion tags per server group, groups may span providers
+	tagRows, err := tx.Query("SELECT server_group, name, value FROM server_group_tags ORDER BY provider")
+	if err != nil {
+		return nil, err
+	}
+	defer tagRows.Close()
+
+	tagMap := make(map[string]map[string]string)
+	for tagRows.Next() {
+		var group, name, value string
+		if err := tagRows.Scan(&group, &name, &value); err != nil {
+			return nil, err
+		}
+
+		if tagMap[group] == nil {
+			tagMap[group] = make(map[string]string)
+		}
+
+		if _, ok := tagMap[group][name]; !ok {
+			tagMap[group][name] = value
+		}
+	}
+
+	// Readiness probe results per server group
+	probeRows, err := tx.Query("SELECT server_group, probe, success, message, checked_at FROM probe_results ORDER BY server_group, position")
+	if err != nil {
+		return nil, err
+	}
+	defer probeRows.Close()
+
+	probeMap := make(map[string][]readiness.ProbeResult)
+	for probeRows.Next() {
+		var group string
+		var p readiness.ProbeResult
+		if err := probeRows.Scan(&group, &p.Probe, &p.Success, &p.Message, &p.CheckedAt); err != nil {
+			return nil, err
+		}
+
+		probeMap[group] = append(probeMap[group], p)
+	}
+
+	// Server groups running without a session
+	driftRows, err := tx.Query("SELECT server_group, policy, detected_at, enforced FROM server_drift")
+	if err != nil {
+		return nil, err
+	}
+	defer driftRows.Close()
+
+	driftMap := make(map[string]*Drift)
+	for driftRows.Next() {
+		var group string
+		var d Drift
+		if err := driftRows.Scan(&group, &d.Policy, &d.DetectedAt, &d.Enforced); err != nil {
+			return nil, err
+		}
+
+		driftMap[group] = &d
+	}
+
+	// Upcoming bookings per server group
+	scheduled, err := r.getScheduledSessions(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduledMap := make(map[string][]ScheduledSession)
+	for _, booking := range scheduled {
+		scheduledMap[booking.ServerGroup] = append(scheduledMap[booking.ServerGroup], booking)
+	}
+
+	// Co-owners per server group
+	coOwnerRows, err := tx.Query(`SELECT so.server_group, u.email
+								FROM session_owners so
+								JOIN users u ON so.user_id = u.id
+								ORDER BY so.time_added, u.email`)
+	if err != nil {
+		return nil, err
+	}
+	defer coOwnerRows.Close()
+
+	coOwnerMap := make(map[string][]string)
+	for coOwnerRows.Next() {
+		var group, email string
+		if err := coOwnerRows.Scan(&group, &email); err != nil {
+			return nil, err
+		}
+
+		coOwnerMap[group] = append(coOwnerMap[group], email)
+	}
+
+	// Waitlist per server group
+	waiting, err := r.getWaitlist(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	waitlistMap := make(map[string][]WaitlistEntry)
+	for _, entry := range waiting {
+		waitlistMap[entry.ServerGroup] = append(waitlistMap[entry.ServerGroup], entry)
+	}
+
+	// Recurring windows per server group
+	schedules, err := r.getRecurringSchedules(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduleMap := make(map[string][]RecurringSchedule)
+	for _, schedule := range schedules {
+		scheduleMap[schedule.ServerGroup] = append(scheduleMap[schedule.ServerGroup], schedule)
+	}
+
 	// Query session info per server group
-	sessionQuery := `SELECT s.server_group, MIN(u.email) AS current_user, MIN(ss.expiry) AS session_expiry
+	sessionQuery := `SELECT s.server_group, MIN(u.email) AS current_user, MIN(ss.expiry) AS session_expiry, COALESCE(MAX(ss.failed), 0) AS failed, MIN(ss.failure_reason) AS failure_reason, COALESCE(MAX(ss.orphan), 0) AS orphan
 					FROM servers AS s
 					LEFT JOIN server_sessions AS ss ON s.server_group = ss.server_group
 					LEFT JOIN users AS u ON ss.user_id = u.id
@@ -55,21 +176,68 @@ func (r *Repository) getServerSessionSummary() ([]ServerSessionSummaryResponse,
 		var group string
 		var currentUser *string // can be null
 		var expiry *int64       // can be null
+		var failed bool
+		var failureReason *string // can be null
+		var orphan bool
 
-		if err := sessionRows.Scan(&group, &currentUser, &expiry); err != nil {
+		if err := sessionRows.Scan(&group, &currentUser, &expiry, &failed, &failureReason, &orphan); err != nil {
 			return nil, err
 		}
 
 		servers := serverMap[group]
+		probes := probeMap[group]
+		if probes == nil {
+			probes = []readiness.ProbeResult{}
+		}
+
+		bookings := scheduledMap[group]
+		if bookings == nil {
+			bookings = []ScheduledSession{}
+		}
+
+		recurring := scheduleMap[group]
+		if recurring == nil {
+			recurring = []RecurringSchedule{}
+		}
+
+		waitlist := waitlistMap[group]
+		if waitlist == nil {
+			waitlist = []WaitlistEntry{}
+		}
+
+		coOwners := coOwnerMap[group]
+		if coOwners == nil {
+			coOwners = []string{}
+		}
+
 		summary = append(summary, ServerSessionSummaryResponse{
-			ServerGroup: group,
-			ServerCount: int64(len(servers)),
-			Servers:     servers,
-			CurrentUser: currentUser,
-			Expiry:      expiry,
+			ServerGroup:   group,
+			ServerCount:   int64(len(servers)),
+			Servers:       servers,
+			CurrentUser:   currentUser,
+			Expiry:        expiry,
+			Failed:        failed,
+			FailureReason: failureReason,
+			Orphan:        orphan,
+			Drift:         driftMap[group],
+			Scheduled:     bookings,
+			Schedules:     recurring,
+			Waitlist:      waitlist,
+			CoOwners:      coOwners,
+			Metadata:      server.NewGroupMetadata(tagMap[group]),
+			Probes:        probes,
 		})
 	}
 
+	// Groups with an order tag first, the rest stay alphabetical
+	sort.SliceStable(summary, func(i, j int) bool {
+		a, b := summary[i].Metadata.Order, summary[j].Metadata.Order
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return *a < *b
+	})
+
 	// No write, just releases trn
 	if err := tx.Commit(); err != nil {
 		return nil, err
@@ -78,6 +246,29 @@ func (r *Repository) getServerSessionSummary() ([]ServerSessionSummaryResponse,
 	return summary, nil
 }
 
+// Get the companion tags for a server group. Where providers disagree the first by provider name wins, matching the summary
+func (r *Repository) getGroupTags(serverGroup string) (map[string]string, error) {
+	rows, err := r.Base.DB.Query("SELECT name, value FROM server_group_tags WHERE server_group = $1 ORDER BY provider", serverGroup)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, err
+		}
+
+		if _, ok := tags[name]; !ok {
+			tags[name] = value
+		}
+	}
+
+	return tags, nil
+}
+
 // Get server sessions which will expire soon and user not yet notified
 func (r *Repository) getExpiringServerSessions() ([]ServerSession, error) {
 	now := time.Now().UTC()
@@ -131,47 +322,35 @@ func (r *Repository) getExpiredServerSessions() ([]ServerSession, error) {
 	return sessions, nil
 }
 
-// Create a new session
-func (r *Repository) newServerSession(session ServerSessionRequest) error {
-	tx, err := r.Base.DB.Begin()
-	if err != nil {
-		return err
-	}
-
+// Set the group's servers on and insert the session
+func (r *Repository) startServerSession(tx *sql.Tx, session ServerSessionRequest) error {
 	// Set server table for state worker
-	result, err := tx.Exec("UPDATE servers SET next_state = $1, time_last_on = $2, last_user_id = $3 WHERE server_group = $4", "on", time.Now().Unix(), session.UserID, session.ServerGroup)
+	// Each request gets a fresh set of attempts
+	result, err := tx.Exec("UPDATE servers SET next_state = $1, time_last_on = $2, last_user_id = $3, attempts = 0, next_attempt = 0 WHERE server_group = $4", "on", time.Now().Unix(), session.UserID, session.ServerGroup)
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
 
 	// Impact check
 	rows, err := result.RowsAffected()
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
 
 	if rows == 0 {
-		tx.Rollback()
 		return fmt.Errorf("no servers found for server_group: %s", session.ServerGroup)
 	}
 
 	if _, err = tx.Exec("INSERT INTO server_sessions (user_id, server_group, expiry, warning_notified, on_notified) VALUES ($1, $2, $3, $4, $5)", session.UserID, session.ServerGroup, session.Expiry, 0, 0); err != nil {
-		tx.Rollback()
-		return err
-	}
-
-	if err = tx.Commit(); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// Update existing session
-func (r *Repository) updateServerSession(session ServerSessionRequest) error {
-	result, err := r.Base.DB.Exec("UPDATE server_sessions SET expiry = $1, warning_notified = $2 WHERE server_group = $3 AND user_id = $4 AND expiry > $5", session.Expiry, 0, session.ServerGroup, session.UserID, time.Now().Unix())
+// Update existing session. Co-owners may extend it too, and sessions opened by a recurring schedule are shared with everyone
+func (r *Repository) updateServerSession(tx *sql.Tx, session ServerSessionRequest) error {
+	result, err := tx.Exec("UPDATE server_sessions SET expiry = $1, warning_notified = $2 WHERE server_group = $3 AND "+sessionUserFilter("$4")+" AND expiry > $5", session.Expiry, 0, session.ServerGroup, session.UserID, time.Now().Unix())
 	if err != nil {
 		return err
 	}
@@ -209,15 +388,58 @@ func (r *Repository) updateServerSessionAdmin(session ServerSessionRequest) erro
 	return nil
 }
 
+// End a running session now. A userID limits it to sessions the user owns or shares, 0 ends any session
+func (r *Repository) endServerSessionEarly(serverGroup string, userID int64) error {
+	tx, err := r.Base.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Expiry is brought forward so the dashboard shows the real end, the warning is no longer needed
+	query := "UPDATE server_sessions SET expiry = $1, warning_notified = 1 WHERE server_group = $2 AND to_cleanup = 0"
+	args := []any{time.Now().Unix(), serverGroup}
+	if userID != 0 {
+		query += " AND " + sessionUserFilter("$3")
+		args = append(args, userID)
+	}
+
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	// Impact check
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return shared.ErrNoRowsUpdated
+	}
+
+	if err := r.endServerSession(tx, serverGroup); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Match server_sessions rows the user owns, co-owns, or which were opened by a recurring schedule
+func sessionUserFilter(param string) string {
+	return "(user_id = " + param + " OR schedule_id IS NOT NULL OR EXISTS (SELECT 1 FROM session_owners so WHERE so.server_group = server_sessions.server_group AND so.user_id = " + param + "))"
+}
+
 // Set servers next_state off and mark session for cleanup
 func (r *Repository) endServerSession(tx *sql.Tx, serverGroup string) error {
 	// Set server next state
-	if _, err := tx.Exec("UPDATE servers SET next_state = $1, time_last_off = $2 WHERE server_group = $3", "off", time.Now().Unix(), serverGroup); err != nil {
+	if _, err := tx.Exec("UPDATE servers SET next_state = $1, time_last_off = $2, attempts = 0, next_attempt = 0 WHERE server_group = $3", "off", time.Now().Unix(), serverGroup); err != nil {
 		return err
 	}
 
-	// Set cleanup flag on session
-	if _, err := tx.Exec("UPDATE server_sessions SET to_cleanup = $1 WHERE server_group = $2", 1, serverGroup); err != nil {
+	// Set cleanup flag on session, stopping is tracked afresh for failures
+	if _, err := tx.Exec("UPDATE server_sessions SET to_cleanup = $1, failed = 0, failure_reason = NULL WHERE server_group = $2", 1, serverGroup); err != nil {
 		return err
 	}
 
@@ -238,6 +460,16 @@ func (r *Repository) cleanupServerSession(tx *sql.Tx, session ServerSession) err
 		return err
 	}
 
+	// Co-owners only share the session which is ending
+	if _, err := tx.Exec("DELETE FROM session_owners WHERE server_group = $1", session.ServerGroup); err != nil {
+		return err
+	}
+
+	// Probe results only describe the session which is ending
+	if _, err := tx.Exec("DELETE FROM probe_results WHERE server_group = $1", session.ServerGroup); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -373,6 +605,613 @@ func (r *Repository) getFinalisedServerSessions() ([]ServerSession, error) {
 	return sessionsForAction, nil
 }
 
+// Find servers holding up a session which has not yet been flagged failed. A server is stuck when it is out of attempts,
+// or has not reached its next state since it was requested before the cutoff. A zero max attempts or cutoff disables that check
+func (r *Repository) getFailedServers(maxAttempts int, cutoff int64) ([]FailedServer, error) {
+	query := `SELECT u.id, u.email, ss.server_group, srv.name, srv.next_state, srv.attempts, srv.last_error
+			FROM server_sessions ss
+			JOIN users u ON ss.user_id = u.id
+			JOIN servers srv ON srv.server_group = ss.server_group
+			WHERE ss.failed = 0 AND srv.next_state IS NOT NULL AND srv.state != srv.next_state
+			AND (($1 > 0 AND srv.attempts >= $1)
+			OR ($2 > 0 AND ((srv.next_state = 'on' AND srv.time_last_on < $2) OR (srv.next_state = 'off' AND srv.time_last_off < $2))))
+			ORDER BY ss.server_group, srv.name`
+
+	rows, err := r.Base.DB.Query(query, maxAttempts, cutoff)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	failed := []FailedServer{}
+
+	for rows.Next() {
+		var f FailedServer
+		if err = rows.Scan(&f.UserID, &f.Email, &f.ServerGroup, &f.Name, &f.NextState, &f.Attempts, &f.LastError); err != nil {
+			return nil, err
+		}
+
+		failed = append(failed, f)
+	}
+
+	return failed, nil
+}
+
+// Flag a session as failed with the reason shown to users - called with notification queuing so runs as a transaction
+func (r *Repository) setFailed(tx *sql.Tx, reason string, serverGroup string) error {
+	_, err := tx.Exec("UPDATE server_sessions SET failed = 1, failure_reason = $1 WHERE server_group = $2", reason, serverGroup)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Find server groups with servers on but no session, along with any drift already recorded
+func (r *Repository) getDriftedServerGroups() ([]DriftedServerGroup, error) {
+	query := `SELECT srv.server_group, GROUP_CONCAT(srv.name, ', '), MIN(d.policy), MIN(d.detected_at), MIN(d.enforced)
+			FROM servers srv
+			LEFT JOIN server_drift d ON d.server_group = srv.server_group
+			WHERE srv.state = 'on'
+			AND NOT EXISTS (
+			SELECT 1
+			FROM server_sessions s
+			WHERE s.server_group = srv.server_group)
+			GROUP BY srv.server_group
+			ORDER BY srv.server_group`
+
+	rows, err := r.Base.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	drifted := []DriftedServerGroup{}
+
+	for rows.Next() {
+		var g DriftedServerGroup
+		var policy *string
+		var detectedAt *int64
+		var enforced *bool
+
+		if err = rows.Scan(&g.ServerGroup, &g.Servers, &policy, &detectedAt, &enforced); err != nil {
+			return nil, err
+		}
+
+		if policy != nil {
+			g.Drift = &Drift{Policy: *policy, DetectedAt: *detectedAt, Enforced: *enforced}
+		}
+
+		drifted = append(drifted, g)
+	}
+
+	return drifted, nil
+}
+
+// Find recorded drift where the group has since stopped or been taken into a session
+func (r *Repository) getResolvedDrift() ([]DriftedServerGroup, error) {
+	query := `SELECT d.server_group, d.policy, d.detected_at, d.enforced
+			FROM server_drift d
+			WHERE EXISTS (
+			SELECT 1
+			FROM server_sessions s
+			WHERE s.server_group = d.server_group)
+			OR NOT EXISTS (
+			SELECT 1
+			FROM servers srv
+			WHERE srv.server_group = d.server_group
+			AND srv.state = 'on')`
+
+	rows, err := r.Base.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resolved := []DriftedServerGroup{}
+
+	for rows.Next() {
+		var d Drift
+		g := DriftedServerGroup{Drift: &d}
+
+		if err = rows.Scan(&g.ServerGroup, &d.Policy, &d.DetectedAt, &d.Enforced); err != nil {
+			return nil, err
+		}
+
+		resolved = append(resolved, g)
+	}
+
+	return resolved, nil
+}
+
+// Record a server group found running without a session
+func (r *Repository) newDrift(tx *sql.Tx, serverGroup string, policy string, detectedAt int64) error {
+	_, err := tx.Exec("INSERT INTO server_drift (server_group, policy, detected_at) VALUES ($1, $2, $3)", serverGroup, policy, detectedAt)
+	return err
+}
+
+// Tell the drifted group's servers to stop. Stopped servers are released once the drift is resolved
+func (r *Repository) enforceDrift(tx *sql.Tx, serverGroup string) error {
+	if _, err := tx.Exec("UPDATE servers SET next_state = $1, time_last_off = $2, attempts = 0, next_attempt = 0 WHERE server_group = $3", "off", time.Now().Unix(), serverGroup); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE server_drift SET enforced = 1 WHERE server_group = $1", serverGroup); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Remove recorded drift. Without a session the group's servers no longer have a next state
+func (r *Repository) resolveDrift(tx *sql.Tx, serverGroup string) error {
+	if _, err := tx.Exec("DELETE FROM server_drift WHERE server_group = $1", serverGroup); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE servers SET next_state = NULL WHERE server_group = $1 AND NOT EXISTS (SELECT 1 FROM server_sessions WHERE server_group = $1)", serverGroup); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Take running servers into a session. Servers which are off are left alone rather than started
+func (r *Repository) adoptServerGroup(tx *sql.Tx, userID int64, serverGroup string, expiry int64) error {
+	if _, err := tx.Exec("INSERT INTO server_sessions (user_id, server_group, expiry, on_notified, orphan) VALUES ($1, $2, $3, $4, $5)", userID, serverGroup, expiry, 1, 1); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE servers SET next_state = $1, time_last_on = $2, attempts = 0, next_attempt = 0 WHERE server_group = $3 AND state = $1", "on", time.Now().Unix(), serverGroup); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Get upcoming bookings, soonest first. Takes a transaction so the summary reads consistently
+func (r *Repository) getScheduledSessions(tx *sql.Tx) ([]ScheduledSession, error) {
+	rows, err := tx.Query(`SELECT sc.id, sc.user_id, u.email, sc.server_group, sc.start_time, sc.duration, sc.expiry
+						FROM scheduled_sessions sc
+						JOIN users u ON sc.user_id = u.id
+						ORDER BY sc.start_time, sc.id`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	return scanScheduledSessions(rows)
+}
+
+// Get bookings which are due to start
+func (r *Repository) getDueScheduledSessions(now int64) ([]ScheduledSession, error) {
+	rows, err := r.Base.DB.Query(`SELECT sc.id, sc.user_id, u.email, sc.server_group, sc.start_time, sc.duration, sc.expiry
+								FROM scheduled_sessions sc
+								JOIN users u ON sc.user_id = u.id
+								WHERE sc.start_time <= $1
+								ORDER BY sc.start_time, sc.id`, now)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	return scanScheduledSessions(rows)
+}
+
+func scanScheduledSessions(rows *sql.Rows) ([]ScheduledSession, error) {
+	bookings := []ScheduledSession{}
+
+	for rows.Next() {
+		var b ScheduledSession
+		var start, expiry int64
+		if err := rows.Scan(&b.ID, &b.UserID, &b.Email, &b.ServerGroup, &start, &b.Duration, &expiry); err != nil {
+			return nil, err
+		}
+
+		b.Start = time.Unix(start, 0).UTC()
+		b.Expiry = time.Unix(expiry, 0).UTC()
+		bookings = append(bookings, b)
+	}
+
+	return bookings, nil
+}
+
+// Get the owner and server group of a booking
+func (r *Repository) getScheduledSessionOwner(id int64) (int64, string, error) {
+	var userID int64
+	var serverGroup string
+	err := r.Base.DB.QueryRow("SELECT user_id, server_group FROM scheduled_sessions WHERE id = $1", id).Scan(&userID, &serverGroup)
+	if err == sql.ErrNoRows {
+		return 0, "", shared.ErrNoRowsUpdated
+	}
+
+	return userID, serverGroup, err
+}
+
+// Check whether a period overlaps another booking for the server group, ignoring the booking being modified
+func (r *Repository) hasBookingConflict(tx *sql.Tx, serverGroup string, start int64, expiry int64, excludeID int64) (bool, error) {
+	var conflict bool
+	err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM scheduled_sessions WHERE server_group = $1 AND expiry > $2 AND start_time < $3 AND id <> $4)", serverGroup, start, expiry, excludeID).Scan(&conflict)
+	return conflict, err
+}
+
+// Get the start of the earliest booking for the server group overlapping a period, 0 when there is none
+func (r *Repository) getNextBookingStart(tx *sql.Tx, serverGroup string, from int64, to int64) (int64, error) {
+	var start int64
+	err := tx.QueryRow("SELECT COALESCE(MIN(start_time), 0) FROM scheduled_sessions WHERE server_group = $1 AND start_time < $2 AND expiry > $3", serverGroup, to, from).Scan(&start)
+	return start, err
+}
+
+// Check whether the server group has a session still running at the given time
+func (r *Repository) hasSessionAt(tx *sql.Tx, serverGroup string, at int64) (bool, error) {
+	var conflict bool
+	err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM server_sessions WHERE server_group = $1 AND to_cleanup = 0 AND expiry > $2)", serverGroup, at).Scan(&conflict)
+	return conflict, err
+}
+
+// Check whether the server group has any session, including one still shutting down
+func (r *Repository) hasSession(serverGroup string) (bool, error) {
+	var exists bool
+	err := r.Base.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM server_sessions WHERE server_group = $1)", serverGroup).Scan(&exists)
+	return exists, err
+}
+
+func (r *Repository) hasServers(serverGroup string) (bool, error) {
+	var exists bool
+	err := r.Base.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM servers WHERE server_group = $1)", serverGroup).Scan(&exists)
+	return exists, err
+}
+
+func (r *Repository) newScheduledSession(tx *sql.Tx, booking ScheduledSessionRequest) (int64, error) {
+	result, err := tx.Exec("INSERT INTO scheduled_sessions (user_id, server_group, start_time, duration, expiry, time_added) VALUES ($1, $2, $3, $4, $5, $6)", booking.UserID, booking.ServerGroup, booking.Start.Unix(), booking.Duration, booking.Expiry, time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (r *Repository) updateScheduledSession(tx *sql.Tx, booking ScheduledSessionRequest) error {
+	result, err := tx.Exec("UPDATE scheduled_sessions SET server_group = $1, start_time = $2, duration = $3, expiry = $4 WHERE id = $5", booking.ServerGroup, booking.Start.Unix(), booking.Duration, booking.Expiry, booking.ID)
+	if err != nil {
+		return err
+	}
+
+	// Impact check
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return shared.ErrNoRowsUpdated
+	}
+
+	return nil
+}
+
+// Delete a booking, either cancelled or started - runs as a transaction when started
+func (r *Repository) deleteScheduledSession(tx *sql.Tx, id int64) error {
+	result, err := tx.Exec("DELETE FROM scheduled_sessions WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	// Impact check
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return shared.ErrNoRowsDeleted
+	}
+
+	return nil
+}
+
+// Get all recurring schedules. Takes a transaction so the summary reads consistently
+func (r *Repository) getRecurringSchedules(tx *sql.Tx) ([]RecurringSchedule, error) {
+	rows, err := tx.Query(`SELECT rs.id, rs.user_id, u.email, rs.server_group, rs.days, rs.start_time, rs.end_time, rs.time_zone, rs.last_opened
+						FROM recurring_schedules rs
+						JOIN users u ON rs.user_id = u.id
+						ORDER BY rs.server_group, rs.id`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	schedules := []RecurringSchedule{}
+	for rows.Next() {
+		var rs RecurringSchedule
+		if err := rows.Scan(&rs.ID, &rs.UserID, &rs.Email, &rs.ServerGroup, &rs.Days, &rs.Start, &rs.End, &rs.TimeZone, &rs.LastOpened); err != nil {
+			return nil, err
+		}
+
+		schedules = append(schedules, rs)
+	}
+
+	return schedules, nil
+}
+
+func (r *Repository) newRecurringSchedule(tx *sql.Tx, schedule RecurringScheduleRequest) (int64, error) {
+	result, err := tx.Exec("INSERT INTO recurring_schedules (user_id, server_group, days, start_time, end_time, time_zone, time_added) VALUES ($1, $2, $3, $4, $5, $6, $7)", schedule.UserID, schedule.ServerGroup, schedule.Days, schedule.Start, schedule.End, schedule.TimeZone, time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// Delete a recurring schedule and return its server group. A session it already opened runs to its expiry
+func (r *Repository) deleteRecurringSchedule(id int64) (string, error) {
+	var serverGroup string
+	err := r.Base.DB.QueryRow("DELETE FROM recurring_schedules WHERE id = $1 RETURNING server_group", id).Scan(&serverGroup)
+	if err == sql.ErrNoRows {
+		return "", shared.ErrNoRowsDeleted
+	}
+
+	return serverGroup, err
+}
+
+// Open a session for a recurring window and record the window so it opens once only
+func (r *Repository) openRecurringSession(tx *sql.Tx, session ServerSessionRequest, scheduleID int64, windowStart int64) error {
+	if err := r.startServerSession(tx, session); err != nil {
+		return err
+	}
+
+	// Nobody asked for the servers, so skip the ready notification
+	if _, err := tx.Exec("UPDATE server_sessions SET schedule_id = $1, on_notified = 1 WHERE server_group = $2", scheduleID, session.ServerGroup); err != nil {
+		return err
+	}
+
+	return r.setRecurringScheduleOpened(tx, scheduleID, windowStart)
+}
+
+// Record the start of the latest window for a recurring schedule
+func (r *Repository) setRecurringScheduleOpened(tx *sql.Tx, id int64, windowStart int64) error {
+	if _, err := tx.Exec("UPDATE recurring_schedules SET last_opened = $1 WHERE id = $2", windowStart, id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Get the owner of a running session
+func (r *Repository) getServerSessionOwner(serverGroup string) (int64, error) {
+	var userID int64
+	err := r.Base.DB.QueryRow("SELECT user_id FROM server_sessions WHERE server_group = $1 AND to_cleanup = 0", serverGroup).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, shared.ErrNoRowsUpdated
+	}
+
+	return userID, err
+}
+
+// Get the co-owners of a session - runs as a transaction with notification queuing
+func (r *Repository) getCoOwnerIDs(tx *sql.Tx, serverGroup string) ([]int64, error) {
+	rows, err := tx.Query("SELECT user_id FROM session_owners WHERE server_group = $1 ORDER BY time_added, user_id", serverGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (r *Repository) addCoOwner(tx *sql.Tx, serverGroup string, userID int64) error {
+	if _, err := tx.Exec("INSERT INTO session_owners (server_group, user_id, time_added) VALUES ($1, $2, $3)", serverGroup, userID, time.Now().Unix()); err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+			return shared.ErrAlreadyOwner
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) removeCoOwner(serverGroup string, userID int64) error {
+	result, err := r.Base.DB.Exec("DELETE FROM session_owners WHERE server_group = $1 AND user_id = $2", serverGroup, userID)
+	if err != nil {
+		return err
+	}
+
+	// Impact check
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return shared.ErrNoRowsDeleted
+	}
+
+	return nil
+}
+
+// Make another user the owner of a running session. A co-owner taking over is no longer listed as one
+func (r *Repository) handOverServerSession(tx *sql.Tx, serverGroup string, userID int64) error {
+	result, err := tx.Exec("UPDATE server_sessions SET user_id = $1 WHERE server_group = $2 AND to_cleanup = 0", userID, serverGroup)
+	if err != nil {
+		return err
+	}
+
+	// Impact check
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return shared.ErrNoRowsUpdated
+	}
+
+	if _, err := tx.Exec("DELETE FROM session_owners WHERE server_group = $1 AND user_id = $2", serverGroup, userID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Get the waitlist for every server group, next first. Takes a transaction so the summary reads consistently
+func (r *Repository) getWaitlist(tx *sql.Tx) ([]WaitlistEntry, error) {
+	rows, err := tx.Query(`SELECT w.id, w.user_id, u.email, w.server_group, w.duration, w.auto_start, w.time_added, w.notified_at
+						FROM waitlist w
+						JOIN users u ON w.user_id = u.id
+						ORDER BY w.server_group, w.id`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	entries := []WaitlistEntry{}
+	positions := make(map[string]int)
+	for rows.Next() {
+		var e WaitlistEntry
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Email, &e.ServerGroup, &e.Duration, &e.AutoStart, &e.TimeAdded, &e.NotifiedAt); err != nil {
+			return nil, err
+		}
+
+		positions[e.ServerGroup]++
+		e.Position = positions[e.ServerGroup]
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// Get the next user waiting for each server group which has no session, including one still shutting down
+func (r *Repository) getFreeWaitlistEntries() ([]WaitlistEntry, error) {
+	rows, err := r.Base.DB.Query(`SELECT w.id, w.user_id, u.email, w.server_group, w.duration, w.auto_start, w.time_added, w.notified_at
+								FROM waitlist w
+								JOIN users u ON w.user_id = u.id
+								WHERE w.id = (SELECT MIN(id) FROM waitlist WHERE server_group = w.server_group)
+								AND NOT EXISTS (SELECT 1 FROM server_sessions ss WHERE ss.server_group = w.server_group)
+								ORDER BY w.id`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	entries := []WaitlistEntry{}
+	for rows.Next() {
+		e := WaitlistEntry{Position: 1}
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Email, &e.ServerGroup, &e.Duration, &e.AutoStart, &e.TimeAdded, &e.NotifiedAt); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// Add a user to the end of the waitlist for a server group
+func (r *Repository) newWaitlistEntry(req WaitlistRequest) (int64, error) {
+	result, err := r.Base.DB.Exec("INSERT INTO waitlist (user_id, server_group, duration, auto_start, time_added) VALUES ($1, $2, $3, $4, $5)", req.UserID, req.ServerGroup, req.Duration, req.AutoStart, time.Now().Unix())
+	if err != nil {
+		if sqliteErr, ok := err.(sqlite3.Error); ok && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return 0, shared.ErrAlreadyWaiting
+		}
+
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// Get the position of a waitlist entry, 1 is next
+func (r *Repository) getWaitlistPosition(id int64) (int, error) {
+	var position int
+	err := r.Base.DB.QueryRow("SELECT COUNT(*) FROM waitlist WHERE server_group = (SELECT server_group FROM waitlist WHERE id = $1) AND id <= $1", id).Scan(&position)
+	return position, err
+}
+
+// Remove a user from the waitlist for a server group
+func (r *Repository) deleteWaitlistEntry(userID int64, serverGroup string) error {
+	result, err := r.Base.DB.Exec("DELETE FROM waitlist WHERE user_id = $1 AND server_group = $2", userID, serverGroup)
+	if err != nil {
+		return err
+	}
+
+	// Impact check
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return shared.ErrNoRowsDeleted
+	}
+
+	return nil
+}
+
+// Check whether anyone is waiting for the server group
+func (r *Repository) hasWaitlist(serverGroup string) (bool, error) {
+	var exists bool
+	err := r.Base.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM waitlist WHERE server_group = $1)", serverGroup).Scan(&exists)
+	return exists, err
+}
+
+// Get the user the server group is held for, notified after the cutoff. 0 when the group is not held
+func (r *Repository) getWaitlistHold(tx *sql.Tx, serverGroup string, cutoff int64) (int64, error) {
+	var userID int64
+	err := tx.QueryRow("SELECT user_id FROM waitlist WHERE server_group = $1 AND notified_at > $2 ORDER BY id LIMIT 1", serverGroup, cutoff).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+
+	return userID, err
+}
+
+// Hold the server group for a notified waitlist entry - called with notification queuing so runs as a transaction
+func (r *Repository) setWaitlistNotified(tx *sql.Tx, id int64, notifiedAt int64) error {
+	if _, err := tx.Exec("UPDATE waitlist SET notified_at = $1 WHERE id = $2", notifiedAt, id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Remove the user's entry once they hold a session for the group, if they were waiting
+func (r *Repository) claimWaitlistEntry(tx *sql.Tx, userID int64, serverGroup string) error {
+	if _, err := tx.Exec("DELETE FROM waitlist WHERE user_id = $1 AND server_group = $2", userID, serverGroup); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Remove a served waitlist entry - runs as a transaction with the session start and notification
+func (r *Repository) removeWaitlistEntry(tx *sql.Tx, id int64) error {
+	if _, err := tx.Exec("DELETE FROM waitlist WHERE id = $1", id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // Set warning notified flag - called with notification queuing so runs as a transaction
 func (r *Repository) setWarningNotifiedFlag(tx *sql.Tx, flagValue int, serverGroup string) error {
 	_, err := tx.Exec("UPDATE server_sessions SET warning_notified = $1 WHERE server_group = $2", flagValue, serverGroup)
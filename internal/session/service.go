@@ -2,14 +2,38 @@ package session
 
 import (
 	"context"
+	"database/sql"
 	"ez2boot/internal/audit"
 	"ez2boot/internal/ctxutil"
 	"ez2boot/internal/notification"
+	"ez2boot/internal/server"
+	"ez2boot/internal/shared"
 	"ez2boot/internal/util"
 	"fmt"
+	"strings"
 	"time"
 )
 
+// Get the session policy for a server group from its companion tags
+func (s *Service) getGroupPolicy(serverGroup string) (server.GroupPolicy, error) {
+	tags, err := s.Repo.getGroupTags(serverGroup)
+	if err != nil {
+		return server.GroupPolicy{}, err
+	}
+
+	return server.NewGroupMetadata(tags).Policy, nil
+}
+
+// Max duration for a server group, used in error messages. Falls back to the global max if the policy cannot be read
+func (s *Service) getGroupMaxDuration(serverGroup string) time.Duration {
+	policy, err := s.getGroupPolicy(serverGroup)
+	if err != nil {
+		s.Logger.Error("Failed to get server group policy", "domain", "session", "server_group", serverGroup, "error", err)
+	}
+
+	return s.getMaxDuration(policy)
+}
+
 func (s *Service) getServerSessionSummary() ([]ServerSessionSummaryResponse, error) {
 	summary, err := s.Repo.getServerSessionSummary()
 	if err != nil {
@@ -42,7 +66,16 @@ func (s *Service) newServerSession(session ServerSessionRequest, ctx context.Con
 		})
 	}()
 
-	if err := s.validateServerSession(session); err != nil {
+	policy, err := s.getGroupPolicy(session.ServerGroup)
+	if err != nil {
+		return ServerSessionResponse{}, err
+	}
+
+	if err := s.validateServerSession(&session, policy); err != nil {
+		return ServerSessionResponse{}, err
+	}
+
+	if err := s.validateGroupAccess(session.UserID, policy); err != nil {
 		return ServerSessionResponse{}, err
 	}
 
@@ -52,11 +85,39 @@ func (s *Service) newServerSession(session ServerSessionRequest, ctx context.Con
 		return ServerSessionResponse{}, err
 	}
 
+	tx, err := s.Repo.Base.DB.Begin()
+	if err != nil {
+		return ServerSessionResponse{}, err
+	}
+	defer tx.Rollback()
+
+	// A group freed for the next user on the waitlist is theirs until the claim window passes
+	heldFor, err := s.Repo.getWaitlistHold(tx, session.ServerGroup, time.Now().Add(-s.Config.WaitlistClaimWindow).Unix())
+	if err != nil {
+		return ServerSessionResponse{}, err
+	}
+
+	if heldFor != 0 && heldFor != session.UserID {
+		return ServerSessionResponse{}, shared.ErrGroupHeld
+	}
+
+	// Must end before the next booking, checked in the same transaction as the insert
+	if err := s.validateSchedule(tx, session.ServerGroup, time.Now().Unix(), sessionExpiry, 0, false); err != nil {
+		return ServerSessionResponse{}, err
+	}
+
 	// Add expiry
 	session.Expiry = sessionExpiry
 
-	err = s.Repo.newServerSession(session)
-	if err != nil {
+	if err := s.Repo.startServerSession(tx, session); err != nil {
+		return ServerSessionResponse{}, err
+	}
+
+	if err := s.Repo.claimWaitlistEntry(tx, session.UserID, session.ServerGroup); err != nil {
+		return ServerSessionResponse{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
 		return ServerSessionResponse{}, err
 	}
 
@@ -90,7 +151,17 @@ func (s *Service) updateServerSession(session ServerSessionRequest, ctx context.
 		})
 	}()
 
-	if err := s.validateServerSession(session); err != nil {
+	policy, err := s.getGroupPolicy(session.ServerGroup)
+	if err != nil {
+		return ServerSessionResponse{}, err
+	}
+
+	if err := s.validateServerSession(&session, policy); err != nil {
+		return ServerSessionResponse{}, err
+	}
+
+	// Policy may have changed since the session started
+	if err := s.validateGroupAccess(session.UserID, policy); err != nil {
 		return ServerSessionResponse{}, err
 	}
 
@@ -100,11 +171,25 @@ func (s *Service) updateServerSession(session ServerSessionRequest, ctx context.
 		return ServerSessionResponse{}, err
 	}
 
+	tx, err := s.Repo.Base.DB.Begin()
+	if err != nil {
+		return ServerSessionResponse{}, err
+	}
+	defer tx.Rollback()
+
+	// Extensions must end before the next booking, admins may override
+	if err := s.validateSchedule(tx, session.ServerGroup, time.Now().Unix(), newExpiry, 0, false); err != nil {
+		return ServerSessionResponse{}, err
+	}
+
 	// Add expiry
 	session.Expiry = newExpiry
 
-	err = s.Repo.updateServerSession(session)
-	if err != nil {
+	if err := s.Repo.updateServerSession(tx, session); err != nil {
+		return ServerSessionResponse{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
 		return ServerSessionResponse{}, err
 	}
 
@@ -138,7 +223,13 @@ func (s *Service) updateServerSessionAdmin(session ServerSessionRequest, ctx con
 		})
 	}()
 
-	if err := s.validateServerSession(session); err != nil {
+	// Admins are not bound by allowed users, duration limits still apply
+	policy, err := s.getGroupPolicy(session.ServerGroup)
+	if err != nil {
+		return ServerSessionResponse{}, err
+	}
+
+	if err := s.validateServerSession(&session, policy); err != nil {
 		return ServerSessionResponse{}, err
 	}
 
@@ -163,177 +254,1619 @@ func (s *Service) updateServerSessionAdmin(session ServerSessionRequest, ctx con
 	}, nil
 }
 
-// High level for processing server sessions in each state - called by go routine worker
-func (s *Service) ProcessServerSessions(ctx context.Context) {
-	// Ready-for-use sessions
-	if err := s.processReadyServerSessions(ctx); err != nil {
-		s.Logger.Error("Failed to process ready server sessions", "domain", "session", "error", err)
-	}
+// End a session before its expiry, servers are stopped by the usual terminated and finalised flow
+func (s *Service) endServerSession(session ServerSessionRequest, ctx context.Context) error {
+	return s.endServerSessionEarly(session, session.UserID, ctx)
+}
 
-	// Expiring sessions
-	if err := s.processExpiringServerSessions(ctx); err != nil {
-		s.Logger.Error("Failed to process expiring server sessions", "domain", "session", "error", err)
-	}
+// End any session before its expiry
+func (s *Service) endServerSessionAdmin(session ServerSessionRequest, ctx context.Context) error {
+	return s.endServerSessionEarly(session, 0, ctx)
+}
 
-	// Expired sessions
-	if err := s.processExpiredServerSessions(ctx); err != nil {
-		s.Logger.Error("Failed to process expired server sessions", "domain", "session", "error", err)
-	}
+func (s *Service) endServerSessionEarly(session ServerSessionRequest, ownerID int64, ctx context.Context) (err error) {
+	actorUserID, actorEmail := ctxutil.GetActor(ctx)
 
-	// Terminated sessions
-	if err := s.processTerminatedServerSessions(ctx); err != nil {
-		s.Logger.Error("Failed to process terminated server sessions", "domain", "session", "error", err)
+	defer func() {
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		}
+
+		s.Audit.Log(audit.Event{
+			ActorUserID: actorUserID,
+			ActorEmail:  actorEmail,
+			Action:      "end",
+			Resource:    "server session",
+			Success:     err == nil,
+			Reason:      reason,
+			Metadata: map[string]any{
+				"server_group": session.ServerGroup,
+			},
+		})
+	}()
+
+	if session.ServerGroup == "" {
+		return shared.ErrFieldMissing
 	}
 
-	// Finalised sessions
-	if err := s.processFinalisedServerSessions(ctx); err != nil {
-		s.Logger.Error("Failed to process finalised server sessions", "domain", "session", "error", err)
+	// Sessions opened by a recurring schedule can be ended by any user the group allows, admins are not bound by allowed users
+	if ownerID != 0 {
+		policy, err := s.getGroupPolicy(session.ServerGroup)
+		if err != nil {
+			return err
+		}
+
+		if err := s.validateGroupAccess(ownerID, policy); err != nil {
+			return err
+		}
 	}
+
+	return s.Repo.endServerSessionEarly(session.ServerGroup, ownerID)
 }
 
-// Server sessions which are ready for use
-func (s *Service) processReadyServerSessions(ctx context.Context) error {
-	sessionsForUse, err := s.Repo.getPendingOnServerSessions()
+func (s *Service) getScheduledSessions() ([]ScheduledSession, error) {
+	tx, err := s.Repo.Base.DB.Begin()
 	if err != nil {
-		s.Logger.Error("Failed to find sessions ready for use", "domain", "session", "error", err)
+		return nil, err
 	}
+	defer tx.Rollback()
 
-	if len(sessionsForUse) == 0 {
-		s.Logger.Debug("No new sessions ready for use", "domain", "session")
-	} else {
-		s.Logger.Debug("New sessions ready for use", "domain", "session")
-
-		// Queue notification and set notified flag for each
-		for _, session := range sessionsForUse {
-			n := notification.NewNotification{
-				UserID: session.UserID,
-				Msg:    fmt.Sprintf("Servers are online and ready for Server Group: %s", session.ServerGroup),
-				Title:  fmt.Sprintf("Session ready: %s", session.ServerGroup),
-			}
+	return s.Repo.getScheduledSessions(tx)
+}
 
-			tx, err := s.Repo.Base.DB.Begin()
-			if err != nil {
-				s.Logger.Error("Failed to create transaction for processing ready session", "user", session.Email, "domain", "session", "server_group", session.ServerGroup, "error", err)
-				continue
-			}
+// Book a server session to start in the future
+func (s *Service) newScheduledSession(booking ScheduledSessionRequest, ctx context.Context) (_ ScheduledSession, err error) {
+	actorUserID, actorEmail := ctxutil.GetActor(ctx)
 
-			if err := s.NotificationService.QueueNotification(tx, n); err != nil {
-				s.Logger.Error("Failed to queue session ready notification", "user", session.Email, "domain", "session", "server group", session.ServerGroup, "error", err)
-				tx.Rollback()
-				continue
-			}
+	defer func() {
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		}
 
-			if err = s.Repo.setOnNotifiedFlag(tx, 1, session.ServerGroup); err != nil {
-				s.Logger.Error("Failed up set flag for session notified on", "user", session.Email, "domain", "session", "server_group", session.ServerGroup, "error", err)
-				tx.Rollback()
-				continue
-			}
+		s.Audit.Log(audit.Event{
+			ActorUserID: actorUserID,
+			ActorEmail:  actorEmail,
+			Action:      "new",
+			Resource:    "scheduled session",
+			Success:     err == nil,
+			Reason:      reason,
+			Metadata: map[string]any{
+				"server_group": booking.ServerGroup,
+				"start":        booking.Start.Unix(),
+				"duration":     booking.Duration,
+			},
+		})
+	}()
 
-			actorUserID, actorEmail := ctxutil.GetActor(ctx)
-			s.Audit.LogTx(tx, audit.Event{
-				ActorUserID: actorUserID,
-				ActorEmail:  actorEmail,
-				Action:      "ready",
-				Resource:    "server session",
-				Success:     true,
-				Metadata: map[string]any{
-					"server_group": session.ServerGroup,
-				},
-			})
+	if err := s.prepareScheduledSession(&booking); err != nil {
+		return ScheduledSession{}, err
+	}
 
-			tx.Commit()
-		}
+	tx, err := s.Repo.Base.DB.Begin()
+	if err != nil {
+		return ScheduledSession{}, err
 	}
+	defer tx.Rollback()
 
-	return nil
-}
+	// Overlap check and insert share a transaction so two overlapping bookings cannot both succeed
+	if err := s.validateSchedule(tx, booking.ServerGroup, booking.Start.Unix(), booking.Expiry, booking.ID, true); err != nil {
+		return ScheduledSession{}, err
+	}
 
-// Process server sessions which will expire soon and user not yet notified
-func (s *Service) processExpiringServerSessions(ctx context.Context) error {
-	expiringSessions, err := s.Repo.getExpiringServerSessions()
+	booking.ID, err = s.Repo.newScheduledSession(tx, booking)
 	if err != nil {
-		return err
+		return ScheduledSession{}, err
 	}
 
-	if len(expiringSessions) == 0 {
-		s.Logger.Debug("No expiring server sessions", "domain", "session")
-		return nil
+	if err := tx.Commit(); err != nil {
+		return ScheduledSession{}, err
 	}
 
-	s.Logger.Debug("Found expiring sessions", "domain", "session", "count", len(expiringSessions))
+	return newScheduledSessionResponse(booking, actorEmail), nil
+}
 
-	// Queue notification for each and set flag
-	for _, session := range expiringSessions {
-		n := notification.NewNotification{
-			UserID: session.UserID,
-			Msg:    fmt.Sprintf("Your session is expiring soon for Server Group %s and can be extended", session.ServerGroup),
-			Title:  fmt.Sprintf("Session expiring: %s", session.ServerGroup),
-		}
+// Move, resize or change the server group of a booking. Owners may modify their own, admins any
+func (s *Service) updateScheduledSession(booking ScheduledSessionRequest, ctx context.Context) (_ ScheduledSession, err error) {
+	actorUserID, actorEmail := ctxutil.GetActor(ctx)
 
-		tx, err := s.Repo.Base.DB.Begin()
+	defer func() {
+		var reason string
 		if err != nil {
-			s.Logger.Error("Failed to create transaction for expiring sessions", "user", session.Email, "domain", "session", "server group", session.ServerGroup, "error", err)
-			continue
-		}
-
-		if err := s.NotificationService.QueueNotification(tx, n); err != nil {
-			s.Logger.Error("Failed to queue expiring session notification", "user", session.Email, "domain", "session", "server group", session.ServerGroup, "error", err)
-			tx.Rollback()
-			continue
-		}
-
-		if err := s.Repo.setWarningNotifiedFlag(tx, 1, session.ServerGroup); err != nil {
-			s.Logger.Error("Failed to set expiring session as notified", "user", session.Email, "domain", "session", "server_group", session.ServerGroup, "error", err)
-			tx.Rollback()
-			continue
+			reason = err.Error()
 		}
 
-		actorUserID, actorEmail := ctxutil.GetActor(ctx)
-		s.Audit.LogTx(tx, audit.Event{
+		s.Audit.Log(audit.Event{
 			ActorUserID: actorUserID,
 			ActorEmail:  actorEmail,
-			Action:      "expiring",
-			Resource:    "server session",
-			Success:     true,
+			Action:      "update",
+			Resource:    "scheduled session",
+			Success:     err == nil,
+			Reason:      reason,
 			Metadata: map[string]any{
-				"server_group": session.ServerGroup,
+				"id":           booking.ID,
+				"server_group": booking.ServerGroup,
+				"start":        booking.Start.Unix(),
+				"duration":     booking.Duration,
 			},
 		})
+	}()
 
-		tx.Commit()
+	ownerID, _, err := s.checkScheduledSessionOwner(booking.ID, booking.UserID)
+	if err != nil {
+		return ScheduledSession{}, err
 	}
 
-	return nil
-}
+	// Policy is checked against the owner, not an admin acting for them
+	booking.UserID = ownerID
+	if err := s.prepareScheduledSession(&booking); err != nil {
+		return ScheduledSession{}, err
+	}
 
-// Process expired server session which haven't been processed yet
-func (s *Service) processExpiredServerSessions(ctx context.Context) error {
-	expiredSessions, err := s.Repo.getExpiredServerSessions()
+	tx, err := s.Repo.Base.DB.Begin()
 	if err != nil {
-		return err
+		return ScheduledSession{}, err
 	}
+	defer tx.Rollback()
 
-	if len(expiredSessions) == 0 {
-		s.Logger.Debug("No expired server sessions", "domain", "session")
-		return nil
+	if err := s.validateSchedule(tx, booking.ServerGroup, booking.Start.Unix(), booking.Expiry, booking.ID, true); err != nil {
+		return ScheduledSession{}, err
 	}
 
-	s.Logger.Debug("Found expired sessions", "domain", "session", "count", len(expiredSessions))
+	if err := s.Repo.updateScheduledSession(tx, booking); err != nil {
+		return ScheduledSession{}, err
+	}
 
-	for _, session := range expiredSessions {
-		n := notification.NewNotification{
-			UserID: session.UserID,
-			Msg:    fmt.Sprintf("Your session has expired for Server Group %s. Servers will power off", session.ServerGroup),
-			Title:  fmt.Sprintf("Session expired: %s", session.ServerGroup),
-		}
+	if err := tx.Commit(); err != nil {
+		return ScheduledSession{}, err
+	}
 
-		tx, err := s.Repo.Base.DB.Begin()
+	ownerEmail, err := s.UserService.GetEmailFromUserID(ownerID)
+	if err != nil {
+		return ScheduledSession{}, err
+	}
+
+	return newScheduledSessionResponse(booking, ownerEmail), nil
+}
+
+// Cancel a booking. Owners may cancel their own, admins any
+func (s *Service) cancelScheduledSession(booking ScheduledSessionRequest, ctx context.Context) (err error) {
+	actorUserID, actorEmail := ctxutil.GetActor(ctx)
+	var serverGroup string
+
+	defer func() {
+		var reason string
 		if err != nil {
-			s.Logger.Error("Failed to create transaction for expired sessions", "user", session.Email, "domain", "session", "server group", session.ServerGroup, "error", err)
-			continue
+			reason = err.Error()
 		}
 
-		if err := s.NotificationService.QueueNotification(tx, n); err != nil {
+		s.Audit.Log(audit.Event{
+			ActorUserID: actorUserID,
+			ActorEmail:  actorEmail,
+			Action:      "cancel",
+			Resource:    "scheduled session",
+			Success:     err == nil,
+			Reason:      reason,
+			Metadata: map[string]any{
+				"id":           booking.ID,
+				"server_group": serverGroup,
+			},
+		})
+	}()
+
+	_, serverGroup, err = s.checkScheduledSessionOwner(booking.ID, booking.UserID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.Repo.Base.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.Repo.deleteScheduledSession(tx, booking.ID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Validate a new or modified booking and work out its expiry. Conflicts are checked with the write
+func (s *Service) prepareScheduledSession(booking *ScheduledSessionRequest) error {
+	policy, err := s.getGroupPolicy(booking.ServerGroup)
+	if err != nil {
+		return err
+	}
+
+	if err := s.validateScheduledSession(booking, policy); err != nil {
+		return err
+	}
+
+	if err := s.validateGroupAccess(booking.UserID, policy); err != nil {
+		return err
+	}
+
+	exists, err := s.Repo.hasServers(booking.ServerGroup)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return fmt.Errorf("no servers found for server_group: %s", booking.ServerGroup)
+	}
+
+	dur, err := time.ParseDuration(booking.Duration)
+	if err != nil {
+		return err
+	}
+
+	booking.Expiry = booking.Start.Add(dur).Unix()
+
+	return nil
+}
+
+// Allow the owner or an admin to change a booking, returning the owner and booked server group
+func (s *Service) checkScheduledSessionOwner(id int64, userID int64) (int64, string, error) {
+	if id == 0 {
+		return 0, "", shared.ErrFieldMissing
+	}
+
+	ownerID, serverGroup, err := s.Repo.getScheduledSessionOwner(id)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if ownerID == userID {
+		return ownerID, serverGroup, nil
+	}
+
+	user, err := s.UserService.GetUserAuthorisation(userID)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if !user.IsAdmin {
+		return 0, "", shared.ErrNoRowsUpdated
+	}
+
+	return ownerID, serverGroup, nil
+}
+
+func newScheduledSessionResponse(booking ScheduledSessionRequest, email string) ScheduledSession {
+	return ScheduledSession{
+		ID:          booking.ID,
+		UserID:      booking.UserID,
+		Email:       email,
+		ServerGroup: booking.ServerGroup,
+		Start:       booking.Start.UTC(),
+		Duration:    booking.Duration,
+		Expiry:      time.Unix(booking.Expiry, 0).UTC(),
+	}
+}
+
+// Share a session with another user, who can then extend or end it and receives its notifications
+func (s *Service) addCoOwner(req SessionOwnerRequest, ctx context.Context) (err error) {
+	actorUserID, actorEmail := ctxutil.GetActor(ctx)
+	var targetUserID int64
+
+	defer func() {
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		}
+
+		s.Audit.Log(audit.Event{
+			ActorUserID:  actorUserID,
+			ActorEmail:   actorEmail,
+			TargetUserID: targetUserID,
+			TargetEmail:  req.Email,
+			Action:       "add co-owner",
+			Resource:     "server session",
+			Success:      err == nil,
+			Reason:       reason,
+			Metadata: map[string]any{
+				"server_group": req.ServerGroup,
+			},
+		})
+	}()
+
+	ownerID, err := s.checkServerSessionOwner(req.ServerGroup, req.UserID)
+	if err != nil {
+		return err
+	}
+
+	targetUserID, err = s.getSessionTarget(&req)
+	if err != nil {
+		return err
+	}
+
+	if targetUserID == ownerID {
+		return shared.ErrAlreadyOwner
+	}
+
+	tx, err := s.Repo.Base.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.Repo.addCoOwner(tx, req.ServerGroup, targetUserID); err != nil {
+		return err
+	}
+
+	n := notification.NewNotification{
+		UserID: targetUserID,
+		Msg:    fmt.Sprintf("%s has shared their session for Server Group %s with you", actorEmail, req.ServerGroup),
+		Title:  fmt.Sprintf("Session shared: %s", req.ServerGroup),
+	}
+
+	if err := s.NotificationService.QueueNotification(tx, n); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Stop sharing a session. The owner or an admin may remove anyone, co-owners may remove themselves
+func (s *Service) removeCoOwner(req SessionOwnerRequest, ctx context.Context) (err error) {
+	actorUserID, actorEmail := ctxutil.GetActor(ctx)
+	var targetUserID int64
+
+	defer func() {
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		}
+
+		s.Audit.Log(audit.Event{
+			ActorUserID:  actorUserID,
+			ActorEmail:   actorEmail,
+			TargetUserID: targetUserID,
+			TargetEmail:  req.Email,
+			Action:       "remove co-owner",
+			Resource:     "server session",
+			Success:      err == nil,
+			Reason:       reason,
+			Metadata: map[string]any{
+				"server_group": req.ServerGroup,
+			},
+		})
+	}()
+
+	if req.ServerGroup == "" || req.Email == "" {
+		return shared.ErrFieldMissing
+	}
+
+	user, err := s.UserService.GetCredentialsByEmail(strings.ToLower(strings.TrimSpace(req.Email)))
+	if err != nil {
+		return err
+	}
+	targetUserID = user.UserID
+
+	if targetUserID != req.UserID {
+		if _, err := s.checkServerSessionOwner(req.ServerGroup, req.UserID); err != nil {
+			return err
+		}
+	}
+
+	return s.Repo.removeCoOwner(req.ServerGroup, targetUserID)
+}
+
+// Make another user the owner of a session, eg at the end of a shift. The previous owner loses access unless shared again
+func (s *Service) handOverServerSession(req SessionOwnerRequest, ctx context.Context) (err error) {
+	actorUserID, actorEmail := ctxutil.GetActor(ctx)
+	var targetUserID int64
+
+	defer func() {
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		}
+
+		s.Audit.Log(audit.Event{
+			ActorUserID:  actorUserID,
+			ActorEmail:   actorEmail,
+			TargetUserID: targetUserID,
+			TargetEmail:  req.Email,
+			Action:       "handover",
+			Resource:     "server session",
+			Success:      err == nil,
+			Reason:       reason,
+			Metadata: map[string]any{
+				"server_group": req.ServerGroup,
+			},
+		})
+	}()
+
+	ownerID, err := s.checkServerSessionOwner(req.ServerGroup, req.UserID)
+	if err != nil {
+		return err
+	}
+
+	targetUserID, err = s.getSessionTarget(&req)
+	if err != nil {
+		return err
+	}
+
+	if targetUserID == ownerID {
+		return shared.ErrAlreadyOwner
+	}
+
+	tx, err := s.Repo.Base.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.Repo.handOverServerSession(tx, req.ServerGroup, targetUserID); err != nil {
+		return err
+	}
+
+	n := notification.NewNotification{
+		UserID: targetUserID,
+		Msg:    fmt.Sprintf("%s has handed over their session for Server Group %s to you", actorEmail, req.ServerGroup),
+		Title:  fmt.Sprintf("Session handed over: %s", req.ServerGroup),
+	}
+
+	if err := s.NotificationService.QueueNotification(tx, n); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Check the user owns the running session for a server group, or is an admin, and return the owner
+func (s *Service) checkServerSessionOwner(serverGroup string, userID int64) (int64, error) {
+	if serverGroup == "" {
+		return 0, shared.ErrFieldMissing
+	}
+
+	ownerID, err := s.Repo.getServerSessionOwner(serverGroup)
+	if err != nil {
+		return 0, err
+	}
+
+	if ownerID == userID {
+		return ownerID, nil
+	}
+
+	user, err := s.UserService.GetUserAuthorisation(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if !user.IsAdmin {
+		return 0, shared.ErrNoRowsUpdated
+	}
+
+	return ownerID, nil
+}
+
+// Find the active user a session is being shared with or handed to, and check they may use the server group
+func (s *Service) getSessionTarget(req *SessionOwnerRequest) (int64, error) {
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	if req.Email == "" {
+		return 0, shared.ErrFieldMissing
+	}
+
+	creds, err := s.UserService.GetCredentialsByEmail(req.Email)
+	if err != nil {
+		return 0, err
+	}
+
+	user, err := s.UserService.GetUserAuthorisation(creds.UserID)
+	if err != nil {
+		return 0, err
+	}
+
+	if !user.IsActive {
+		return 0, shared.ErrUserNotFound
+	}
+
+	policy, err := s.getGroupPolicy(req.ServerGroup)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.validateGroupAccess(creds.UserID, policy); err != nil {
+		return 0, err
+	}
+
+	return creds.UserID, nil
+}
+
+// Queue a session notification for its owner and each co-owner
+func (s *Service) queueSessionNotification(tx *sql.Tx, serverGroup string, n notification.NewNotification) error {
+	if err := s.NotificationService.QueueNotification(tx, n); err != nil {
+		return err
+	}
+
+	coOwnerIDs, err := s.Repo.getCoOwnerIDs(tx, serverGroup)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range coOwnerIDs {
+		n.UserID = id
+		if err := s.NotificationService.QueueNotification(tx, n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Join the waitlist for a busy server group. Access and duration are checked now so the wait is not wasted
+func (s *Service) joinWaitlist(req WaitlistRequest, ctx context.Context) (_ WaitlistEntry, err error) {
+	actorUserID, actorEmail := ctxutil.GetActor(ctx)
+
+	defer func() {
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		}
+
+		s.Audit.Log(audit.Event{
+			ActorUserID: actorUserID,
+			ActorEmail:  actorEmail,
+			Action:      "join",
+			Resource:    "waitlist",
+			Success:     err == nil,
+			Reason:      reason,
+			Metadata: map[string]any{
+				"server_group": req.ServerGroup,
+				"duration":     req.Duration,
+				"auto_start":   req.AutoStart,
+			},
+		})
+	}()
+
+	if req.ServerGroup == "" {
+		return WaitlistEntry{}, shared.ErrFieldMissing
+	}
+
+	policy, err := s.getGroupPolicy(req.ServerGroup)
+	if err != nil {
+		return WaitlistEntry{}, err
+	}
+
+	if req.AutoStart {
+		session := ServerSessionRequest{ServerGroup: req.ServerGroup, Duration: req.Duration}
+		if err := s.validateServerSession(&session, policy); err != nil {
+			return WaitlistEntry{}, err
+		}
+		req.Duration = session.Duration
+	}
+
+	if err := s.validateGroupAccess(req.UserID, policy); err != nil {
+		return WaitlistEntry{}, err
+	}
+
+	busy, err := s.Repo.hasSession(req.ServerGroup)
+	if err != nil {
+		return WaitlistEntry{}, err
+	}
+
+	// A free group with a queue is held for the user at its head, so others may still join
+	if !busy {
+		busy, err = s.Repo.hasWaitlist(req.ServerGroup)
+		if err != nil {
+			return WaitlistEntry{}, err
+		}
+	}
+
+	if !busy {
+		return WaitlistEntry{}, shared.ErrGroupNotInUse
+	}
+
+	id, err := s.Repo.newWaitlistEntry(req)
+	if err != nil {
+		return WaitlistEntry{}, err
+	}
+
+	position, err := s.Repo.getWaitlistPosition(id)
+	if err != nil {
+		return WaitlistEntry{}, err
+	}
+
+	return WaitlistEntry{
+		ID:          id,
+		UserID:      req.UserID,
+		Email:       actorEmail,
+		ServerGroup: req.ServerGroup,
+		Position:    position,
+		Duration:    req.Duration,
+		AutoStart:   req.AutoStart,
+		TimeAdded:   time.Now().Unix(),
+	}, nil
+}
+
+func (s *Service) leaveWaitlist(req WaitlistRequest, ctx context.Context) (err error) {
+	actorUserID, actorEmail := ctxutil.GetActor(ctx)
+
+	defer func() {
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		}
+
+		s.Audit.Log(audit.Event{
+			ActorUserID: actorUserID,
+			ActorEmail:  actorEmail,
+			Action:      "leave",
+			Resource:    "waitlist",
+			Success:     err == nil,
+			Reason:      reason,
+			Metadata: map[string]any{
+				"server_group": req.ServerGroup,
+			},
+		})
+	}()
+
+	if req.ServerGroup == "" {
+		return shared.ErrFieldMissing
+	}
+
+	return s.Repo.deleteWaitlistEntry(req.UserID, req.ServerGroup)
+}
+
+func (s *Service) getRecurringSchedules() ([]RecurringSchedule, error) {
+	tx, err := s.Repo.Base.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return s.Repo.getRecurringSchedules(tx)
+}
+
+// Attach a weekly window to a server group, sessions it opens are owned by the admin creating it
+func (s *Service) newRecurringSchedule(schedule RecurringScheduleRequest, ctx context.Context) (_ RecurringSchedule, err error) {
+	actorUserID, actorEmail := ctxutil.GetActor(ctx)
+
+	defer func() {
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		}
+
+		s.Audit.Log(audit.Event{
+			ActorUserID: actorUserID,
+			ActorEmail:  actorEmail,
+			Action:      "new",
+			Resource:    "recurring schedule",
+			Success:     err == nil,
+			Reason:      reason,
+			Metadata: map[string]any{
+				"server_group": schedule.ServerGroup,
+				"days":         schedule.Days,
+				"start":        schedule.Start,
+				"end":          schedule.End,
+				"time_zone":    schedule.TimeZone,
+			},
+		})
+	}()
+
+	if err := s.validateRecurringSchedule(&schedule); err != nil {
+		return RecurringSchedule{}, err
+	}
+
+	tx, err := s.Repo.Base.DB.Begin()
+	if err != nil {
+		return RecurringSchedule{}, err
+	}
+	defer tx.Rollback()
+
+	// Windows may not overlap bookings, checked in the same transaction as the insert
+	if err := s.validateRecurringConflicts(tx, schedule); err != nil {
+		return RecurringSchedule{}, err
+	}
+
+	schedule.ID, err = s.Repo.newRecurringSchedule(tx, schedule)
+	if err != nil {
+		return RecurringSchedule{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return RecurringSchedule{}, err
+	}
+
+	return RecurringSchedule{
+		ID:          schedule.ID,
+		UserID:      schedule.UserID,
+		Email:       actorEmail,
+		ServerGroup: schedule.ServerGroup,
+		Days:        schedule.Days,
+		Start:       schedule.Start,
+		End:         schedule.End,
+		TimeZone:    schedule.TimeZone,
+	}, nil
+}
+
+func (s *Service) deleteRecurringSchedule(schedule RecurringScheduleRequest, ctx context.Context) (err error) {
+	actorUserID, actorEmail := ctxutil.GetActor(ctx)
+	var serverGroup string
+
+	defer func() {
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		}
+
+		s.Audit.Log(audit.Event{
+			ActorUserID: actorUserID,
+			ActorEmail:  actorEmail,
+			Action:      "delete",
+			Resource:    "recurring schedule",
+			Success:     err == nil,
+			Reason:      reason,
+			Metadata: map[string]any{
+				"id":           schedule.ID,
+				"server_group": serverGroup,
+			},
+		})
+	}()
+
+	serverGroup, err = s.Repo.deleteRecurringSchedule(schedule.ID)
+	return err
+}
+
+// High level for processing server sessions in each state - called by go routine worker
+func (s *Service) ProcessServerSessions(ctx context.Context) {
+	// Bookings due to start, ahead of recurring windows so a booking takes precedence
+	if err := s.processScheduledServerSessions(ctx); err != nil {
+		s.Logger.Error("Failed to process scheduled server sessions", "domain", "session", "error", err)
+	}
+
+	// Recurring windows due to open
+	if err := s.processRecurringSchedules(ctx); err != nil {
+		s.Logger.Error("Failed to process recurring schedules", "domain", "session", "error", err)
+	}
+
+	// Waitlists for groups which are free
+	if err := s.processWaitlist(ctx); err != nil {
+		s.Logger.Error("Failed to process waitlist", "domain", "session", "error", err)
+	}
+
+	// Ready-for-use sessions
+	if err := s.processReadyServerSessions(ctx); err != nil {
+		s.Logger.Error("Failed to process ready server sessions", "domain", "session", "error", err)
+	}
+
+	// Failed sessions
+	if err := s.processFailedServerSessions(ctx); err != nil {
+		s.Logger.Error("Failed to process failed server sessions", "domain", "session", "error", err)
+	}
+
+	// Servers running without a session
+	if err := s.processDriftedServerGroups(ctx); err != nil {
+		s.Logger.Error("Failed to process drifted server groups", "domain", "session", "error", err)
+	}
+
+	// Expiring sessions
+	if err := s.processExpiringServerSessions(ctx); err != nil {
+		s.Logger.Error("Failed to process expiring server sessions", "domain", "session", "error", err)
+	}
+
+	// Expired sessions
+	if err := s.processExpiredServerSessions(ctx); err != nil {
+		s.Logger.Error("Failed to process expired server sessions", "domain", "session", "error", err)
+	}
+
+	// Terminated sessions
+	if err := s.processTerminatedServerSessions(ctx); err != nil {
+		s.Logger.Error("Failed to process terminated server sessions", "domain", "session", "error", err)
+	}
+
+	// Finalised sessions
+	if err := s.processFinalisedServerSessions(ctx); err != nil {
+		s.Logger.Error("Failed to process finalised server sessions", "domain", "session", "error", err)
+	}
+}
+
+// Start bookings which are due. A booking waits while an earlier session is still running, and is dropped once its end has passed
+func (s *Service) processScheduledServerSessions(ctx context.Context) error {
+	now := time.Now()
+
+	due, err := s.Repo.getDueScheduledSessions(now.Unix())
+	if err != nil {
+		return err
+	}
+
+	if len(due) == 0 {
+		s.Logger.Debug("No scheduled sessions due", "domain", "session")
+		return nil
+	}
+
+	for _, booking := range due {
+		missed := !now.Before(booking.Expiry)
+
+		if !missed {
+			exists, err := s.Repo.hasSession(booking.ServerGroup)
+			if err != nil {
+				s.Logger.Error("Failed to check for a running session", "user", booking.Email, "domain", "session", "server_group", booking.ServerGroup, "error", err)
+				continue
+			}
+
+			if exists {
+				s.Logger.Debug("Scheduled session waiting on a running session", "user", booking.Email, "domain", "session", "server_group", booking.ServerGroup)
+				continue
+			}
+		}
+
+		tx, err := s.Repo.Base.DB.Begin()
+		if err != nil {
+			s.Logger.Error("Failed to create transaction for scheduled session", "user", booking.Email, "domain", "session", "server_group", booking.ServerGroup, "error", err)
+			continue
+		}
+
+		action := "start"
+		n := notification.NewNotification{
+			UserID: booking.UserID,
+			Msg:    fmt.Sprintf("Your scheduled session for Server Group %s has started, servers are on their way up", booking.ServerGroup),
+			Title:  fmt.Sprintf("Scheduled session started: %s", booking.ServerGroup),
+		}
+
+		if missed {
+			action = "missed"
+			n.Msg = fmt.Sprintf("Your scheduled session for Server Group %s could not start before it was due to end", booking.ServerGroup)
+			n.Title = fmt.Sprintf("Scheduled session missed: %s", booking.ServerGroup)
+		} else {
+			session := ServerSessionRequest{
+				UserID:      booking.UserID,
+				ServerGroup: booking.ServerGroup,
+				Expiry:      booking.Expiry.Unix(),
+			}
+
+			if err := s.Repo.startServerSession(tx, session); err != nil {
+				s.Logger.Error("Failed to start scheduled session", "user", booking.Email, "domain", "session", "server_group", booking.ServerGroup, "error", err)
+				tx.Rollback()
+				continue
+			}
+		}
+
+		if err := s.Repo.deleteScheduledSession(tx, booking.ID); err != nil {
+			s.Logger.Error("Failed to remove scheduled session", "user", booking.Email, "domain", "session", "server_group", booking.ServerGroup, "error", err)
+			tx.Rollback()
+			continue
+		}
+
+		if err := s.NotificationService.QueueNotification(tx, n); err != nil {
+			s.Logger.Error("Failed to queue scheduled session notification", "user", booking.Email, "domain", "session", "server_group", booking.ServerGroup, "error", err)
+			tx.Rollback()
+			continue
+		}
+
+		actorUserID, actorEmail := ctxutil.GetActor(ctx)
+		s.Audit.LogTx(tx, audit.Event{
+			ActorUserID: actorUserID,
+			ActorEmail:  actorEmail,
+			Action:      action,
+			Resource:    "scheduled session",
+			Success:     !missed,
+			Metadata: map[string]any{
+				"id":           booking.ID,
+				"server_group": booking.ServerGroup,
+				"owner":        booking.Email,
+				"expiry":       booking.Expiry.Unix(),
+			},
+		})
+
+		tx.Commit()
+	}
+
+	return nil
+}
+
+// Open a session for each recurring window which has started. Each window opens once, so a session ended early stays
+// ended. A window waits while another session is running and is skipped once it has closed. Bookings take precedence,
+// a window closes early when a booking starts inside it
+func (s *Service) processRecurringSchedules(ctx context.Context) error {
+	now := time.Now()
+
+	tx, err := s.Repo.Base.DB.Begin()
+	if err != nil {
+		return err
+	}
+	schedules, err := s.Repo.getRecurringSchedules(tx)
+	tx.Rollback()
+	if err != nil {
+		return err
+	}
+
+	for _, schedule := range schedules {
+		window, err := parseRecurringSchedule(schedule)
+		if err != nil {
+			s.Logger.Error("Failed to parse recurring schedule", "domain", "session", "id", schedule.ID, "server_group", schedule.ServerGroup, "error", err)
+			continue
+		}
+
+		start, end, open := window.openAt(now)
+		if !open || schedule.LastOpened >= start.Unix() {
+			continue
+		}
+
+		exists, err := s.Repo.hasSession(schedule.ServerGroup)
+		if err != nil {
+			s.Logger.Error("Failed to check for a running session", "domain", "session", "server_group", schedule.ServerGroup, "error", err)
+			continue
+		}
+
+		if exists {
+			s.Logger.Debug("Recurring schedule waiting on a running session", "domain", "session", "id", schedule.ID, "server_group", schedule.ServerGroup)
+			continue
+		}
+
+		tx, err := s.Repo.Base.DB.Begin()
+		if err != nil {
+			s.Logger.Error("Failed to create transaction for recurring schedule", "domain", "session", "server_group", schedule.ServerGroup, "error", err)
+			continue
+		}
+
+		session := ServerSessionRequest{
+			UserID:      schedule.UserID,
+			ServerGroup: schedule.ServerGroup,
+			Expiry:      end.Unix(),
+		}
+
+		// Conflicts are refused on create, but a booking may predate the window or the window may have been recreated
+		nextBooking, err := s.Repo.getNextBookingStart(tx, schedule.ServerGroup, now.Unix(), end.Unix())
+		if err != nil {
+			s.Logger.Error("Failed to check bookings for recurring schedule", "domain", "session", "server_group", schedule.ServerGroup, "error", err)
+			tx.Rollback()
+			continue
+		}
+
+		if nextBooking != 0 && nextBooking <= now.Unix() {
+			s.Logger.Debug("Recurring schedule yielding to a booking", "domain", "session", "id", schedule.ID, "server_group", schedule.ServerGroup)
+			tx.Rollback()
+			continue
+		}
+
+		if nextBooking != 0 {
+			session.Expiry = nextBooking
+		}
+
+		if err := s.Repo.openRecurringSession(tx, session, schedule.ID, start.Unix()); err != nil {
+			s.Logger.Error("Failed to open recurring session", "domain", "session", "server_group", schedule.ServerGroup, "error", err)
+			tx.Rollback()
+			continue
+		}
+
+		actorUserID, actorEmail := ctxutil.GetActor(ctx)
+		s.Audit.LogTx(tx, audit.Event{
+			ActorUserID: actorUserID,
+			ActorEmail:  actorEmail,
+			Action:      "open",
+			Resource:    "recurring schedule",
+			Success:     true,
+			Metadata: map[string]any{
+				"id":           schedule.ID,
+				"server_group": schedule.ServerGroup,
+				"owner":        schedule.Email,
+				"expiry":       session.Expiry,
+			},
+		})
+
+		if err := tx.Commit(); err != nil {
+			s.Logger.Error("Failed to commit recurring session", "domain", "session", "server_group", schedule.ServerGroup, "error", err)
+			continue
+		}
+
+		s.Logger.Info("Recurring session opened", "domain", "session", "server_group", schedule.ServerGroup, "expiry", time.Unix(session.Expiry, 0))
+	}
+
+	return nil
+}
+
+// Serve the next user waiting for each free server group, one per group each cycle. Runs after bookings so they take
+// priority. Auto start falls back to a notification when the session can no longer be started. A notified user keeps
+// their place and the group is held for them for the claim window, after which they are removed and the next user served
+func (s *Service) processWaitlist(ctx context.Context) error {
+	entries, err := s.Repo.getFreeWaitlistEntries()
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		s.Logger.Debug("No waitlist entries for free server groups", "domain", "session")
+		return nil
+	}
+
+	now := time.Now()
+	for _, entry := range entries {
+		action := "notify"
+		var reason string
+		var session ServerSessionRequest
+
+		if entry.NotifiedAt != 0 {
+			if now.Before(time.Unix(entry.NotifiedAt, 0).Add(s.Config.WaitlistClaimWindow)) {
+				s.Logger.Debug("Server group held for waitlist user", "user", entry.Email, "domain", "session", "server_group", entry.ServerGroup)
+				continue
+			}
+
+			action = "expire"
+		} else if entry.AutoStart {
+			session, err = s.prepareWaitlistSession(entry)
+			if err != nil {
+				reason = err.Error()
+			} else {
+				action = "start"
+			}
+		}
+
+		tx, err := s.Repo.Base.DB.Begin()
+		if err != nil {
+			s.Logger.Error("Failed to create transaction for waitlist", "user", entry.Email, "domain", "session", "server_group", entry.ServerGroup, "error", err)
+			continue
+		}
+
+		// Must end before the next booking, checked in the same transaction as the start
+		if action == "start" {
+			if err := s.validateSchedule(tx, entry.ServerGroup, now.Unix(), session.Expiry, 0, false); err != nil {
+				action = "notify"
+				reason = err.Error()
+			}
+		}
+
+		n := notification.NewNotification{
+			UserID: entry.UserID,
+			Msg:    fmt.Sprintf("Server Group %s is now free and held for you for %s, start a session to claim it", entry.ServerGroup, s.Config.WaitlistClaimWindow),
+			Title:  fmt.Sprintf("Server group free: %s", entry.ServerGroup),
+		}
+
+		switch {
+		case action == "start":
+			n.Msg = fmt.Sprintf("Server Group %s is free and your session has started, servers are on their way up", entry.ServerGroup)
+			n.Title = fmt.Sprintf("Waitlist session started: %s", entry.ServerGroup)
+
+			if err := s.Repo.startServerSession(tx, session); err != nil {
+				s.Logger.Error("Failed to start waitlist session", "user", entry.Email, "domain", "session", "server_group", entry.ServerGroup, "error", err)
+				tx.Rollback()
+				continue
+			}
+
+			err = s.Repo.removeWaitlistEntry(tx, entry.ID)
+		case action == "expire":
+			n.Msg = fmt.Sprintf("Server Group %s was not claimed in time and has passed to the next user on the waitlist", entry.ServerGroup)
+			n.Title = fmt.Sprintf("Waitlist hold expired: %s", entry.ServerGroup)
+
+			err = s.Repo.removeWaitlistEntry(tx, entry.ID)
+		case reason != "":
+			// Auto start was refused, the user is told why and may still claim the group themselves
+			n.Msg = fmt.Sprintf("Server Group %s is now free but a session could not be started for you: %s. It is held for you for %s", entry.ServerGroup, reason, s.Config.WaitlistClaimWindow)
+			fallthrough
+		default:
+			err = s.Repo.setWaitlistNotified(tx, entry.ID, now.Unix())
+		}
+
+		if err != nil {
+			s.Logger.Error("Failed to update waitlist entry", "user", entry.Email, "domain", "session", "server_group", entry.ServerGroup, "error", err)
+			tx.Rollback()
+			continue
+		}
+
+		if err := s.NotificationService.QueueNotification(tx, n); err != nil {
+			s.Logger.Error("Failed to queue waitlist notification", "user", entry.Email, "domain", "session", "server_group", entry.ServerGroup, "error", err)
+			tx.Rollback()
+			continue
+		}
+
+		actorUserID, actorEmail := ctxutil.GetActor(ctx)
+		s.Audit.LogTx(tx, audit.Event{
+			ActorUserID: actorUserID,
+			ActorEmail:  actorEmail,
+			Action:      action,
+			Resource:    "waitlist",
+			Success:     action != "expire" && (!entry.AutoStart || action == "start"),
+			Reason:      reason,
+			Metadata: map[string]any{
+				"server_group": entry.ServerGroup,
+				"user":         entry.Email,
+				"duration":     entry.Duration,
+			},
+		})
+
+		tx.Commit()
+	}
+
+	return nil
+}
+
+// Check a waitlist entry against the group policy as it is now, and build its session
+func (s *Service) prepareWaitlistSession(entry WaitlistEntry) (ServerSessionRequest, error) {
+	session := ServerSessionRequest{UserID: entry.UserID, ServerGroup: entry.ServerGroup, Duration: entry.Duration}
+
+	policy, err := s.getGroupPolicy(entry.ServerGroup)
+	if err != nil {
+		return ServerSessionRequest{}, err
+	}
+
+	if err := s.validateServerSession(&session, policy); err != nil {
+		return ServerSessionRequest{}, err
+	}
+
+	if err := s.validateGroupAccess(entry.UserID, policy); err != nil {
+		return ServerSessionRequest{}, err
+	}
+
+	session.Expiry, err = util.GetExpiryFromDuration(session.Duration)
+	if err != nil {
+		return ServerSessionRequest{}, err
+	}
+
+	return session, nil
+}
+
+// Server sessions which are ready for use
+func (s *Service) processReadyServerSessions(ctx context.Context) error {
+	sessionsForUse, err := s.Repo.getPendingOnServerSessions()
+	if err != nil {
+		s.Logger.Error("Failed to find sessions ready for use", "domain", "session", "error", err)
+	}
+
+	if len(sessionsForUse) == 0 {
+		s.Logger.Debug("No new sessions ready for use", "domain", "session")
+	} else {
+		s.Logger.Debug("New sessions ready for use", "domain", "session")
+
+		// Servers are on, wait for applications too. Probes for every group run together and again next cycle
+		serverGroups := make([]string, len(sessionsForUse))
+		for i, session := range sessionsForUse {
+			serverGroups[i] = session.ServerGroup
+		}
+		ready := s.ReadinessService.CheckGroups(serverGroups)
+
+		// Queue notification and set notified flag for each
+		for _, session := range sessionsForUse {
+			if !ready[session.ServerGroup] {
+				continue
+			}
+
+			n := notification.NewNotification{
+				UserID: session.UserID,
+				Msg:    fmt.Sprintf("Servers are online and ready for Server Group: %s", session.ServerGroup),
+				Title:  fmt.Sprintf("Session ready: %s", session.ServerGroup),
+			}
+
+			tx, err := s.Repo.Base.DB.Begin()
+			if err != nil {
+				s.Logger.Error("Failed to create transaction for processing ready session", "user", session.Email, "domain", "session", "server_group", session.ServerGroup, "error", err)
+				continue
+			}
+
+			if err := s.queueSessionNotification(tx, session.ServerGroup, n); err != nil {
+				s.Logger.Error("Failed to queue session ready notification", "user", session.Email, "domain", "session", "server group", session.ServerGroup, "error", err)
+				tx.Rollback()
+				continue
+			}
+
+			if err = s.Repo.setOnNotifiedFlag(tx, 1, session.ServerGroup); err != nil {
+				s.Logger.Error("Failed up set flag for session notified on", "user", session.Email, "domain", "session", "server_group", session.ServerGroup, "error", err)
+				tx.Rollback()
+				continue
+			}
+
+			actorUserID, actorEmail := ctxutil.GetActor(ctx)
+			s.Audit.LogTx(tx, audit.Event{
+				ActorUserID: actorUserID,
+				ActorEmail:  actorEmail,
+				Action:      "ready",
+				Resource:    "server session",
+				Success:     true,
+				Metadata: map[string]any{
+					"server_group": session.ServerGroup,
+				},
+			})
+
+			tx.Commit()
+		}
+	}
+
+	return nil
+}
+
+// Flag sessions whose servers ran out of attempts or are stuck past the transition timeout, notifying the owner and admins
+func (s *Service) processFailedServerSessions(ctx context.Context) error {
+	var cutoff int64
+	if s.Config.ServerTransitionTimeout > 0 {
+		cutoff = time.Now().Add(-s.Config.ServerTransitionTimeout).Unix()
+	}
+
+	failedServers, err := s.Repo.getFailedServers(s.Config.ServerMaxAttempts, cutoff)
+	if err != nil {
+		return err
+	}
+
+	if len(failedServers) == 0 {
+		s.Logger.Debug("No failed server sessions", "domain", "session")
+		return nil
+	}
+
+	// Rows are ordered by group, report each group once with every server holding it up
+	groups := []string{}
+	byGroup := make(map[string][]FailedServer)
+	for _, f := range failedServers {
+		if _, ok := byGroup[f.ServerGroup]; !ok {
+			groups = append(groups, f.ServerGroup)
+		}
+		byGroup[f.ServerGroup] = append(byGroup[f.ServerGroup], f)
+	}
+
+	adminIDs, err := s.UserService.GetAdminUserIDs()
+	if err != nil {
+		return err
+	}
+
+	s.Logger.Debug("Found failed sessions", "domain", "session", "count", len(groups))
+
+	for _, group := range groups {
+		servers := byGroup[group]
+		owner := servers[0]
+
+		reasons := make([]string, len(servers))
+		for i, f := range servers {
+			reasons[i] = s.describeFailure(f)
+		}
+		reason := strings.Join(reasons, "; ")
+
+		tx, err := s.Repo.Base.DB.Begin()
+		if err != nil {
+			s.Logger.Error("Failed to create transaction for failed session", "user", owner.Email, "domain", "session", "server_group", group, "error", err)
+			continue
+		}
+
+		if err := s.Repo.setFailed(tx, reason, group); err != nil {
+			s.Logger.Error("Failed to flag session as failed", "user", owner.Email, "domain", "session", "server_group", group, "error", err)
+			tx.Rollback()
+			continue
+		}
+
+		// Owner and admins, once each
+		recipients := []int64{owner.UserID}
+		for _, id := range adminIDs {
+			if id != owner.UserID {
+				recipients = append(recipients, id)
+			}
+		}
+
+		queued := true
+		for _, userID := range recipients {
+			n := notification.NewNotification{
+				UserID: userID,
+				Msg:    fmt.Sprintf("Session for Server Group %s has failed: %s", group, reason),
+				Title:  fmt.Sprintf("Session failed: %s", group),
+			}
+
+			if err := s.NotificationService.QueueNotification(tx, n); err != nil {
+				s.Logger.Error("Failed to queue failed session notification", "user", owner.Email, "domain", "session", "server_group", group, "error", err)
+				queued = false
+				break
+			}
+		}
+
+		if !queued {
+			tx.Rollback()
+			continue
+		}
+
+		actorUserID, actorEmail := ctxutil.GetActor(ctx)
+		s.Audit.LogTx(tx, audit.Event{
+			ActorUserID: actorUserID,
+			ActorEmail:  actorEmail,
+			Action:      "failed",
+			Resource:    "server session",
+			Success:     false,
+			Reason:      reason,
+			Metadata: map[string]any{
+				"server_group": group,
+				"owner":        owner.Email,
+			},
+		})
+
+		tx.Commit()
+	}
+
+	return nil
+}
+
+// Describe why a server is holding up its session
+func (s *Service) describeFailure(f FailedServer) string {
+	action := "start"
+	if f.NextState == string(server.ServerOff) {
+		action = "stop"
+	}
+
+	if maxAttempts := s.Config.ServerMaxAttempts; maxAttempts > 0 && f.Attempts >= maxAttempts {
+		reason := fmt.Sprintf("%s failed to %s after %d attempts", f.Name, action, f.Attempts)
+		if f.LastError != nil {
+			reason += ": " + *f.LastError
+		}
+		return reason
+	}
+
+	return fmt.Sprintf("%s did not reach %s within %s", f.Name, f.NextState, s.Config.ServerTransitionTimeout)
+}
+
+// Apply the drift policy to server groups running without a session, eg started from the cloud console. Every decision is audited
+func (s *Service) processDriftedServerGroups(ctx context.Context) error {
+	// Resolve first so a group can drift again
+	resolved, err := s.Repo.getResolvedDrift()
+	if err != nil {
+		return err
+	}
+
+	for _, group := range resolved {
+		tx, err := s.Repo.Base.DB.Begin()
+		if err != nil {
+			s.Logger.Error("Failed to create transaction for resolving drift", "domain", "session", "server_group", group.ServerGroup, "error", err)
+			continue
+		}
+
+		if err := s.Repo.resolveDrift(tx, group.ServerGroup); err != nil {
+			s.Logger.Error("Failed to resolve drift", "domain", "session", "server_group", group.ServerGroup, "error", err)
+			tx.Rollback()
+			continue
+		}
+
+		s.auditDrift(ctx, tx, "drift resolved", group.ServerGroup, group.Drift.Policy, "", nil)
+		tx.Commit()
+	}
+
+	drifted, err := s.Repo.getDriftedServerGroups()
+	if err != nil {
+		return err
+	}
+
+	if len(drifted) == 0 {
+		s.Logger.Debug("No drifted server groups", "domain", "session")
+		return nil
+	}
+
+	adminIDs, err := s.UserService.GetAdminUserIDs()
+	if err != nil {
+		return err
+	}
+
+	for _, group := range drifted {
+		if group.Drift == nil {
+			s.handleNewDrift(ctx, group, adminIDs)
+			continue
+		}
+
+		// Grace period over, stop the servers
+		gracePeriodEnd := time.Unix(group.Drift.DetectedAt, 0).Add(s.Config.DriftGracePeriod)
+		if group.Drift.Policy == shared.DriftPolicyOff && !group.Drift.Enforced && !time.Now().Before(gracePeriodEnd) {
+			s.enforceDrift(ctx, group, adminIDs)
+		}
+	}
+
+	return nil
+}
+
+// Decide what to do with a newly drifted server group. The group's tag overrides the global policy, which defaults to ignore.
+// Adopted sessions are owned by the longest standing active admin, who can hand them over
+func (s *Service) handleNewDrift(ctx context.Context, group DriftedServerGroup, adminIDs []int64) {
+	policy, err := s.getGroupPolicy(group.ServerGroup)
+	if err != nil {
+		s.Logger.Error("Failed to get server group policy", "domain", "session", "server_group", group.ServerGroup, "error", err)
+		return
+	}
+
+	decision := policy.DriftPolicy
+	if decision == "" {
+		decision = s.Config.DriftPolicy
+	}
+
+	now := time.Now()
+	deadline := now.Add(s.Config.DriftGracePeriod)
+	reason := ""
+
+	// An adopted session needs an owner
+	if decision == shared.DriftPolicyAdopt && len(adminIDs) == 0 {
+		decision = shared.DriftPolicyIgnore
+		reason = "no admin to own an adopted session"
+	}
+
+	tx, err := s.Repo.Base.DB.Begin()
+	if err != nil {
+		s.Logger.Error("Failed to create transaction for drifted server group", "domain", "session", "server_group", group.ServerGroup, "error", err)
+		return
+	}
+	defer tx.Rollback()
+
+	var msg string
+	switch decision {
+	case shared.DriftPolicyAdopt:
+		// Tracked by the session from here, no drift recorded
+		err = s.Repo.adoptServerGroup(tx, adminIDs[0], group.ServerGroup, deadline.Unix())
+		msg = fmt.Sprintf("Servers running without a session in Server Group %s were adopted into a session ending %s: %s", group.ServerGroup, deadline.UTC().Format(time.RFC1123), group.Servers)
+	case shared.DriftPolicyOff:
+		err = s.Repo.newDrift(tx, group.ServerGroup, decision, now.Unix())
+		msg = fmt.Sprintf("Servers running without a session in Server Group %s will be stopped at %s unless a session is started: %s", group.ServerGroup, deadline.UTC().Format(time.RFC1123), group.Servers)
+	case shared.DriftPolicyAlert:
+		err = s.Repo.newDrift(tx, group.ServerGroup, decision, now.Unix())
+		msg = fmt.Sprintf("Servers running without a session in Server Group %s: %s", group.ServerGroup, group.Servers)
+	case shared.DriftPolicyIgnore:
+		err = s.Repo.newDrift(tx, group.ServerGroup, decision, now.Unix())
+	}
+
+	if err != nil {
+		s.Logger.Error("Failed to record drifted server group", "domain", "session", "server_group", group.ServerGroup, "policy", decision, "error", err)
+		return
+	}
+
+	if msg != "" {
+		if err := s.notifyAdmins(tx, adminIDs, fmt.Sprintf("Servers running without session: %s", group.ServerGroup), msg); err != nil {
+			s.Logger.Error("Failed to queue drift notification", "domain", "session", "server_group", group.ServerGroup, "error", err)
+			return
+		}
+	}
+
+	s.auditDrift(ctx, tx, "drift detected", group.ServerGroup, decision, reason, map[string]any{"servers": group.Servers})
+
+	if err := tx.Commit(); err != nil {
+		s.Logger.Error("Failed to commit drifted server group", "domain", "session", "server_group", group.ServerGroup, "error", err)
+		return
+	}
+
+	s.Logger.Warn("Server group running without a session", "domain", "session", "server_group", group.ServerGroup, "policy", decision, "servers", group.Servers)
+}
+
+// Stop a drifted server group once its grace period is over
+func (s *Service) enforceDrift(ctx context.Context, group DriftedServerGroup, adminIDs []int64) {
+	tx, err := s.Repo.Base.DB.Begin()
+	if err != nil {
+		s.Logger.Error("Failed to create transaction for enforcing drift", "domain", "session", "server_group", group.ServerGroup, "error", err)
+		return
+	}
+	defer tx.Rollback()
+
+	if err := s.Repo.enforceDrift(tx, group.ServerGroup); err != nil {
+		s.Logger.Error("Failed to stop drifted server group", "domain", "session", "server_group", group.ServerGroup, "error", err)
+		return
+	}
+
+	msg := fmt.Sprintf("Stopping servers running without a session in Server Group %s: %s", group.ServerGroup, group.Servers)
+	if err := s.notifyAdmins(tx, adminIDs, fmt.Sprintf("Stopping servers: %s", group.ServerGroup), msg); err != nil {
+		s.Logger.Error("Failed to queue drift notification", "domain", "session", "server_group", group.ServerGroup, "error", err)
+		return
+	}
+
+	s.auditDrift(ctx, tx, "drift enforced", group.ServerGroup, group.Drift.Policy, "", map[string]any{"servers": group.Servers})
+
+	if err := tx.Commit(); err != nil {
+		s.Logger.Error("Failed to commit drift enforcement", "domain", "session", "server_group", group.ServerGroup, "error", err)
+		return
+	}
+
+	s.Logger.Info("Stopping server group running without a session", "domain", "session", "server_group", group.ServerGroup, "servers", group.Servers)
+}
+
+// Queue the same notification for each admin
+func (s *Service) notifyAdmins(tx *sql.Tx, adminIDs []int64, title string, msg string) error {
+	for _, userID := range adminIDs {
+		n := notification.NewNotification{
+			UserID: userID,
+			Msg:    msg,
+			Title:  title,
+		}
+
+		if err := s.NotificationService.QueueNotification(tx, n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) auditDrift(ctx context.Context, tx *sql.Tx, action string, serverGroup string, policy string, reason string, metadata map[string]any) {
+	if metadata == nil {
+		metadata = map[string]any{}
+	}
+	metadata["server_group"] = serverGroup
+	metadata["policy"] = policy
+
+	actorUserID, actorEmail := ctxutil.GetActor(ctx)
+	s.Audit.LogTx(tx, audit.Event{
+		ActorUserID: actorUserID,
+		ActorEmail:  actorEmail,
+		Action:      action,
+		Resource:    "server group",
+		Success:     true,
+		Reason:      reason,
+		Metadata:    metadata,
+	})
+}
+
+// Process server sessions which will expire soon and user not yet notified
+func (s *Service) processExpiringServerSessions(ctx context.Context) error {
+	expiringSessions, err := s.Repo.getExpiringServerSessions()
+	if err != nil {
+		return err
+	}
+
+	if len(expiringSessions) == 0 {
+		s.Logger.Debug("No expiring server sessions", "domain", "session")
+		return nil
+	}
+
+	s.Logger.Debug("Found expiring sessions", "domain", "session", "count", len(expiringSessions))
+
+	// Queue notification for each and set flag
+	for _, session := range expiringSessions {
+		n := notification.NewNotification{
+			UserID: session.UserID,
+			Msg:    fmt.Sprintf("Your session is expiring soon for Server Group %s and can be extended", session.ServerGroup),
+			Title:  fmt.Sprintf("Session expiring: %s", session.ServerGroup),
+		}
+
+		tx, err := s.Repo.Base.DB.Begin()
+		if err != nil {
+			s.Logger.Error("Failed to create transaction for expiring sessions", "user", session.Email, "domain", "session", "server group", session.ServerGroup, "error", err)
+			continue
+		}
+
+		if err := s.queueSessionNotification(tx, session.ServerGroup, n); err != nil {
+			s.Logger.Error("Failed to queue expiring session notification", "user", session.Email, "domain", "session", "server group", session.ServerGroup, "error", err)
+			tx.Rollback()
+			continue
+		}
+
+		if err := s.Repo.setWarningNotifiedFlag(tx, 1, session.ServerGroup); err != nil {
+			s.Logger.Error("Failed to set expiring session as notified", "user", session.Email, "domain", "session", "server_group", session.ServerGroup, "error", err)
+			tx.Rollback()
+			continue
+		}
+
+		actorUserID, actorEmail := ctxutil.GetActor(ctx)
+		s.Audit.LogTx(tx, audit.Event{
+			ActorUserID: actorUserID,
+			ActorEmail:  actorEmail,
+			Action:      "expiring",
+			Resource:    "server session",
+			Success:     true,
+			Metadata: map[string]any{
+				"server_group": session.ServerGroup,
+			},
+		})
+
+		tx.Commit()
+	}
+
+	return nil
+}
+
+// Process expired server session which haven't been processed yet
+func (s *Service) processExpiredServerSessions(ctx context.Context) error {
+	expiredSessions, err := s.Repo.getExpiredServerSessions()
+	if err != nil {
+		return err
+	}
+
+	if len(expiredSessions) == 0 {
+		s.Logger.Debug("No expired server sessions", "domain", "session")
+		return nil
+	}
+
+	s.Logger.Debug("Found expired sessions", "domain", "session", "count", len(expiredSessions))
+
+	for _, session := range expiredSessions {
+		n := notification.NewNotification{
+			UserID: session.UserID,
+			Msg:    fmt.Sprintf("Your session has expired for Server Group %s. Servers will power off", session.ServerGroup),
+			Title:  fmt.Sprintf("Session expired: %s", session.ServerGroup),
+		}
+
+		tx, err := s.Repo.Base.DB.Begin()
+		if err != nil {
+			s.Logger.Error("Failed to create transaction for expired sessions", "user", session.Email, "domain", "session", "server group", session.ServerGroup, "error", err)
+			continue
+		}
+
+		if err := s.queueSessionNotification(tx, session.ServerGroup, n); err != nil {
 			s.Logger.Error("Failed to queue expired session notification", "user", session.Email, "domain", "session", "server group", session.ServerGroup, "error", err)
 			tx.Rollback()
 			continue
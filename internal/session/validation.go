@@ -1,15 +1,25 @@
 package session
 
 import (
+	"database/sql"
+	"ez2boot/internal/server"
 	"ez2boot/internal/shared"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 )
 
-func (s *Service) validateServerSession(session ServerSessionRequest) error {
+func (s *Service) validateServerSession(session *ServerSessionRequest, policy server.GroupPolicy) error {
 	if session.ServerGroup == "" {
 		return shared.ErrFieldMissing
 	}
 
+	// Fall back to the group default when no duration is requested
+	if session.Duration == "" {
+		session.Duration = policy.DefaultDuration
+	}
+
 	if session.Duration == "" {
 		return shared.ErrFieldMissing
 	}
@@ -20,9 +30,160 @@ func (s *Service) validateServerSession(session ServerSessionRequest) error {
 	}
 
 	// Check if duration is beyond max
-	if dur > s.Config.MaxServerSessionDuration {
+	if dur > s.getMaxDuration(policy) {
 		return shared.ErrDurationTooLong
 	}
 
 	return nil
 }
+
+// Validate a booking as a session request, and that it starts in the future
+func (s *Service) validateScheduledSession(booking *ScheduledSessionRequest, policy server.GroupPolicy) error {
+	session := ServerSessionRequest{ServerGroup: booking.ServerGroup, Duration: booking.Duration}
+	if err := s.validateServerSession(&session, policy); err != nil {
+		return err
+	}
+	booking.Duration = session.Duration
+
+	if booking.Start.IsZero() {
+		return shared.ErrFieldMissing
+	}
+
+	if !booking.Start.After(time.Now()) {
+		return shared.ErrStartInPast
+	}
+
+	return nil
+}
+
+// Check a period is free of bookings and, when checking a booking, of running sessions and recurring windows. Runs in
+// the caller's transaction so the check and the write it guards cannot be interleaved
+func (s *Service) validateSchedule(tx *sql.Tx, serverGroup string, start int64, expiry int64, excludeID int64, isBooking bool) error {
+	conflict, err := s.Repo.hasBookingConflict(tx, serverGroup, start, expiry, excludeID)
+	if err != nil {
+		return err
+	}
+
+	if !conflict && isBooking {
+		conflict, err = s.Repo.hasSessionAt(tx, serverGroup, start)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !conflict && isBooking {
+		conflict, err = s.hasRecurringConflict(tx, serverGroup, time.Unix(start, 0), time.Unix(expiry, 0))
+		if err != nil {
+			return err
+		}
+	}
+
+	if conflict {
+		return shared.ErrScheduleConflict
+	}
+
+	return nil
+}
+
+// Check a recurring schedule parses and targets a known server group
+func (s *Service) validateRecurringSchedule(schedule *RecurringScheduleRequest) error {
+	if schedule.ServerGroup == "" || schedule.Days == "" || schedule.Start == "" || schedule.End == "" || schedule.TimeZone == "" {
+		return shared.ErrFieldMissing
+	}
+
+	if _, err := parseRecurringSchedule(RecurringSchedule{Days: schedule.Days, Start: schedule.Start, End: schedule.End, TimeZone: schedule.TimeZone}); err != nil {
+		return err
+	}
+
+	exists, err := s.Repo.hasServers(schedule.ServerGroup)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return fmt.Errorf("%w: no servers found for server_group %s", shared.ErrInvalidSchedule, schedule.ServerGroup)
+	}
+
+	schedule.Days = strings.ToLower(schedule.Days)
+
+	return nil
+}
+
+// Check whether any recurring window for the server group overlaps a period
+func (s *Service) hasRecurringConflict(tx *sql.Tx, serverGroup string, start time.Time, expiry time.Time) (bool, error) {
+	schedules, err := s.Repo.getRecurringSchedules(tx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, schedule := range schedules {
+		if schedule.ServerGroup != serverGroup {
+			continue
+		}
+
+		window, err := parseRecurringSchedule(schedule)
+		if err != nil {
+			s.Logger.Error("Failed to parse recurring schedule", "domain", "session", "id", schedule.ID, "server_group", schedule.ServerGroup, "error", err)
+			continue
+		}
+
+		if window.overlaps(start, expiry) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Check a new recurring schedule does not overlap any booking for the server group
+func (s *Service) validateRecurringConflicts(tx *sql.Tx, schedule RecurringScheduleRequest) error {
+	window, err := parseRecurringSchedule(RecurringSchedule{Days: schedule.Days, Start: schedule.Start, End: schedule.End, TimeZone: schedule.TimeZone})
+	if err != nil {
+		return err
+	}
+
+	bookings, err := s.Repo.getScheduledSessions(tx)
+	if err != nil {
+		return err
+	}
+
+	for _, booking := range bookings {
+		if booking.ServerGroup == schedule.ServerGroup && window.overlaps(booking.Start, booking.Expiry) {
+			return shared.ErrScheduleConflict
+		}
+	}
+
+	return nil
+}
+
+// Check the user is allowed to hold a session for the server group
+func (s *Service) validateGroupAccess(userID int64, policy server.GroupPolicy) error {
+	if len(policy.AllowedUsers) == 0 {
+		return nil
+	}
+
+	user, err := s.UserService.GetUserAuthorisation(userID)
+	if err != nil {
+		return err
+	}
+
+	if slices.Contains(policy.AllowedUsers, strings.ToLower(user.Email)) {
+		return nil
+	}
+
+	if user.IsAdmin && slices.Contains(policy.AllowedUsers, server.GroupPolicyAdmins) {
+		return nil
+	}
+
+	return shared.ErrGroupAccessDenied
+}
+
+// Group max applies only when it is shorter than the global max
+func (s *Service) getMaxDuration(policy server.GroupPolicy) time.Duration {
+	limit := s.Config.MaxServerSessionDuration
+	if dur, err := time.ParseDuration(policy.MaxDuration); err == nil && dur < limit {
+		limit = dur
+	}
+
+	return limit
+}
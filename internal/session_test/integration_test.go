@@ -2,10 +2,12 @@ package session_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"ez2boot/internal/session"
 	"ez2boot/internal/shared"
 	"ez2boot/internal/testutil"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -196,3 +198,1001 @@ func TestAdminTerminateServerSession_NonAdmin_Forbidden(t *testing.T) {
 		t.Fatalf("want session still active, got terminated")
 	}
 }
+
+// Group policy tags cap duration, fill the default and restrict who can start a session
+func TestNewServerSession_GroupPolicy(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	userEmail := "user@example.com"
+	userPassword := "testpassword123"
+	userHash := "$argon2id$v=19$m=131072,t=4,p=1$bBVby41uAKJ7KghSdCEt8g$80aCufSfLP2tAZ9bxAjbs8mArxgjmgrP3UkPn8MKCJY"
+	testutil.InsertUser(t, env.DB, userEmail, &userHash, true, false, true, true, "local")
+
+	testutil.InsertServer(t, env.DB, "i-3728hvi2vn2u4vn2", "gpu01", "off", "GPU", time.Now().Unix())
+	testutil.InsertServer(t, env.DB, "i-453uvbu5894uvbdu", "secret01", "off", "SECRET", time.Now().Unix())
+	testutil.InsertGroupTag(t, env.DB, "GPU", "max-duration", "30m")
+	testutil.InsertGroupTag(t, env.DB, "GPU", "default-duration", "15m")
+	testutil.InsertGroupTag(t, env.DB, "SECRET", "allowed-users", "someone@example.com admins")
+
+	cookies := testutil.LoginAndGetCookies(t, env.Router, userEmail, userPassword)
+
+	post := func(serverGroup string, duration string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(session.ServerSessionRequest{ServerGroup: serverGroup, Duration: duration})
+		req := httptest.NewRequest("POST", "/ui/session", bytes.NewReader(body))
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		w := httptest.NewRecorder()
+		env.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Within global max but over group max
+	if w := post("GPU", "1h"); w.Code != http.StatusBadRequest || !bytes.Contains(w.Body.Bytes(), []byte("30m")) {
+		t.Fatalf("want 400 quoting group max, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	// Not in allowed users
+	if w := post("SECRET", "1h"); w.Code != http.StatusForbidden {
+		t.Fatalf("want 403, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	// No duration uses the group default
+	w := post("GPU", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	var got shared.ApiResponse[session.ServerSessionResponse]
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	if got.Data.Duration != "15m" {
+		t.Errorf("want default duration 15m, got %s", got.Data.Duration)
+	}
+}
+
+// Sessions held up by a server out of attempts or stuck transitioning are flagged failed and the owner and admins notified once
+func TestProcessServerSessions_Failed(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+	env.Cfg.ServerMaxAttempts = 3
+	env.Cfg.ServerTransitionTimeout = 30 * time.Minute
+
+	testutil.InsertUser(t, env.DB, "admin@example.com", nil, true, true, true, true, "local")
+	testutil.InsertUser(t, env.DB, "user@example.com", nil, true, false, false, true, "local")
+
+	// QA out of attempts
+	testutil.InsertServer(t, env.DB, "i-3728hvi2vn2u4vn2", "test01", "off", "QA", time.Now().Unix())
+	testutil.InsertServerSession(t, env.DB, 2, "QA", time.Now().Add(1*time.Hour).Unix())
+	if _, err := env.DB.Exec("UPDATE servers SET attempts = $1, last_error = $2 WHERE server_group = $3", 3, "start failed: insufficient capacity", "QA"); err != nil {
+		t.Fatalf("failed to update server: %v", err)
+	}
+
+	// DEV stuck transitioning past the timeout
+	testutil.InsertServer(t, env.DB, "i-9f8e7d6c5b4a3210", "test02", "transitioning", "DEV", time.Now().Unix())
+	testutil.InsertServerSession(t, env.DB, 2, "DEV", time.Now().Add(1*time.Hour).Unix())
+	if _, err := env.DB.Exec("UPDATE servers SET time_last_on = $1 WHERE server_group = $2", time.Now().Add(-1*time.Hour).Unix(), "DEV"); err != nil {
+		t.Fatalf("failed to update server: %v", err)
+	}
+
+	// UAT starting within the timeout
+	testutil.InsertServer(t, env.DB, "i-0a1b2c3d4e5f6789", "test03", "transitioning", "UAT", time.Now().Unix())
+	testutil.InsertServerSession(t, env.DB, 2, "UAT", time.Now().Add(1*time.Hour).Unix())
+
+	// Second pass must not notify again
+	env.Worker.SessionService.ProcessServerSessions(context.Background())
+	env.Worker.SessionService.ProcessServerSessions(context.Background())
+
+	want := map[string]string{
+		"QA":  "test01 failed to start after 3 attempts: start failed: insufficient capacity",
+		"DEV": "test02 did not reach on within 30m0s",
+		"UAT": "",
+	}
+
+	for group, wantReason := range want {
+		var failed bool
+		var reason *string
+		if err := env.DB.QueryRow("SELECT failed, failure_reason FROM server_sessions WHERE server_group = $1", group).Scan(&failed, &reason); err != nil {
+			t.Fatalf("failed to query session: %v", err)
+		}
+
+		if failed != (wantReason != "") {
+			t.Errorf("group %s want failed %v, got %v", group, wantReason != "", failed)
+		}
+
+		if wantReason != "" && (reason == nil || *reason != wantReason) {
+			t.Errorf("group %s want reason %q, got %v", group, wantReason, reason)
+		}
+	}
+
+	// Owner and admin, for each failed group
+	var count int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM notification_queue").Scan(&count); err != nil {
+		t.Fatalf("failed to query notifications: %v", err)
+	}
+
+	if count != 4 {
+		t.Errorf("want 4 notifications, got %d", count)
+	}
+}
+
+// Server groups running without a session are handled by their drift policy, with every decision audited
+func TestProcessServerSessions_Drift(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+	env.Cfg.DriftPolicy = "alert"
+	env.Cfg.DriftGracePeriod = 1 * time.Hour
+
+	testutil.InsertUser(t, env.DB, "admin@example.com", nil, true, true, true, true, "local")
+
+	for i, group := range []string{"QA", "DEV", "UAT", "PROD"} {
+		testutil.InsertServer(t, env.DB, fmt.Sprintf("i-%d", i), fmt.Sprintf("test0%d", i), "on", group, time.Now().Unix())
+	}
+	testutil.InsertGroupTag(t, env.DB, "QA", "drift-policy", "off")
+	testutil.InsertGroupTag(t, env.DB, "DEV", "drift-policy", "adopt")
+	testutil.InsertGroupTag(t, env.DB, "PROD", "drift-policy", "ignore")
+
+	// Detect, then stop QA once its grace period is over
+	env.Worker.SessionService.ProcessServerSessions(context.Background())
+	if _, err := env.DB.Exec("UPDATE server_drift SET detected_at = $1 WHERE server_group = $2", time.Now().Add(-2*time.Hour).Unix(), "QA"); err != nil {
+		t.Fatalf("failed to update drift: %v", err)
+	}
+	env.Worker.SessionService.ProcessServerSessions(context.Background())
+
+	var nextState *string
+	if err := env.DB.QueryRow("SELECT next_state FROM servers WHERE server_group = $1", "QA").Scan(&nextState); err != nil {
+		t.Fatalf("failed to query server: %v", err)
+	}
+	if nextState == nil || *nextState != "off" {
+		t.Errorf("want QA next state off, got %v", nextState)
+	}
+
+	// DEV adopted by the admin
+	var userID int64
+	var orphan bool
+	if err := env.DB.QueryRow("SELECT user_id, orphan FROM server_sessions WHERE server_group = $1", "DEV").Scan(&userID, &orphan); err != nil {
+		t.Fatalf("failed to query adopted session: %v", err)
+	}
+	if userID != 1 || !orphan {
+		t.Errorf("want DEV adopted by admin, got user %d orphan %v", userID, orphan)
+	}
+
+	// UAT on the global policy, PROD ignored
+	for group, want := range map[string]string{"QA": "off", "UAT": "alert", "PROD": "ignore"} {
+		var policy string
+		if err := env.DB.QueryRow("SELECT policy FROM server_drift WHERE server_group = $1", group).Scan(&policy); err != nil {
+			t.Fatalf("failed to query drift for %s: %v", group, err)
+		}
+		if policy != want {
+			t.Errorf("group %s want policy %s, got %s", group, want, policy)
+		}
+	}
+
+	// QA stopped, drift resolved and servers released
+	testutil.UpdateServerState(t, env.DB, "QA", "off")
+	env.Worker.SessionService.ProcessServerSessions(context.Background())
+
+	var count int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM server_drift WHERE server_group = $1", "QA").Scan(&count); err != nil {
+		t.Fatalf("failed to query drift: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("want QA drift resolved, got %d rows", count)
+	}
+
+	if err := env.DB.QueryRow("SELECT next_state FROM servers WHERE server_group = $1", "QA").Scan(&nextState); err != nil {
+		t.Fatalf("failed to query server: %v", err)
+	}
+	if nextState != nil {
+		t.Errorf("want QA next state cleared, got %s", *nextState)
+	}
+
+	// QA warned and stopped, DEV adopted, UAT alerted
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM notification_queue").Scan(&count); err != nil {
+		t.Fatalf("failed to query notifications: %v", err)
+	}
+	if count != 4 {
+		t.Errorf("want 4 notifications, got %d", count)
+	}
+
+	want := map[string]int{"drift detected": 4, "drift enforced": 1, "drift resolved": 1}
+	for action, wantCount := range want {
+		if err := env.DB.QueryRow("SELECT COUNT(*) FROM audit_log WHERE action = $1 AND resource = $2", action, "server group").Scan(&count); err != nil {
+			t.Fatalf("failed to query audit log: %v", err)
+		}
+		if count != wantCount {
+			t.Errorf("want %d %s audit events, got %d", wantCount, action, count)
+		}
+	}
+}
+
+// Bookings reject overlaps, block sessions which would run into them, and start on time
+func TestScheduledSession_Lifecycle(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	userEmail := "user@example.com"
+	userPassword := "testpassword123"
+	userHash := "$argon2id$v=19$m=131072,t=4,p=1$bBVby41uAKJ7KghSdCEt8g$80aCufSfLP2tAZ9bxAjbs8mArxgjmgrP3UkPn8MKCJY"
+	testutil.InsertUser(t, env.DB, userEmail, &userHash, true, false, false, true, "local")
+	testutil.InsertUser(t, env.DB, "other@example.com", &userHash, true, false, false, true, "local")
+
+	testutil.InsertServer(t, env.DB, "i-3728hvi2vn2u4vn2", "test01", "off", "QA", time.Now().Unix())
+
+	cookies := testutil.LoginAndGetCookies(t, env.Router, userEmail, userPassword)
+	otherCookies := testutil.LoginAndGetCookies(t, env.Router, "other@example.com", userPassword)
+
+	send := func(cookies []*http.Cookie, method string, path string, payload any) *httptest.ResponseRecorder {
+		t.Helper()
+
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		w := httptest.NewRecorder()
+		env.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	start := time.Now().Add(1 * time.Hour).Truncate(time.Second)
+
+	w := send(cookies, "POST", "/ui/session/scheduled", session.ScheduledSessionRequest{ServerGroup: "QA", Start: start, Duration: "2h"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("want 200, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	var got shared.ApiResponse[session.ScheduledSession]
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+
+	booking := got.Data
+	if !booking.Expiry.Equal(start.Add(2 * time.Hour)) {
+		t.Errorf("want expiry %v, got %v", start.Add(2*time.Hour), booking.Expiry)
+	}
+
+	tests := []struct {
+		name    string
+		cookies []*http.Cookie
+		method  string
+		path    string
+		payload any
+		want    int
+	}{
+		{"overlapping booking", otherCookies, "POST", "/ui/session/scheduled", session.ScheduledSessionRequest{ServerGroup: "QA", Start: start.Add(90 * time.Minute), Duration: "1h"}, http.StatusConflict},
+		{"start in the past", otherCookies, "POST", "/ui/session/scheduled", session.ScheduledSessionRequest{ServerGroup: "QA", Start: time.Now().Add(-1 * time.Minute), Duration: "1h"}, http.StatusBadRequest},
+		{"session running into booking", otherCookies, "POST", "/ui/session", session.ServerSessionRequest{ServerGroup: "QA", Duration: "2h"}, http.StatusConflict},
+		{"modify by another user", otherCookies, "PUT", "/ui/session/scheduled", session.ScheduledSessionRequest{ID: booking.ID, ServerGroup: "QA", Start: start, Duration: "1h"}, http.StatusNotFound},
+		{"cancel by another user", otherCookies, "DELETE", "/ui/session/scheduled", session.ScheduledSessionRequest{ID: booking.ID}, http.StatusNotFound},
+		{"session ending before booking", otherCookies, "POST", "/ui/session", session.ServerSessionRequest{ServerGroup: "QA", Duration: "30m"}, http.StatusOK},
+		{"move into running session", cookies, "PUT", "/ui/session/scheduled", session.ScheduledSessionRequest{ID: booking.ID, ServerGroup: "QA", Start: time.Now().Add(10 * time.Minute), Duration: "1h"}, http.StatusConflict},
+		{"modify by owner", cookies, "PUT", "/ui/session/scheduled", session.ScheduledSessionRequest{ID: booking.ID, ServerGroup: "QA", Start: start, Duration: "1h"}, http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		if w := send(tc.cookies, tc.method, tc.path, tc.payload); w.Code != tc.want {
+			t.Errorf("%s: want %d, got %d, body=%s", tc.name, tc.want, w.Code, w.Body.String())
+		}
+	}
+
+	// Due with the earlier session still running, the booking waits
+	if _, err := env.DB.Exec("UPDATE scheduled_sessions SET start_time = $1 WHERE id = $2", time.Now().Add(-1*time.Minute).Unix(), booking.ID); err != nil {
+		t.Fatalf("failed to update booking: %v", err)
+	}
+	env.Worker.SessionService.ProcessServerSessions(context.Background())
+
+	var count int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM scheduled_sessions").Scan(&count); err != nil {
+		t.Fatalf("failed to query bookings: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("want booking waiting, got %d bookings", count)
+	}
+
+	// Earlier session gone, the booking starts
+	if _, err := env.DB.Exec("DELETE FROM server_sessions WHERE server_group = $1", "QA"); err != nil {
+		t.Fatalf("failed to delete session: %v", err)
+	}
+	env.Worker.SessionService.ProcessServerSessions(context.Background())
+
+	var userID, expiry int64
+	if err := env.DB.QueryRow("SELECT user_id, expiry FROM server_sessions WHERE server_group = $1", "QA").Scan(&userID, &expiry); err != nil {
+		t.Fatalf("failed to query started session: %v", err)
+	}
+	if userID != 1 || expiry != start.Add(1*time.Hour).Unix() {
+		t.Errorf("want session for user 1 until %d, got user %d until %d", start.Add(1*time.Hour).Unix(), userID, expiry)
+	}
+
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM audit_log WHERE resource = $1 AND action = $2", "scheduled session", "start").Scan(&count); err != nil {
+		t.Fatalf("failed to query audit log: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("want 1 start audit event, got %d", count)
+	}
+}
+
+func TestRecurringSchedule_OpensSession(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	adminEmail := "admin@example.com"
+	userEmail := "user@example.com"
+	password := "testpassword123"
+	hash := "$argon2id$v=19$m=131072,t=4,p=1$bBVby41uAKJ7KghSdCEt8g$80aCufSfLP2tAZ9bxAjbs8mArxgjmgrP3UkPn8MKCJY"
+	testutil.InsertUser(t, env.DB, adminEmail, &hash, true, true, false, true, "local")
+	testutil.InsertUser(t, env.DB, userEmail, &hash, true, false, false, true, "local")
+
+	testutil.InsertServer(t, env.DB, "i-3728hvi2vn2u4vn2", "test01", "off", "QA", time.Now().Unix())
+
+	adminCookies := testutil.LoginAndGetCookies(t, env.Router, adminEmail, password)
+	userCookies := testutil.LoginAndGetCookies(t, env.Router, userEmail, password)
+
+	send := func(cookies []*http.Cookie, method string, path string, payload any) *httptest.ResponseRecorder {
+		t.Helper()
+
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		w := httptest.NewRecorder()
+		env.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Window open from an hour ago to an hour from now
+	now := time.Now().UTC()
+	schedule := session.RecurringScheduleRequest{
+		ServerGroup: "QA",
+		Days:        "mon-sun",
+		Start:       now.Add(-1 * time.Hour).Format("15:04"),
+		End:         now.Add(1 * time.Hour).Format("15:04"),
+		TimeZone:    "UTC",
+	}
+
+	badZone := schedule
+	badZone.TimeZone = "Mars/Olympus"
+
+	tests := []struct {
+		name    string
+		cookies []*http.Cookie
+		payload session.RecurringScheduleRequest
+		want    int
+	}{
+		{"non admin", userCookies, schedule, http.StatusForbidden},
+		{"unknown time zone", adminCookies, badZone, http.StatusBadRequest},
+		{"valid", adminCookies, schedule, http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		if w := send(tc.cookies, "POST", "/ui/admin/schedule", tc.payload); w.Code != tc.want {
+			t.Errorf("%s: want %d, got %d, body=%s", tc.name, tc.want, w.Code, w.Body.String())
+		}
+	}
+
+	env.Worker.SessionService.ProcessServerSessions(context.Background())
+
+	var scheduleID *int64
+	var expiry int64
+	if err := env.DB.QueryRow("SELECT schedule_id, expiry FROM server_sessions WHERE server_group = 'QA'").Scan(&scheduleID, &expiry); err != nil {
+		t.Fatalf("want session opened by schedule: %v", err)
+	}
+
+	if scheduleID == nil {
+		t.Errorf("want schedule_id set")
+	}
+
+	if remaining := time.Until(time.Unix(expiry, 0)); remaining < 58*time.Minute || remaining > 61*time.Minute {
+		t.Errorf("want session to expire at the end of the window, got %v remaining", remaining)
+	}
+
+	// Shared sessions still respect the group's allowed users
+	testutil.InsertGroupTag(t, env.DB, "QA", "allowed-users", "someone@example.com")
+	if w := send(userCookies, "DELETE", "/ui/session", session.ServerSessionRequest{ServerGroup: "QA"}); w.Code != http.StatusForbidden {
+		t.Fatalf("want 403 ending a group the user is not allowed, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if _, err := env.DB.Exec("DELETE FROM server_group_tags"); err != nil {
+		t.Fatalf("failed to delete group tags: %v", err)
+	}
+
+	// Sessions opened by a schedule are shared, any user may end them early
+	if w := send(userCookies, "PUT", "/ui/session", session.ServerSessionRequest{ServerGroup: "QA", Duration: "0h"}); w.Code != http.StatusOK {
+		t.Fatalf("want 200 ending early, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	// Once the session is cleaned up the same window is not reopened
+	if _, err := env.DB.Exec("DELETE FROM server_sessions"); err != nil {
+		t.Fatalf("failed to delete session: %v", err)
+	}
+	env.Worker.SessionService.ProcessServerSessions(context.Background())
+
+	var count int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM server_sessions").Scan(&count); err != nil {
+		t.Fatalf("failed to query sessions: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("want window opened once, got %d sessions", count)
+	}
+}
+
+// Bookings and recurring windows refuse each other on create, and a booking wins when they overlap anyway
+func TestRecurringSchedule_BookingConflict(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	password := "testpassword123"
+	hash := "$argon2id$v=19$m=131072,t=4,p=1$bBVby41uAKJ7KghSdCEt8g$80aCufSfLP2tAZ9bxAjbs8mArxgjmgrP3UkPn8MKCJY"
+	testutil.InsertUser(t, env.DB, "admin@example.com", &hash, true, true, false, true, "local")
+	testutil.InsertUser(t, env.DB, "user@example.com", &hash, true, false, false, true, "local")
+
+	testutil.InsertServer(t, env.DB, "i-3728hvi2vn2u4vn2", "test01", "off", "QA", time.Now().Unix())
+	testutil.InsertServer(t, env.DB, "i-9f8e7d6c5b4a3921", "test02", "off", "UAT", time.Now().Unix())
+	testutil.InsertServer(t, env.DB, "i-1a2b3c4d5e6f7081", "test03", "off", "DEV", time.Now().Unix())
+
+	adminCookies := testutil.LoginAndGetCookies(t, env.Router, "admin@example.com", password)
+	userCookies := testutil.LoginAndGetCookies(t, env.Router, "user@example.com", password)
+
+	send := func(cookies []*http.Cookie, method string, path string, payload any) *httptest.ResponseRecorder {
+		t.Helper()
+
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		w := httptest.NewRecorder()
+		env.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	now := time.Now().UTC().Truncate(time.Minute)
+	window := func(group string, from time.Duration, to time.Duration) session.RecurringScheduleRequest {
+		return session.RecurringScheduleRequest{
+			ServerGroup: group,
+			Days:        "mon-sun",
+			Start:       now.Add(from).Format("15:04"),
+			End:         now.Add(to).Format("15:04"),
+			TimeZone:    "UTC",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		cookies []*http.Cookie
+		path    string
+		payload any
+		want    int
+	}{
+		{"booking", userCookies, "/ui/session/scheduled", session.ScheduledSessionRequest{ServerGroup: "QA", Start: now.Add(2 * time.Hour), Duration: "1h"}, http.StatusOK},
+		{"window over booking", adminCookies, "/ui/admin/schedule", window("QA", 1*time.Hour, 3*time.Hour), http.StatusConflict},
+		{"window clear of booking", adminCookies, "/ui/admin/schedule", window("QA", 4*time.Hour, 6*time.Hour), http.StatusOK},
+		{"booking inside window", userCookies, "/ui/session/scheduled", session.ScheduledSessionRequest{ServerGroup: "QA", Start: now.Add(5 * time.Hour), Duration: "30m"}, http.StatusConflict},
+		{"booking straddling window start", userCookies, "/ui/session/scheduled", session.ScheduledSessionRequest{ServerGroup: "QA", Start: now.Add(3*time.Hour + 30*time.Minute), Duration: "1h"}, http.StatusConflict},
+	}
+
+	for _, tc := range tests {
+		if w := send(tc.cookies, "POST", tc.path, tc.payload); w.Code != tc.want {
+			t.Errorf("%s: want %d, got %d, body=%s", tc.name, tc.want, w.Code, w.Body.String())
+		}
+	}
+
+	// Overlaps which predate the checks, on groups with a window open now
+	insertWindow := func(group string) {
+		t.Helper()
+		w := window(group, -1*time.Hour, 2*time.Hour)
+		if _, err := env.DB.Exec("INSERT INTO recurring_schedules (user_id, server_group, days, start_time, end_time, time_zone, time_added) VALUES ($1, $2, $3, $4, $5, $6, $7)", 1, group, w.Days, w.Start, w.End, w.TimeZone, now.Unix()); err != nil {
+			t.Fatalf("failed to insert recurring schedule: %v", err)
+		}
+	}
+
+	insertBooking := func(group string, start time.Time) {
+		t.Helper()
+		if _, err := env.DB.Exec("INSERT INTO scheduled_sessions (user_id, server_group, start_time, duration, expiry, time_added) VALUES ($1, $2, $3, $4, $5, $6)", 2, group, start.Unix(), "1h", start.Add(1*time.Hour).Unix(), now.Unix()); err != nil {
+			t.Fatalf("failed to insert booking: %v", err)
+		}
+	}
+
+	// UAT booking later in the window, DEV booking due now
+	insertWindow("UAT")
+	insertBooking("UAT", now.Add(30*time.Minute))
+	insertWindow("DEV")
+	insertBooking("DEV", now.Add(-1*time.Minute))
+
+	env.Worker.SessionService.ProcessServerSessions(context.Background())
+
+	var scheduleID *int64
+	var userID, expiry int64
+
+	// Window opens until the booking starts
+	if err := env.DB.QueryRow("SELECT user_id, schedule_id, expiry FROM server_sessions WHERE server_group = $1", "UAT").Scan(&userID, &scheduleID, &expiry); err != nil {
+		t.Fatalf("want UAT session opened by window: %v", err)
+	}
+	if scheduleID == nil || expiry != now.Add(30*time.Minute).Unix() {
+		t.Errorf("want UAT window session until the booking at %d, got schedule %v until %d", now.Add(30*time.Minute).Unix(), scheduleID, expiry)
+	}
+
+	// Booking starts rather than the window
+	if err := env.DB.QueryRow("SELECT user_id, schedule_id, expiry FROM server_sessions WHERE server_group = $1", "DEV").Scan(&userID, &scheduleID, &expiry); err != nil {
+		t.Fatalf("want DEV session started by booking: %v", err)
+	}
+	if scheduleID != nil || userID != 2 {
+		t.Errorf("want DEV session for booking owner 2, got user %d schedule %v", userID, scheduleID)
+	}
+
+	var count int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM audit_log WHERE resource = $1 AND action = $2", "scheduled session", "missed").Scan(&count); err != nil {
+		t.Fatalf("failed to query audit log: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("want no missed bookings, got %d", count)
+	}
+}
+
+func TestEndServerSession(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	password := "testpassword123"
+	hash := "$argon2id$v=19$m=131072,t=4,p=1$bBVby41uAKJ7KghSdCEt8g$80aCufSfLP2tAZ9bxAjbs8mArxgjmgrP3UkPn8MKCJY"
+	testutil.InsertUser(t, env.DB, "admin@example.com", &hash, true, true, false, true, "local")
+	testutil.InsertUser(t, env.DB, "user@example.com", &hash, true, false, false, true, "local")
+	testutil.InsertUser(t, env.DB, "other@example.com", &hash, true, false, false, true, "local")
+
+	testutil.InsertServer(t, env.DB, "i-3728hvi2vn2u4vn2", "test01", "on", "QA", time.Now().Unix())
+	testutil.InsertServer(t, env.DB, "i-9s8d7f6g5h4j3k2l", "test02", "on", "UAT", time.Now().Unix())
+	testutil.InsertServerSession(t, env.DB, 2, "QA", time.Now().Add(2*time.Hour).Unix())
+	testutil.InsertServerSession(t, env.DB, 3, "UAT", time.Now().Add(2*time.Hour).Unix())
+
+	adminCookies := testutil.LoginAndGetCookies(t, env.Router, "admin@example.com", password)
+	userCookies := testutil.LoginAndGetCookies(t, env.Router, "user@example.com", password)
+
+	send := func(cookies []*http.Cookie, path string, serverGroup string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		body, _ := json.Marshal(session.ServerSessionRequest{ServerGroup: serverGroup})
+		req := httptest.NewRequest("DELETE", path, bytes.NewReader(body))
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		w := httptest.NewRecorder()
+		env.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name    string
+		cookies []*http.Cookie
+		path    string
+		group   string
+		want    int
+	}{
+		{"missing server group", userCookies, "/ui/session", "", http.StatusBadRequest},
+		{"not owner", userCookies, "/ui/session", "UAT", http.StatusNotFound},
+		{"admin route as user", userCookies, "/ui/admin/session", "UAT", http.StatusForbidden},
+		{"owner", userCookies, "/ui/session", "QA", http.StatusOK},
+		{"already ended", userCookies, "/ui/session", "QA", http.StatusNotFound},
+		{"admin", adminCookies, "/ui/admin/session", "UAT", http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		if w := send(tc.cookies, tc.path, tc.group); w.Code != tc.want {
+			t.Errorf("%s: want %d, got %d, body=%s", tc.name, tc.want, w.Code, w.Body.String())
+		}
+	}
+
+	// Both sessions are handed to the normal shutdown flow
+	var ended int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM server_sessions WHERE to_cleanup = 1 AND expiry <= $1", time.Now().Unix()).Scan(&ended); err != nil {
+		t.Fatalf("failed to query sessions: %v", err)
+	}
+	if ended != 2 {
+		t.Errorf("want 2 sessions ended, got %d", ended)
+	}
+
+	var stopping int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM servers WHERE next_state = 'off'").Scan(&stopping); err != nil {
+		t.Fatalf("failed to query servers: %v", err)
+	}
+	if stopping != 2 {
+		t.Errorf("want 2 servers stopping, got %d", stopping)
+	}
+
+	// Owner is told once the servers are off
+	testutil.UpdateServerState(t, env.DB, "QA", "off")
+	env.Worker.SessionService.ProcessServerSessions(context.Background())
+
+	var notified int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM notification_queue WHERE user_id = 2 AND title = 'Session terminated: QA'").Scan(&notified); err != nil {
+		t.Fatalf("failed to query notifications: %v", err)
+	}
+	if notified != 1 {
+		t.Errorf("want owner notified of terminated session, got %d", notified)
+	}
+}
+
+func TestWaitlist_AutoStart(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	password := "testpassword123"
+	hash := "$argon2id$v=19$m=131072,t=4,p=1$bBVby41uAKJ7KghSdCEt8g$80aCufSfLP2tAZ9bxAjbs8mArxgjmgrP3UkPn8MKCJY"
+	testutil.InsertUser(t, env.DB, "owner@example.com", &hash, true, false, false, true, "local")
+	testutil.InsertUser(t, env.DB, "next@example.com", &hash, true, false, false, true, "local")
+	testutil.InsertUser(t, env.DB, "later@example.com", &hash, true, false, false, true, "local")
+
+	testutil.InsertServer(t, env.DB, "i-3728hvi2vn2u4vn2", "test01", "on", "QA", time.Now().Unix())
+	testutil.InsertServer(t, env.DB, "i-9s8d7f6g5h4j3k2l", "test02", "off", "UAT", time.Now().Unix())
+	testutil.InsertServerSession(t, env.DB, 1, "QA", time.Now().Add(2*time.Hour).Unix())
+
+	ownerCookies := testutil.LoginAndGetCookies(t, env.Router, "owner@example.com", password)
+	nextCookies := testutil.LoginAndGetCookies(t, env.Router, "next@example.com", password)
+	laterCookies := testutil.LoginAndGetCookies(t, env.Router, "later@example.com", password)
+
+	send := func(cookies []*http.Cookie, method string, path string, payload any) *httptest.ResponseRecorder {
+		t.Helper()
+
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		w := httptest.NewRecorder()
+		env.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name    string
+		cookies []*http.Cookie
+		method  string
+		payload session.WaitlistRequest
+		want    int
+	}{
+		{"free group", nextCookies, "POST", session.WaitlistRequest{ServerGroup: "UAT"}, http.StatusBadRequest},
+		{"duration too long", nextCookies, "POST", session.WaitlistRequest{ServerGroup: "QA", Duration: "48h", AutoStart: true}, http.StatusBadRequest},
+		{"auto start", nextCookies, "POST", session.WaitlistRequest{ServerGroup: "QA", Duration: "2h", AutoStart: true}, http.StatusOK},
+		{"already waiting", nextCookies, "POST", session.WaitlistRequest{ServerGroup: "QA"}, http.StatusConflict},
+		{"notify only", laterCookies, "POST", session.WaitlistRequest{ServerGroup: "QA"}, http.StatusOK},
+		{"leave when not waiting", ownerCookies, "DELETE", session.WaitlistRequest{ServerGroup: "QA"}, http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		if w := send(tc.cookies, tc.method, "/ui/session/waitlist", tc.payload); w.Code != tc.want {
+			t.Errorf("%s: want %d, got %d, body=%s", tc.name, tc.want, w.Code, w.Body.String())
+		}
+	}
+
+	// Queue positions are in the summary
+	w := send(ownerCookies, "GET", "/ui/sessions/summary", nil)
+	var summary shared.ApiResponse[[]session.ServerSessionSummaryResponse]
+	if err := json.Unmarshal(w.Body.Bytes(), &summary); err != nil {
+		t.Fatalf("failed to unmarshal summary: %v", err)
+	}
+
+	for _, group := range summary.Data {
+		if group.ServerGroup != "QA" {
+			continue
+		}
+
+		if len(group.Waitlist) != 2 || group.Waitlist[0].Email != "next@example.com" || group.Waitlist[1].Position != 2 {
+			t.Errorf("want next then later on the waitlist, got %+v", group.Waitlist)
+		}
+	}
+
+	// Owner finishes, once the servers are off and the session is cleaned up the next user gets a session
+	if w := send(ownerCookies, "DELETE", "/ui/session", session.ServerSessionRequest{ServerGroup: "QA"}); w.Code != http.StatusOK {
+		t.Fatalf("want 200 ending session, got %d, body=%s", w.Code, w.Body.String())
+	}
+	testutil.UpdateServerState(t, env.DB, "QA", "off")
+
+	for range 2 {
+		env.Worker.SessionService.ProcessServerSessions(context.Background())
+	}
+
+	var userID int64
+	if err := env.DB.QueryRow("SELECT user_id FROM server_sessions WHERE server_group = 'QA'").Scan(&userID); err != nil {
+		t.Fatalf("want session started from waitlist: %v", err)
+	}
+	if userID != 2 {
+		t.Errorf("want session for next user, got user %d", userID)
+	}
+
+	var waiting int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM waitlist WHERE server_group = 'QA'").Scan(&waiting); err != nil {
+		t.Fatalf("failed to query waitlist: %v", err)
+	}
+	if waiting != 1 {
+		t.Errorf("want later user still waiting, got %d entries", waiting)
+	}
+
+	var notified int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM notification_queue WHERE user_id = 2 AND title = 'Waitlist session started: QA'").Scan(&notified); err != nil {
+		t.Fatalf("failed to query notifications: %v", err)
+	}
+	if notified != 1 {
+		t.Errorf("want next user notified, got %d", notified)
+	}
+}
+
+// A notified user keeps their place and the group is held for them until they claim it or the claim window passes
+func TestWaitlist_NotifyOnly(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+	env.Cfg.WaitlistClaimWindow = 10 * time.Minute
+
+	password := "testpassword123"
+	hash := "$argon2id$v=19$m=131072,t=4,p=1$bBVby41uAKJ7KghSdCEt8g$80aCufSfLP2tAZ9bxAjbs8mArxgjmgrP3UkPn8MKCJY"
+	testutil.InsertUser(t, env.DB, "owner@example.com", &hash, true, false, false, true, "local")
+	testutil.InsertUser(t, env.DB, "next@example.com", &hash, true, false, false, true, "local")
+	testutil.InsertUser(t, env.DB, "later@example.com", &hash, true, false, false, true, "local")
+	testutil.InsertUser(t, env.DB, "other@example.com", &hash, true, false, false, true, "local")
+
+	testutil.InsertServer(t, env.DB, "i-3728hvi2vn2u4vn2", "test01", "off", "QA", time.Now().Unix())
+	testutil.InsertServerSession(t, env.DB, 1, "QA", time.Now().Add(2*time.Hour).Unix())
+
+	nextCookies := testutil.LoginAndGetCookies(t, env.Router, "next@example.com", password)
+	laterCookies := testutil.LoginAndGetCookies(t, env.Router, "later@example.com", password)
+	otherCookies := testutil.LoginAndGetCookies(t, env.Router, "other@example.com", password)
+
+	send := func(cookies []*http.Cookie, method string, path string, payload any) *httptest.ResponseRecorder {
+		t.Helper()
+
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		w := httptest.NewRecorder()
+		env.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	countRows := func(query string, args ...any) int {
+		t.Helper()
+
+		var count int
+		if err := env.DB.QueryRow(query, args...).Scan(&count); err != nil {
+			t.Fatalf("failed to count rows: %v", err)
+		}
+		return count
+	}
+
+	for _, cookies := range [][]*http.Cookie{nextCookies, laterCookies} {
+		if w := send(cookies, "POST", "/ui/session/waitlist", session.WaitlistRequest{ServerGroup: "QA"}); w.Code != http.StatusOK {
+			t.Fatalf("want 200 joining waitlist, got %d, body=%s", w.Code, w.Body.String())
+		}
+	}
+
+	// Group freed, several cycles notify the next user once and keep both queued
+	if _, err := env.DB.Exec("DELETE FROM server_sessions"); err != nil {
+		t.Fatalf("failed to delete session: %v", err)
+	}
+	for range 3 {
+		env.Worker.SessionService.ProcessServerSessions(context.Background())
+	}
+
+	if got := countRows("SELECT COUNT(*) FROM notification_queue WHERE user_id = 2 AND title = 'Server group free: QA'"); got != 1 {
+		t.Errorf("want next user notified once, got %d", got)
+	}
+	if got := countRows("SELECT COUNT(*) FROM notification_queue WHERE user_id = 3"); got != 0 {
+		t.Errorf("want later user not notified while the group is held, got %d", got)
+	}
+	if got := countRows("SELECT COUNT(*) FROM waitlist WHERE server_group = 'QA'"); got != 2 {
+		t.Errorf("want both users still queued, got %d", got)
+	}
+
+	// Held group refuses others, but may still be queued for
+	if w := send(otherCookies, "POST", "/ui/session", session.ServerSessionRequest{ServerGroup: "QA", Duration: "1h"}); w.Code != http.StatusConflict {
+		t.Errorf("want 409 starting a held group, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if w := send(otherCookies, "POST", "/ui/session/waitlist", session.WaitlistRequest{ServerGroup: "QA"}); w.Code != http.StatusOK {
+		t.Errorf("want 200 joining the waitlist of a held group, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	// Next user claims the group and leaves the queue
+	if w := send(nextCookies, "POST", "/ui/session", session.ServerSessionRequest{ServerGroup: "QA", Duration: "1h"}); w.Code != http.StatusOK {
+		t.Fatalf("want 200 claiming the group, got %d, body=%s", w.Code, w.Body.String())
+	}
+	if got := countRows("SELECT COUNT(*) FROM waitlist WHERE user_id = 2"); got != 0 {
+		t.Errorf("want claimed entry removed, got %d", got)
+	}
+
+	// Freed again, the later user is notified but lets the claim window pass
+	if _, err := env.DB.Exec("DELETE FROM server_sessions"); err != nil {
+		t.Fatalf("failed to delete session: %v", err)
+	}
+	env.Worker.SessionService.ProcessServerSessions(context.Background())
+
+	if _, err := env.DB.Exec("UPDATE waitlist SET notified_at = $1 WHERE user_id = 3", time.Now().Add(-11*time.Minute).Unix()); err != nil {
+		t.Fatalf("failed to update waitlist: %v", err)
+	}
+	env.Worker.SessionService.ProcessServerSessions(context.Background())
+
+	if got := countRows("SELECT COUNT(*) FROM waitlist WHERE user_id = 3"); got != 0 {
+		t.Errorf("want unclaimed entry removed, got %d", got)
+	}
+	if got := countRows("SELECT COUNT(*) FROM notification_queue WHERE user_id = 3 AND title = 'Waitlist hold expired: QA'"); got != 1 {
+		t.Errorf("want later user told the hold expired, got %d", got)
+	}
+
+	// The group passes to the user behind
+	env.Worker.SessionService.ProcessServerSessions(context.Background())
+
+	if got := countRows("SELECT COUNT(*) FROM notification_queue WHERE user_id = 4 AND title = 'Server group free: QA'"); got != 1 {
+		t.Errorf("want other user notified after the hold expired, got %d", got)
+	}
+}
+
+// An auto start entry behind a notified user waits for the hold rather than taking the group
+func TestWaitlist_MixedQueue(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+	env.Cfg.WaitlistClaimWindow = 10 * time.Minute
+
+	password := "testpassword123"
+	hash := "$argon2id$v=19$m=131072,t=4,p=1$bBVby41uAKJ7KghSdCEt8g$80aCufSfLP2tAZ9bxAjbs8mArxgjmgrP3UkPn8MKCJY"
+	testutil.InsertUser(t, env.DB, "owner@example.com", &hash, true, false, false, true, "local")
+	testutil.InsertUser(t, env.DB, "notify@example.com", &hash, true, false, false, true, "local")
+	testutil.InsertUser(t, env.DB, "auto@example.com", &hash, true, false, false, true, "local")
+
+	testutil.InsertServer(t, env.DB, "i-3728hvi2vn2u4vn2", "test01", "off", "QA", time.Now().Unix())
+	testutil.InsertServerSession(t, env.DB, 1, "QA", time.Now().Add(2*time.Hour).Unix())
+
+	notifyCookies := testutil.LoginAndGetCookies(t, env.Router, "notify@example.com", password)
+	autoCookies := testutil.LoginAndGetCookies(t, env.Router, "auto@example.com", password)
+
+	send := func(cookies []*http.Cookie, payload session.WaitlistRequest) {
+		t.Helper()
+
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest("POST", "/ui/session/waitlist", bytes.NewReader(body))
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		w := httptest.NewRecorder()
+		env.Router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("want 200 joining waitlist, got %d, body=%s", w.Code, w.Body.String())
+		}
+	}
+
+	send(notifyCookies, session.WaitlistRequest{ServerGroup: "QA"})
+	send(autoCookies, session.WaitlistRequest{ServerGroup: "QA", Duration: "1h", AutoStart: true})
+
+	if _, err := env.DB.Exec("DELETE FROM server_sessions"); err != nil {
+		t.Fatalf("failed to delete session: %v", err)
+	}
+	for range 3 {
+		env.Worker.SessionService.ProcessServerSessions(context.Background())
+	}
+
+	var sessions int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM server_sessions").Scan(&sessions); err != nil {
+		t.Fatalf("failed to query sessions: %v", err)
+	}
+	if sessions != 0 {
+		t.Fatalf("want group held for the notified user, got %d sessions", sessions)
+	}
+
+	// Hold passes, the notified user is removed then the auto start user gets the session
+	if _, err := env.DB.Exec("UPDATE waitlist SET notified_at = $1 WHERE user_id = 2", time.Now().Add(-11*time.Minute).Unix()); err != nil {
+		t.Fatalf("failed to update waitlist: %v", err)
+	}
+	for range 2 {
+		env.Worker.SessionService.ProcessServerSessions(context.Background())
+	}
+
+	var userID int64
+	if err := env.DB.QueryRow("SELECT user_id FROM server_sessions WHERE server_group = 'QA'").Scan(&userID); err != nil {
+		t.Fatalf("want session started for the auto start user: %v", err)
+	}
+	if userID != 3 {
+		t.Errorf("want session for auto start user 3, got user %d", userID)
+	}
+
+	var waiting int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM waitlist").Scan(&waiting); err != nil {
+		t.Fatalf("failed to query waitlist: %v", err)
+	}
+	if waiting != 0 {
+		t.Errorf("want waitlist empty, got %d entries", waiting)
+	}
+}
+
+func TestSessionCoOwnerAndHandover(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+
+	password := "testpassword123"
+	hash := "$argon2id$v=19$m=131072,t=4,p=1$bBVby41uAKJ7KghSdCEt8g$80aCufSfLP2tAZ9bxAjbs8mArxgjmgrP3UkPn8MKCJY"
+	testutil.InsertUser(t, env.DB, "owner@example.com", &hash, true, false, false, true, "local")
+	testutil.InsertUser(t, env.DB, "colleague@example.com", &hash, true, false, false, true, "local")
+	testutil.InsertUser(t, env.DB, "other@example.com", &hash, true, false, false, true, "local")
+
+	testutil.InsertServer(t, env.DB, "i-3728hvi2vn2u4vn2", "test01", "on", "QA", time.Now().Unix())
+	testutil.InsertServerSession(t, env.DB, 1, "QA", time.Now().Add(10*time.Minute).Unix())
+
+	ownerCookies := testutil.LoginAndGetCookies(t, env.Router, "owner@example.com", password)
+	colleagueCookies := testutil.LoginAndGetCookies(t, env.Router, "colleague@example.com", password)
+	otherCookies := testutil.LoginAndGetCookies(t, env.Router, "other@example.com", password)
+
+	send := func(cookies []*http.Cookie, method string, path string, payload any) *httptest.ResponseRecorder {
+		t.Helper()
+
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+
+		w := httptest.NewRecorder()
+		env.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name    string
+		cookies []*http.Cookie
+		method  string
+		path    string
+		payload any
+		want    int
+	}{
+		{"extend by colleague before sharing", colleagueCookies, "PUT", "/ui/session", session.ServerSessionRequest{ServerGroup: "QA", Duration: "1h"}, http.StatusUnauthorized},
+		{"share by non owner", otherCookies, "POST", "/ui/session/owner", session.SessionOwnerRequest{ServerGroup: "QA", Email: "colleague@example.com"}, http.StatusNotFound},
+		{"share with unknown user", ownerCookies, "POST", "/ui/session/owner", session.SessionOwnerRequest{ServerGroup: "QA", Email: "nobody@example.com"}, http.StatusNotFound},
+		{"share with self", ownerCookies, "POST", "/ui/session/owner", session.SessionOwnerRequest{ServerGroup: "QA", Email: "owner@example.com"}, http.StatusConflict},
+		{"share", ownerCookies, "POST", "/ui/session/owner", session.SessionOwnerRequest{ServerGroup: "QA", Email: "Colleague@example.com"}, http.StatusOK},
+		{"share twice", ownerCookies, "POST", "/ui/session/owner", session.SessionOwnerRequest{ServerGroup: "QA", Email: "colleague@example.com"}, http.StatusConflict},
+	}
+
+	for _, tc := range tests {
+		if w := send(tc.cookies, tc.method, tc.path, tc.payload); w.Code != tc.want {
+			t.Errorf("%s: want %d, got %d, body=%s", tc.name, tc.want, w.Code, w.Body.String())
+		}
+	}
+
+	// Co-owners receive the expiring warning too
+	env.Worker.SessionService.ProcessServerSessions(context.Background())
+
+	var warned int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM notification_queue WHERE title = 'Session expiring: QA' AND user_id IN (1, 2)").Scan(&warned); err != nil {
+		t.Fatalf("failed to query notifications: %v", err)
+	}
+	if warned != 2 {
+		t.Errorf("want owner and co-owner warned, got %d", warned)
+	}
+
+	// And can extend it
+	if w := send(colleagueCookies, "PUT", "/ui/session", session.ServerSessionRequest{ServerGroup: "QA", Duration: "1h"}); w.Code != http.StatusOK {
+		t.Errorf("want co-owner to extend, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	// Handing over makes the colleague the owner and removes the previous owner's access
+	if w := send(ownerCookies, "PUT", "/ui/session/handover", session.SessionOwnerRequest{ServerGroup: "QA", Email: "colleague@example.com"}); w.Code != http.StatusOK {
+		t.Fatalf("want 200 handing over, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	var ownerID int64
+	var coOwners int
+	if err := env.DB.QueryRow("SELECT user_id, (SELECT COUNT(*) FROM session_owners) FROM server_sessions WHERE server_group = 'QA'").Scan(&ownerID, &coOwners); err != nil {
+		t.Fatalf("failed to query session: %v", err)
+	}
+	if ownerID != 2 || coOwners != 0 {
+		t.Errorf("want colleague as sole owner, got owner %d with %d co-owners", ownerID, coOwners)
+	}
+
+	if w := send(ownerCookies, "DELETE", "/ui/session", session.ServerSessionRequest{ServerGroup: "QA"}); w.Code != http.StatusNotFound {
+		t.Errorf("want previous owner unable to end session, got %d, body=%s", w.Code, w.Body.String())
+	}
+
+	// Both changes are audited against the colleague
+	var audited int
+	if err := env.DB.QueryRow("SELECT COUNT(*) FROM audit_log WHERE target_user_id = 2 AND action IN ('add co-owner', 'handover') AND success = 1").Scan(&audited); err != nil {
+		t.Fatalf("failed to query audit log: %v", err)
+	}
+	if audited != 2 {
+		t.Errorf("want 2 audit events for the colleague, got %d", audited)
+	}
+}
@@ -5,3 +5,23 @@ const (
 	IdentityProviderLDAP  = "ldap"
 	IdentityProviderOIDC  = "oidc"
 )
+
+const (
+	CloudProviderAWS        = "aws"
+	CloudProviderAzure      = "azure"
+	CloudProviderGCP        = "gcp"
+	CloudProviderDocker     = "docker"
+	CloudProviderSimulated  = "simulated"
+	CloudProviderProxmox    = "proxmox"
+	CloudProviderKubernetes = "kubernetes"
+)
+
+// What to do with a server group found running without a session
+const (
+	DriftPolicyIgnore = "ignore" // Audit only
+	DriftPolicyAlert  = "alert"  // Notify admins
+	DriftPolicyAdopt  = "adopt"  // Take into a short session owned by an admin
+	DriftPolicyOff    = "off"    // Stop after the grace period
+)
+
+var DriftPolicies = []string{DriftPolicyIgnore, DriftPolicyAlert, DriftPolicyAdopt, DriftPolicyOff}
@@ -25,6 +25,15 @@ var (
 	ErrEmailOrPasswordMissing       = errors.New("email and password field required")
 	ErrInputTooLong                 = errors.New("input too long")
 	ErrDurationTooLong              = errors.New("duration too long")
+	ErrGroupAccessDenied            = errors.New("user is not allowed to use this server group")
+	ErrInvalidTimeRange             = errors.New("time range start must be before its end")
+	ErrScheduleConflict             = errors.New("server group is already booked for that time")
+	ErrStartInPast                  = errors.New("start time must be in the future")
+	ErrInvalidSchedule              = errors.New("invalid recurring schedule")
+	ErrAlreadyWaiting               = errors.New("already on the waitlist for this server group")
+	ErrGroupNotInUse                = errors.New("server group is not in use")
+	ErrGroupHeld                    = errors.New("server group is held for the next user on the waitlist")
+	ErrAlreadyOwner                 = errors.New("user already owns this session")
 	ErrEmailMissing                 = errors.New("email field missing")
 	ErrCurrentOrNewPasswordMissing  = errors.New("current_password and new_password field required")
 	ErrCannotModifyOwnAuth          = errors.New("cannot modify own authorisation")
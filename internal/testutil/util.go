@@ -11,6 +11,8 @@ import (
 	"ez2boot/internal/config"
 	"ez2boot/internal/db"
 	"ez2boot/internal/encryption"
+	"ez2boot/internal/server"
+	"ez2boot/internal/shared"
 	"ez2boot/internal/worker"
 	"log/slog"
 	"net/http"
@@ -28,16 +30,17 @@ type Encryptor interface {
 }
 
 type TestEnv struct {
-	DB          *sql.DB
-	Logger      *slog.Logger
-	Base        *db.Repository
-	Cfg         *config.Config
-	Router      http.Handler
-	Worker      *worker.Worker
-	Encryptor   Encryptor
-	AuthService *auth.Service
-	LdapService *ldap.Service
-	OidcService *oidc.Service
+	DB            *sql.DB
+	Logger        *slog.Logger
+	Base          *db.Repository
+	Cfg           *config.Config
+	Router        http.Handler
+	Worker        *worker.Worker
+	Encryptor     Encryptor
+	AuthService   *auth.Service
+	LdapService   *ldap.Service
+	OidcService   *oidc.Service
+	ServerService *server.Service
 }
 
 // Build test environment - in memory only
@@ -66,12 +69,14 @@ func NewTestEnv(t *testing.T) *TestEnv {
 	}
 
 	cfg := &config.Config{
-		CloudProvider:            "aws",
+		CloudProviders:           []string{"aws"},
 		AWSRegion:                "ap-southeast-2",
+		AWSTargets:               []config.AWSTarget{{Region: "ap-southeast-2"}},
 		PublicRateLimit:          50,            // Elevate if 429's in tests
 		PrivateRateLimit:         100,           // Elevate if 429's in tests
 		UserSessionDuration:      1 * time.Hour, // Prevent intermittent 401s during test
 		MaxServerSessionDuration: 2 * time.Hour,
+		DriftPolicy:              shared.DriftPolicyIgnore, // Production default
 		EncryptionPhrase:         "newphrase",
 	}
 
@@ -86,16 +91,17 @@ func NewTestEnv(t *testing.T) *TestEnv {
 	}
 
 	return &TestEnv{
-		DB:          testDB,
-		Logger:      logger,
-		Base:        baseRepo,
-		Cfg:         cfg,
-		Router:      router,
-		Worker:      wkr,
-		Encryptor:   encryptor,
-		AuthService: services.AuthService,
-		LdapService: services.LdapService,
-		OidcService: services.OidcService,
+		DB:            testDB,
+		Logger:        logger,
+		Base:          baseRepo,
+		Cfg:           cfg,
+		Router:        router,
+		Worker:        wkr,
+		Encryptor:     encryptor,
+		AuthService:   services.AuthService,
+		LdapService:   services.LdapService,
+		OidcService:   services.OidcService,
+		ServerService: services.ServerService,
 	}
 }
 
@@ -130,6 +136,25 @@ func UpdateServerState(t *testing.T, db *sql.DB, serverGroup string, state strin
 	}
 }
 
+func SetServerProvider(t *testing.T, db *sql.DB, serverGroup string, provider string) {
+	t.Helper()
+
+	_, err := db.Exec("UPDATE servers SET provider = $1 WHERE server_group = $2", provider, serverGroup)
+	if err != nil {
+		t.Fatalf("failed to update server provider: %v", err)
+	}
+}
+
+// Insert a companion tag for a server group as if read by a scrape
+func InsertGroupTag(t *testing.T, db *sql.DB, serverGroup string, name string, value string) {
+	t.Helper()
+
+	_, err := db.Exec("INSERT INTO server_group_tags (server_group, provider, name, value) VALUES ($1, $2, $3, $4)", serverGroup, "aws", name, value)
+	if err != nil {
+		t.Fatalf("failed to insert group tag: %v", err)
+	}
+}
+
 // Logs in a UI user and returns cookie - use in tests other than login flow
 func LoginAndGetCookies(t *testing.T, router http.Handler, email, password string) []*http.Cookie {
 	t.Helper()
@@ -50,6 +50,29 @@ func (r *Repository) getUsers() ([]GetUsersResponse, error) {
 	return users, nil
 }
 
+// Get IDs of active admin users
+func (r *Repository) getAdminUserIDs() ([]int64, error) {
+	rows, err := r.Base.DB.Query("SELECT id FROM users WHERE is_admin = 1 AND is_active = 1 ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	userIDs := []int64{}
+
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		userIDs = append(userIDs, id)
+	}
+
+	return userIDs, nil
+}
+
 func (r *Repository) getUserAuthorisation(userID int64) (UserAuthResponse, error) {
 	query := `SELECT id, email, is_active, is_admin, api_enabled, ui_enabled, identity_provider, mfa_confirmed FROM users WHERE id = $1`
 
@@ -35,6 +35,11 @@ func (s *Service) GetUserAuthorisation(userID int64) (UserAuthResponse, error) {
 	return s.Repo.getUserAuthorisation(userID)
 }
 
+// Get IDs of active admin users, oldest first, eg for operational notifications
+func (s *Service) GetAdminUserIDs() ([]int64, error) {
+	return s.Repo.getAdminUserIDs()
+}
+
 func (s *Service) updateUserAuthorisation(users []UpdateUserRequest, ctx context.Context) error {
 	userID, email := ctxutil.GetActor(ctx)
 	currentUserID := userID